@@ -33,8 +33,13 @@ async function captureAuthData() {
   const url = new URL(tab.url);
   const baseURL = `${url.protocol}//${url.host}`;
 
-  // Get cookies for the domain
-  const cookies = await chrome.cookies.getAll({ url: baseURL });
+  // Server and Data Center sites are only reachable once the side panel has been granted access
+  if (!isCloudSite(url) && !(await chrome.permissions.contains({ origins: [`${url.origin}/*`] }))) {
+    throw new Error(`No access to ${url.host} yet, capture from the side panel to grant it`);
+  }
+
+  // Get cookies for the domain, with expires in the unix seconds the parser expects
+  const cookies = (await chrome.cookies.getAll({ url: baseURL })).map(withExpires);
 
   // Inject content script to extract cloudId and atlToken from page
   const [{ result: pageTokens }] = await chrome.scripting.executeScript({
@@ -53,6 +58,12 @@ async function captureAuthData() {
         tokens.cloudId = metaCloudId.content;
       }
 
+      // Server and Data Center sites can be served under a context path such as /jira
+      const contextPath = document.querySelector('meta[name="ajs-context-path"]');
+      if (contextPath && contextPath.content) {
+        tokens.contextPath = contextPath.content;
+      }
+
       // Try to get atlToken
       const atlTokenMeta = document.querySelector('meta[name="atl-token"]');
       if (atlTokenMeta && atlTokenMeta.content) {
@@ -95,7 +106,25 @@ async function captureAuthData() {
     cookies: cookies,
     tokens: tokens,
     userAgent: userAgent,
-    baseUrl: baseURL,
+    baseUrl: siteBaseURL(baseURL, url, pageTokens.contextPath),
     timestamp: Date.now()
   };
 }
+
+// Chrome reports expiry as fractional expirationDate, absent for browser-session cookies
+function withExpires(cookie) {
+  return { ...cookie, expires: cookie.expirationDate ? Math.floor(cookie.expirationDate) : 0 };
+}
+
+// Cloud sites are covered by the manifest host permissions
+function isCloudSite(url) {
+  return url.hostname.endsWith('.atlassian.net') || url.hostname.endsWith('.jira.com');
+}
+
+// Server and Data Center APIs live under the context path; Confluence Cloud reports /wiki, which the parser adds itself
+function siteBaseURL(baseURL, url, contextPath) {
+  if (isCloudSite(url) || !contextPath) {
+    return baseURL;
+  }
+  return baseURL + contextPath.replace(/\/$/, '');
+}
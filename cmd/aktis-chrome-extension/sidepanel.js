@@ -87,10 +87,32 @@ async function captureAuth() {
     }
 
     const url = new URL(tab.url);
-    const baseURL = `${url.protocol}//${url.host}`;
+    let baseURL = `${url.protocol}//${url.host}`;
 
-    // Get cookies
-    const cookies = await chrome.cookies.getAll({ url: baseURL });
+    // Server and Data Center sites are not covered by the manifest, ask for access to them
+    if (!isCloudSite(url)) {
+      const granted = await chrome.permissions.request({ origins: [`${url.origin}/*`] });
+      if (!granted) {
+        throw new Error(`Access to ${url.host} was not granted`);
+      }
+    }
+
+    // Get cookies, with expires in the unix seconds the parser expects
+    const cookies = (await chrome.cookies.getAll({ url: baseURL })).map(withExpires);
+
+    // Server and Data Center APIs live under the site's context path, such as /jira
+    if (!isCloudSite(url)) {
+      const [{ result: contextPath }] = await chrome.scripting.executeScript({
+        target: { tabId: tab.id },
+        func: () => {
+          const meta = document.querySelector('meta[name="ajs-context-path"]');
+          return meta ? meta.content : '';
+        }
+      });
+      if (contextPath) {
+        baseURL += contextPath.replace(/\/$/, '');
+      }
+    }
 
     // Extract tokens from cookies
     const tokens = {};
@@ -173,3 +195,13 @@ function showError(message) {
     element.style.display = 'none';
   }, 5000);
 }
+
+// Chrome reports expiry as fractional expirationDate, absent for browser-session cookies
+function withExpires(cookie) {
+  return { ...cookie, expires: cookie.expirationDate ? Math.floor(cookie.expirationDate) : 0 };
+}
+
+// Cloud sites are covered by the manifest host permissions
+function isCloudSite(url) {
+  return url.hostname.endsWith('.atlassian.net') || url.hostname.endsWith('.jira.com');
+}
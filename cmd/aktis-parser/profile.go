@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"aktis-parser/internal/common"
+	"aktis-parser/internal/handlers"
+	"aktis-parser/internal/interfaces"
+	"aktis-parser/internal/services"
+	"github.com/ternarybob/arbor"
+	bolt "go.etcd.io/bbolt"
+)
+
+// databaseOpenTimeout bounds waiting for the database file lock, which another process may hold
+const databaseOpenTimeout = 5 * time.Second
+
+// startProfile opens a site profile's database and builds its services and routes
+// Profiles share nothing but the auth cipher, so scrapes of different sites run side by side
+func startProfile(profile common.SiteProfile, authCipher *services.AuthCipher, logger arbor.ILogger) (*handlers.Profile, *bolt.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(profile.DatabasePath), 0700); err != nil {
+		return nil, nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+	db, err := bolt.Open(profile.DatabasePath, 0600, &bolt.Options{Timeout: databaseOpenTimeout})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Any failure from here on must close the database again
+	site, err := buildProfile(db, profile, authCipher, logger)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return site, db, nil
+}
+
+// buildProfile creates the services and handlers of one site profile on its open database
+func buildProfile(db *bolt.DB, profile common.SiteProfile, authCipher *services.AuthCipher, logger arbor.ILogger) (*handlers.Profile, error) {
+	config := &profile.Scraper
+
+	// Credentials in the auth bucket are encrypted at rest and bound to this profile; plain or
+	// rotated-out records are migrated first
+	authCipher = authCipher.ForProfile(profile.Name)
+	if err := services.MigrateAuthRecords(db, authCipher, logger); err != nil {
+		return nil, fmt.Errorf("failed to migrate auth records: %w", err)
+	}
+
+	// Initialize centralized AuthService (shared by all scrapers)
+	// An API token is validated now, so bad credentials stop startup rather than the first scrape
+	var authService interfaces.AuthService
+	var oauthAuthorizer interfaces.OAuthAuthorizer
+	var extensionService *services.AtlassianAuthService
+	var err error
+	switch config.AuthMethod {
+	case "api_token":
+		authService, err = services.NewAPITokenAuthService(config, logger)
+	case "oauth":
+		var oauthService *services.OAuthAuthService
+		oauthService, err = services.NewOAuthAuthService(db, authCipher, config, logger)
+		authService, oauthAuthorizer = oauthService, oauthService
+	default:
+		extensionService, err = services.NewAtlassianAuthService(db, authCipher, logger)
+		authService = extensionService
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize AuthService: %w", err)
+	}
+
+	// Validate credentials against Jira and Confluence rather than trusting that they exist
+	authValidator := services.NewAuthValidator(authService, logger)
+
+	// Initialize Jira service (shares DB and AuthService)
+	jiraService, err := services.NewJiraScraper(db, authService, &config.Jira, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Jira service: %w", err)
+	}
+
+	// Initialize Confluence service (shares DB and AuthService)
+	confluenceService, err := services.NewConfluenceScraperWithDB(db, authService, &config.Confluence, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Confluence service: %w", err)
+	}
+
+	// Both scrapers target Cloud or Server/Data Center per the configured flavor
+	jiraService.SetFlavor(config.Flavor)
+	confluenceService.SetFlavor(config.Flavor)
+
+	// Initialize attachment store (shared by both scrapers)
+	attachmentService, err := services.NewAttachmentService(db, authService, &config.Attachments, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize attachment service: %w", err)
+	}
+	jiraService.SetAttachmentStore(attachmentService)
+	confluenceService.SetAttachmentStore(attachmentService)
+
+	// Initialize saved queries (runs through both scrapers)
+	queryService, err := services.NewQueryService(db, jiraService, confluenceService, config.Queries, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize query service: %w", err)
+	}
+
+	// Initialize handlers
+	apiHandler := handlers.NewAPIHandler()
+	uiHandler := handlers.NewUIHandler(jiraService, confluenceService)
+	wsHandler := handlers.NewWebSocketHandler()
+	scraperHandler := handlers.NewScraperHandler(authService, jiraService, confluenceService, wsHandler)
+	dataHandler := handlers.NewDataHandler(jiraService, confluenceService)
+	collectorHandler := handlers.NewCollectorHandler(jiraService, confluenceService, logger)
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService)
+	graphHandler := handlers.NewGraphHandler(jiraService)
+	queryHandler := handlers.NewQueryHandler(queryService, authService)
+	oauthHandler := handlers.NewOAuthHandler(oauthAuthorizer)
+	authHandler := handlers.NewAuthHandler(authValidator)
+
+	// Captured sessions, with the cookies Atlassian rotates, are kept so a working one can be restored
+	if extensionService != nil {
+		authHandler.SetAuthHistory(extensionService)
+	}
+
+	// Set UI logger for services
+	jiraService.SetUILogger(wsHandler)
+	confluenceService.SetUILogger(wsHandler)
+
+	// Set auth loader for WebSocket handler (so it can send auth on connect)
+	wsHandler.SetAuthLoader(authService)
+
+	// Show validated auth state in status updates, and re-validate on every capture
+	wsHandler.SetAuthStatusProvider(authValidator)
+	uiHandler.SetAuthStatusProvider(authValidator)
+	scraperHandler.SetAuthValidator(authValidator)
+	authValidator.Start()
+
+	// Load stored authentication if available (just to log status)
+	if _, err := authService.LoadAuth(); err == nil {
+		logger.Info().Str("profile", profile.Name).Msg("Loaded stored authentication from database")
+	} else {
+		logger.Debug().Str("profile", profile.Name).Err(err).Msg("No stored authentication found")
+	}
+
+	// Start WebSocket status broadcaster and log streamer
+	// The server log holds every profile's entries, so only the default profile's UI streams it; the
+	// others still see their own scrape progress
+	wsHandler.StartStatusBroadcaster()
+	if profile.Name == common.DefaultProfileName {
+		wsHandler.StartLogStreamer()
+	}
+
+	mux := http.NewServeMux()
+
+	// UI routes
+	mux.HandleFunc("/", uiHandler.IndexHandler)
+	mux.HandleFunc("/jira", uiHandler.JiraPageHandler)
+	mux.HandleFunc("/confluence", uiHandler.ConfluencePageHandler)
+	mux.HandleFunc("/static/common.css", uiHandler.StaticFileHandler)
+	mux.HandleFunc("/static/profiles.js", uiHandler.StaticFileHandler)
+	mux.HandleFunc("/favicon.ico", uiHandler.StaticFileHandler)
+	mux.HandleFunc("/ui/status", uiHandler.StatusHandler)
+	mux.HandleFunc("/ui/parser-status", uiHandler.ParserStatusHandler)
+
+	// WebSocket route
+	mux.HandleFunc("/ws", wsHandler.HandleWebSocket)
+
+	// API routes
+	mux.HandleFunc("/api/auth", scraperHandler.AuthUpdateHandler)
+	mux.HandleFunc("/api/auth/status", authHandler.AuthStatusHandler)
+	mux.HandleFunc("/api/auth/history", authHandler.AuthHistoryHandler)
+	mux.HandleFunc("/api/auth/history/{id}/restore", authHandler.RestoreAuthHandler)
+	mux.HandleFunc("/api/oauth/start", oauthHandler.StartHandler)
+	mux.HandleFunc("/api/oauth/callback", oauthHandler.CallbackHandler)
+	mux.HandleFunc("/api/scrape", scraperHandler.ScrapeHandler)
+	mux.HandleFunc("/api/scrape/projects", scraperHandler.ScrapeProjectsHandler)
+	mux.HandleFunc("/api/scrape/boards", scraperHandler.ScrapeBoardsHandler)
+	mux.HandleFunc("/api/scrape/spaces", scraperHandler.ScrapeSpacesHandler)
+	mux.HandleFunc("/api/projects/refresh-cache", scraperHandler.RefreshProjectsCacheHandler)
+	mux.HandleFunc("/api/projects/get-issues", scraperHandler.GetProjectIssuesHandler)
+	mux.HandleFunc("/api/spaces/refresh-cache", scraperHandler.RefreshSpacesCacheHandler)
+	mux.HandleFunc("/api/spaces/get-pages", scraperHandler.GetSpacePagesHandler)
+	mux.HandleFunc("/api/data/clear-all", scraperHandler.ClearAllDataHandler)
+	mux.HandleFunc("/api/data/jira", dataHandler.GetJiraDataHandler)
+	mux.HandleFunc("/api/data/jira/issues", dataHandler.GetJiraIssuesHandler)
+	mux.HandleFunc("/api/data/jira/issues/{key}/history", dataHandler.GetIssueHistoryHandler)
+	mux.HandleFunc("/api/data/jira/boards", dataHandler.GetJiraBoardsHandler)
+	mux.HandleFunc("/api/data/jira/boards/{id}/sprints", dataHandler.GetBoardSprintsHandler)
+	mux.HandleFunc("/api/data/jira/prune-reports", dataHandler.GetJiraPruneReportsHandler)
+	mux.HandleFunc("/api/data/jira/tombstones", dataHandler.GetJiraTombstonesHandler)
+	mux.HandleFunc("/api/data/confluence", dataHandler.GetConfluenceDataHandler)
+	mux.HandleFunc("/api/data/confluence/pages", dataHandler.GetConfluencePagesHandler)
+	mux.HandleFunc("/api/data/confluence/spaces/{key}/tree", dataHandler.GetSpaceTreeHandler)
+	mux.HandleFunc("/api/data/confluence/pages/{id}/versions", dataHandler.GetPageVersionsHandler)
+	mux.HandleFunc("/api/data/confluence/pages/{id}/diff", dataHandler.GetPageDiffHandler)
+	mux.HandleFunc("/api/data/confluence/prune-reports", dataHandler.GetConfluencePruneReportsHandler)
+	mux.HandleFunc("/api/data/confluence/tombstones", dataHandler.GetConfluenceTombstonesHandler)
+	mux.HandleFunc("/api/graph/issues/{key}", graphHandler.GetIssueGraphHandler)
+	mux.HandleFunc("/api/attachments/{id}", attachmentHandler.GetAttachmentHandler)
+	mux.HandleFunc("/api/queries", queryHandler.QueriesHandler)
+	mux.HandleFunc("/api/queries/{name}", queryHandler.SavedQueryHandler)
+	mux.HandleFunc("/api/queries/{name}/run", queryHandler.RunQueryHandler)
+	mux.HandleFunc("/api/collector/projects", collectorHandler.GetProjectsHandler)
+	mux.HandleFunc("/api/collector/spaces", collectorHandler.GetSpacesHandler)
+	mux.HandleFunc("/api/collector/issues", collectorHandler.GetIssuesHandler)
+	mux.HandleFunc("/api/collector/pages", collectorHandler.GetPagesHandler)
+	mux.HandleFunc("/api/version", apiHandler.VersionHandler)
+	mux.HandleFunc("/api/health", apiHandler.HealthHandler)
+
+	// 404 handler for unmatched API routes
+	mux.HandleFunc("/api/", apiHandler.NotFoundHandler)
+
+	logger.Info().Str("profile", profile.Name).Str("baseURL", config.BaseURL).Str("authMethod", config.AuthMethod).Str("database", profile.DatabasePath).Msg("Site profile ready")
+
+	return &handlers.Profile{
+		Name:       profile.Name,
+		BaseURL:    config.BaseURL,
+		AuthMethod: config.AuthMethod,
+		Handler:    mux,
+	}, nil
+}
@@ -14,6 +14,34 @@ type Config struct {
 	Scraper ScraperConfig `toml:"scraper"`
 	Storage StorageConfig `toml:"storage"`
 	Logging LoggingConfig `toml:"logging"`
+	// Profiles are further Atlassian sites, each with its own auth and database, alongside the default one
+	Profiles []ProfileConfig `toml:"profiles"`
+}
+
+// DefaultProfileName names the site configured by [scraper] and [storage] themselves
+const DefaultProfileName = "default"
+
+type ProfileConfig struct {
+	// Name selects the profile with ?profile=, the X-Aktis-Profile header or the UI
+	Name string `toml:"name"`
+	// BaseURL, AuthMethod and Flavor override [scraper] for this site, empty ones are inherited
+	BaseURL    string `toml:"base_url"`
+	AuthMethod string `toml:"auth_method"`
+	Flavor     string `toml:"flavor"`
+	// Credentials are never inherited, so one tenant's token is never sent to another tenant's site
+	Email    string `toml:"email"`
+	APIToken string `toml:"api_token"`
+	// OAuth needs the profile's own client_id and client_secret; redirect_url and scopes are inherited when empty
+	OAuth OAuthConfig `toml:"oauth"`
+	// DatabasePath defaults to profiles/<name>/ next to storage database_path, keeping each site's data apart
+	DatabasePath string `toml:"database_path"`
+}
+
+// SiteProfile is a profile with its settings resolved against [scraper] and [storage]
+type SiteProfile struct {
+	Name         string
+	DatabasePath string
+	Scraper      ScraperConfig
 }
 
 type ParserConfig struct {
@@ -23,13 +51,41 @@ type ParserConfig struct {
 }
 
 type ScraperConfig struct {
-	AuthMethod     string           `toml:"auth_method"`
-	BaseURL        string           `toml:"base_url"`
-	TimeoutSeconds int              `toml:"timeout_seconds"`
-	RateLimitMs    int              `toml:"rate_limit_ms"`
-	Targets        TargetsConfig    `toml:"targets"`
-	Jira           JiraConfig       `toml:"jira"`
-	Confluence     ConfluenceConfig `toml:"confluence"`
+	// AuthMethod is "extension" (cookies captured by the browser extension), "api_token" (Email and APIToken)
+	// or "oauth" (an OAuth 2.0 authorization started from /api/oauth/start)
+	AuthMethod string `toml:"auth_method"`
+	BaseURL    string `toml:"base_url"`
+	// Email and APIToken are the Atlassian account credentials used with auth_method "api_token"
+	Email    string `toml:"email"`
+	APIToken string `toml:"api_token"`
+	// Flavor is "cloud", "datacenter" (Server and Data Center) or "auto" to detect it from serverInfo
+	Flavor         string            `toml:"flavor"`
+	TimeoutSeconds int               `toml:"timeout_seconds"`
+	RateLimitMs    int               `toml:"rate_limit_ms"`
+	Targets        TargetsConfig     `toml:"targets"`
+	Jira           JiraConfig        `toml:"jira"`
+	Confluence     ConfluenceConfig  `toml:"confluence"`
+	Attachments    AttachmentsConfig `toml:"attachments"`
+	// Queries are saved JQL and CQL queries seeded into the saved_queries bucket on startup
+	Queries []SavedQueryConfig `toml:"queries"`
+	OAuth   OAuthConfig        `toml:"oauth"`
+}
+
+type OAuthConfig struct {
+	// ClientID and ClientSecret identify the OAuth 2.0 (3LO) app registered in the Atlassian developer console
+	ClientID     string `toml:"client_id"`
+	ClientSecret string `toml:"client_secret"`
+	// RedirectURL must match the app's callback URL and point at /api/oauth/callback on this service
+	RedirectURL string   `toml:"redirect_url"`
+	Scopes      []string `toml:"scopes"`
+}
+
+type SavedQueryConfig struct {
+	// Name identifies the query and is the tag added to matching records
+	Name string `toml:"name"`
+	// Type is "jql" for Jira issues or "cql" for Confluence pages and blog posts
+	Type  string `toml:"type"`
+	Query string `toml:"query"`
 }
 
 type TargetsConfig struct {
@@ -39,15 +95,53 @@ type TargetsConfig struct {
 
 type JiraConfig struct {
 	MaxResultsPerPage int `toml:"max_results_per_page"`
+	// Fields lists presets ("minimal", "standard", "all"), field ids or field names to request
+	Fields []string `toml:"fields"`
+	// IncludeChangelog fetches issue changelogs into the issue_history bucket on every sync
+	IncludeChangelog bool `toml:"include_changelog"`
+	// IncludeComments fetches issue comments into the issue_comments bucket on every sync
+	IncludeComments bool `toml:"include_comments"`
+	// IncludeWorklogs fetches issue worklogs into the issue_worklogs bucket on every sync
+	IncludeWorklogs bool `toml:"include_worklogs"`
 }
 
 type ConfluenceConfig struct {
 	MaxResultsPerPage int `toml:"max_results_per_page"`
+	// ContentTypes selects the content scraped from each space: "page" and/or "blogpost"
+	ContentTypes []string `toml:"content_types"`
+	// IncludeVersions fetches page version bodies into the confluence_versions bucket
+	IncludeVersions bool `toml:"include_versions"`
+	// MaxVersions caps the versions kept per page, newest first (0 = all)
+	MaxVersions int `toml:"max_versions"`
+	// IncludeComments fetches footer and inline comments with their replies into the confluence_comments bucket
+	IncludeComments bool `toml:"include_comments"`
+}
+
+type AttachmentsConfig struct {
+	Enabled bool `toml:"enabled"`
+	// Directory holds the blob store, defaults to "attachments" next to the database file
+	Directory string `toml:"directory"`
+	MaxSizeMB int    `toml:"max_size_mb"`
+	// AllowedMimeTypes supports "type/*" wildcards, an empty list allows everything
+	AllowedMimeTypes []string `toml:"allowed_mime_types"`
 }
 
 type StorageConfig struct {
 	DatabasePath  string `toml:"database_path"`
 	RetentionDays int    `toml:"retention_days"`
+	// Encryption protects the credentials stored in the auth bucket
+	Encryption EncryptionConfig `toml:"encryption"`
+}
+
+type EncryptionConfig struct {
+	Enabled bool `toml:"enabled"`
+	// KeyFile holds the base64 AES-256 key; the AUTH_ENCRYPTION_KEY variable takes precedence
+	KeyFile string `toml:"key_file"`
+	// KeyringFile is used on Linux when no key is set and is generated on first start,
+	// defaults to $XDG_DATA_HOME/keyrings/aktis-parser.key
+	KeyringFile string `toml:"keyring_file"`
+	// PreviousKeyFiles hold rotated-out keys, one per line; records they wrapped are re-wrapped on startup
+	PreviousKeyFiles []string `toml:"previous_key_files"`
 }
 
 type LoggingConfig struct {
@@ -58,6 +152,56 @@ type LoggingConfig struct {
 	MaxBackups int    `toml:"max_backups"`
 }
 
+// SiteProfiles returns the default site followed by the configured profiles
+func (c *Config) SiteProfiles() []SiteProfile {
+	profiles := []SiteProfile{{
+		Name:         DefaultProfileName,
+		DatabasePath: c.Storage.DatabasePath,
+		Scraper:      c.Scraper,
+	}}
+
+	for _, profile := range c.Profiles {
+		scraper := c.Scraper
+		if profile.BaseURL != "" {
+			scraper.BaseURL = profile.BaseURL
+		}
+		if profile.AuthMethod != "" {
+			scraper.AuthMethod = profile.AuthMethod
+		}
+		if profile.Flavor != "" {
+			scraper.Flavor = profile.Flavor
+		}
+
+		scraper.Email = profile.Email
+		scraper.APIToken = profile.APIToken
+		scraper.OAuth.ClientID = profile.OAuth.ClientID
+		scraper.OAuth.ClientSecret = profile.OAuth.ClientSecret
+		if profile.OAuth.RedirectURL != "" {
+			scraper.OAuth.RedirectURL = profile.OAuth.RedirectURL
+		}
+		if len(profile.OAuth.Scopes) > 0 {
+			scraper.OAuth.Scopes = profile.OAuth.Scopes
+		}
+		// A shared attachments directory would mix sites, so each profile gets its own below it
+		if scraper.Attachments.Directory != "" {
+			scraper.Attachments.Directory = filepath.Join(scraper.Attachments.Directory, profile.Name)
+		}
+
+		dbPath := profile.DatabasePath
+		if dbPath == "" {
+			dbPath = filepath.Join(filepath.Dir(c.Storage.DatabasePath), "profiles", profile.Name, filepath.Base(c.Storage.DatabasePath))
+		}
+
+		profiles = append(profiles, SiteProfile{
+			Name:         profile.Name,
+			DatabasePath: dbPath,
+			Scraper:      scraper,
+		})
+	}
+
+	return profiles
+}
+
 func DefaultConfig() *Config {
 	execPath, _ := os.Executable()
 	execDir := filepath.Dir(execPath)
@@ -75,6 +219,7 @@ func DefaultConfig() *Config {
 		Scraper: ScraperConfig{
 			AuthMethod:     "extension",
 			BaseURL:        "https://your-company.atlassian.net",
+			Flavor:         "auto",
 			TimeoutSeconds: 30,
 			RateLimitMs:    500,
 			Targets: TargetsConfig{
@@ -83,14 +228,35 @@ func DefaultConfig() *Config {
 			},
 			Jira: JiraConfig{
 				MaxResultsPerPage: 50,
+				Fields:            []string{"standard"},
 			},
 			Confluence: ConfluenceConfig{
 				MaxResultsPerPage: 25,
+				ContentTypes:      []string{"page"},
+				MaxVersions:       10,
+			},
+			Attachments: AttachmentsConfig{
+				Enabled:          false,
+				MaxSizeMB:        50,
+				AllowedMimeTypes: []string{"image/*", "application/pdf", "text/*"},
+			},
+			OAuth: OAuthConfig{
+				RedirectURL: "http://localhost:8080/api/oauth/callback",
+				Scopes: []string{
+					"read:jira-work",
+					"read:jira-user",
+					"read:confluence-content.all",
+					"read:confluence-space.summary",
+					"offline_access",
+				},
 			},
 		},
 		Storage: StorageConfig{
 			DatabasePath:  defaultDBPath,
 			RetentionDays: 90,
+			Encryption: EncryptionConfig{
+				Enabled: true,
+			},
 		},
 		Logging: LoggingConfig{
 			Level:      "info",
@@ -125,18 +291,18 @@ func LoadConfig(configFile string) (*Config, error) {
 			}
 		}
 
-		if configFile == "" {
-			return config, nil
-		}
 	}
 
-	data, err := os.ReadFile(configFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file %s: %w", configFile, err)
-	}
+	// Without a config file the defaults are configured through the environment alone
+	if configFile != "" {
+		data, err := os.ReadFile(configFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file %s: %w", configFile, err)
+		}
 
-	if err := toml.Unmarshal(data, config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+		if err := toml.Unmarshal(data, config); err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
 	}
 
 	_applyEnvOverrides(config)
@@ -166,6 +332,30 @@ func _applyEnvOverrides(config *Config) {
 	if baseURL := os.Getenv("SCRAPER_BASE_URL"); baseURL != "" {
 		config.Scraper.BaseURL = baseURL
 	}
+
+	if flavor := os.Getenv("SCRAPER_FLAVOR"); flavor != "" {
+		config.Scraper.Flavor = flavor
+	}
+
+	if authMethod := os.Getenv("SCRAPER_AUTH_METHOD"); authMethod != "" {
+		config.Scraper.AuthMethod = authMethod
+	}
+
+	if email := os.Getenv("SCRAPER_EMAIL"); email != "" {
+		config.Scraper.Email = email
+	}
+
+	if apiToken := os.Getenv("SCRAPER_API_TOKEN"); apiToken != "" {
+		config.Scraper.APIToken = apiToken
+	}
+
+	if clientID := os.Getenv("SCRAPER_OAUTH_CLIENT_ID"); clientID != "" {
+		config.Scraper.OAuth.ClientID = clientID
+	}
+
+	if clientSecret := os.Getenv("SCRAPER_OAUTH_CLIENT_SECRET"); clientSecret != "" {
+		config.Scraper.OAuth.ClientSecret = clientSecret
+	}
 }
 
 func (c *Config) Validate() error {
@@ -201,6 +391,10 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid log output: %s", c.Logging.Output)
 	}
 
+	if err := c.Scraper.validateSite(); err != nil {
+		return err
+	}
+
 	if c.Scraper.TimeoutSeconds <= 0 {
 		c.Scraper.TimeoutSeconds = 30
 	}
@@ -209,9 +403,155 @@ func (c *Config) Validate() error {
 		c.Scraper.RateLimitMs = 0
 	}
 
+	if c.Scraper.Attachments.MaxSizeMB < 0 {
+		c.Scraper.Attachments.MaxSizeMB = 0
+	}
+
+	if c.Scraper.Confluence.MaxVersions < 0 {
+		c.Scraper.Confluence.MaxVersions = 0
+	}
+
+	if len(c.Scraper.Confluence.ContentTypes) == 0 {
+		c.Scraper.Confluence.ContentTypes = []string{"page"}
+	}
+	for _, contentType := range c.Scraper.Confluence.ContentTypes {
+		if contentType != "page" && contentType != "blogpost" {
+			return fmt.Errorf("invalid confluence content type: %s", contentType)
+		}
+	}
+
+	if len(c.Scraper.Jira.Fields) == 0 {
+		c.Scraper.Jira.Fields = []string{"standard"}
+	}
+
+	for _, query := range c.Scraper.Queries {
+		if err := ValidateSavedQuery(query.Name, query.Type, query.Query); err != nil {
+			return err
+		}
+	}
+
+	// Scopes a profile sets replace the inherited ones, so they need offline_access too
+	for i := range c.Profiles {
+		if len(c.Profiles[i].OAuth.Scopes) > 0 {
+			c.Profiles[i].OAuth.Scopes = withOfflineAccess(c.Profiles[i].OAuth.Scopes)
+		}
+	}
+
+	// Profiles inherit the validated [scraper] settings except credentials, which each profile must set itself
+	// Two sites on one database would also hang startup on the file lock, so every path must be distinct
+	names := map[string]bool{}
+	databases := map[string]string{}
+	for i, profile := range c.SiteProfiles() {
+		if i > 0 {
+			if err := ValidateProfileName(profile.Name); err != nil {
+				return err
+			}
+			if names[profile.Name] {
+				return fmt.Errorf("duplicate profile name: %s", profile.Name)
+			}
+			if err := profile.Scraper.validateSite(); err != nil {
+				return fmt.Errorf("profile %s: %w", profile.Name, err)
+			}
+		}
+		names[profile.Name] = true
+
+		dbPath, err := filepath.Abs(profile.DatabasePath)
+		if err != nil {
+			return fmt.Errorf("profile %s: invalid database_path: %w", profile.Name, err)
+		}
+		if other, ok := databases[dbPath]; ok {
+			return fmt.Errorf("profiles %s and %s share the database %s", other, profile.Name, dbPath)
+		}
+		databases[dbPath] = profile.Name
+	}
+
 	return nil
 }
 
+// validateSite checks the auth method and flavor, filling in their defaults
+func (s *ScraperConfig) validateSite() error {
+	switch s.AuthMethod {
+	case "":
+		s.AuthMethod = "extension"
+	case "extension":
+	case "api_token":
+		if s.BaseURL == "" || s.Email == "" || s.APIToken == "" {
+			return fmt.Errorf("auth_method api_token requires base_url, email and api_token")
+		}
+	case "oauth":
+		if s.OAuth.ClientID == "" || s.OAuth.ClientSecret == "" || s.OAuth.RedirectURL == "" {
+			return fmt.Errorf("auth_method oauth requires oauth client_id, client_secret and redirect_url")
+		}
+		s.OAuth.Scopes = withOfflineAccess(s.OAuth.Scopes)
+	default:
+		return fmt.Errorf("invalid scraper auth method: %s", s.AuthMethod)
+	}
+
+	switch s.Flavor {
+	case "":
+		s.Flavor = "auto"
+	case "auto", "cloud", "datacenter":
+	default:
+		return fmt.Errorf("invalid scraper flavor: %s", s.Flavor)
+	}
+
+	return nil
+}
+
+// withOfflineAccess adds offline_access to OAuth scopes that lack it
+// Without offline_access no refresh token is issued and the grant lapses within the hour
+func withOfflineAccess(scopes []string) []string {
+	for _, scope := range scopes {
+		if scope == "offline_access" {
+			return scopes
+		}
+	}
+	return append(scopes, "offline_access")
+}
+
+// ValidateProfileName checks a profile name, which is limited to letters, digits, "-" and "_"
+// since it is used in URLs, headers, cookies and directory names
+func ValidateProfileName(name string) error {
+	if name == "" {
+		return fmt.Errorf("profile name is required")
+	}
+	if name == DefaultProfileName {
+		return fmt.Errorf("profile name %s is reserved for the [scraper] site", name)
+	}
+	if !isIdentifier(name) {
+		return fmt.Errorf("invalid profile name: %s", name)
+	}
+	return nil
+}
+
+// ValidateSavedQuery checks a saved query's name, type and query text
+// Names are limited to letters, digits, "-" and "_" since they are used in URLs and as record tags
+func ValidateSavedQuery(name, queryType, query string) error {
+	if name == "" {
+		return fmt.Errorf("saved query name is required")
+	}
+	if !isIdentifier(name) {
+		return fmt.Errorf("invalid saved query name: %s", name)
+	}
+	if queryType != "jql" && queryType != "cql" {
+		return fmt.Errorf("invalid saved query type for %s: %s", name, queryType)
+	}
+	if query == "" {
+		return fmt.Errorf("saved query %s has no query", name)
+	}
+	return nil
+}
+
+// isIdentifier reports whether a name only has letters, digits, "-" and "_"
+func isIdentifier(name string) bool {
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
 func (c *Config) IsProduction() bool {
 	return c.Parser.Environment == "production"
 }
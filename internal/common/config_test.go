@@ -0,0 +1,157 @@
+package common
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSiteProfilesDoNotInheritCredentials(t *testing.T) {
+	config := DefaultConfig()
+	config.Scraper.Email = "default@example.com"
+	config.Scraper.APIToken = "default-token"
+	config.Scraper.OAuth.ClientID = "default-client"
+	config.Scraper.OAuth.ClientSecret = "default-secret"
+	config.Profiles = []ProfileConfig{{Name: "acme", BaseURL: "https://acme.atlassian.net"}}
+
+	profile := config.SiteProfiles()[1]
+	if profile.Scraper.BaseURL != "https://acme.atlassian.net" {
+		t.Errorf("BaseURL = %q, want the profile's", profile.Scraper.BaseURL)
+	}
+	if profile.Scraper.Email != "" || profile.Scraper.APIToken != "" {
+		t.Errorf("profile inherited api_token credentials: %q / %q", profile.Scraper.Email, profile.Scraper.APIToken)
+	}
+	if profile.Scraper.OAuth.ClientID != "" || profile.Scraper.OAuth.ClientSecret != "" {
+		t.Errorf("profile inherited the OAuth client: %q / %q", profile.Scraper.OAuth.ClientID, profile.Scraper.OAuth.ClientSecret)
+	}
+	if profile.Scraper.OAuth.RedirectURL != config.Scraper.OAuth.RedirectURL {
+		t.Errorf("RedirectURL = %q, want it inherited", profile.Scraper.OAuth.RedirectURL)
+	}
+}
+
+func TestValidateProfiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		scraper  func(*ScraperConfig)
+		profiles []ProfileConfig
+		wantErr  string
+	}{
+		{
+			name:     "extension profile",
+			profiles: []ProfileConfig{{Name: "acme", BaseURL: "https://acme.atlassian.net"}},
+		},
+		{
+			name: "api_token profile with its own credentials",
+			profiles: []ProfileConfig{{
+				Name: "acme", BaseURL: "https://acme.atlassian.net", AuthMethod: "api_token",
+				Email: "me@acme.com", APIToken: "acme-token",
+			}},
+		},
+		{
+			name:     "api_token profile without credentials",
+			profiles: []ProfileConfig{{Name: "acme", BaseURL: "https://acme.atlassian.net", AuthMethod: "api_token"}},
+			wantErr:  "profile acme: auth_method api_token requires",
+		},
+		{
+			name: "api_token inherited without credentials",
+			scraper: func(s *ScraperConfig) {
+				s.AuthMethod, s.Email, s.APIToken = "api_token", "default@example.com", "default-token"
+			},
+			profiles: []ProfileConfig{{Name: "acme", BaseURL: "https://acme.atlassian.net"}},
+			wantErr:  "profile acme: auth_method api_token requires",
+		},
+		{
+			name: "oauth profile without its own client",
+			scraper: func(s *ScraperConfig) {
+				s.OAuth.ClientID, s.OAuth.ClientSecret = "default-client", "default-secret"
+			},
+			profiles: []ProfileConfig{{Name: "acme", BaseURL: "https://acme.atlassian.net", AuthMethod: "oauth"}},
+			wantErr:  "profile acme: auth_method oauth requires",
+		},
+		{
+			name:     "reserved name",
+			profiles: []ProfileConfig{{Name: DefaultProfileName}},
+			wantErr:  "reserved",
+		},
+		{
+			name:     "invalid name",
+			profiles: []ProfileConfig{{Name: "a/b"}},
+			wantErr:  "invalid profile name",
+		},
+		{
+			name:     "duplicate name",
+			profiles: []ProfileConfig{{Name: "acme"}, {Name: "acme", DatabasePath: "other.db"}},
+			wantErr:  "duplicate profile name",
+		},
+		{
+			name:     "database shared with the default site",
+			profiles: []ProfileConfig{{Name: "acme", DatabasePath: "data/../scraper.db"}},
+			wantErr:  "share the database",
+		},
+		{
+			name:     "database shared between profiles",
+			profiles: []ProfileConfig{{Name: "a", DatabasePath: "shared.db"}, {Name: "b", DatabasePath: "./shared.db"}},
+			wantErr:  "profiles a and b share the database",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.Storage.DatabasePath = "scraper.db"
+			if tt.scraper != nil {
+				tt.scraper(&config.Scraper)
+			}
+			config.Profiles = tt.profiles
+
+			err := config.Validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Validate() = %v, want no error", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("Validate() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSiteProfilesDatabasePaths(t *testing.T) {
+	config := DefaultConfig()
+	config.Storage.DatabasePath = filepath.Join("data", "scraper.db")
+	config.Profiles = []ProfileConfig{{Name: "acme"}, {Name: "beta", DatabasePath: "beta.db"}}
+
+	profiles := config.SiteProfiles()
+	want := []string{
+		filepath.Join("data", "scraper.db"),
+		filepath.Join("data", "profiles", "acme", "scraper.db"),
+		"beta.db",
+	}
+	for i, profile := range profiles {
+		if profile.DatabasePath != want[i] {
+			t.Errorf("%s DatabasePath = %q, want %q", profile.Name, profile.DatabasePath, want[i])
+		}
+	}
+}
+
+func TestLoadConfigFromEnvironmentOnly(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("SCRAPER_BASE_URL", "https://acme.atlassian.net")
+	t.Setenv("SCRAPER_AUTH_METHOD", "api_token")
+	t.Setenv("SCRAPER_EMAIL", "ci@acme.com")
+	t.Setenv("SCRAPER_API_TOKEN", "ci-token")
+
+	config, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	scraper := config.Scraper
+	if scraper.AuthMethod != "api_token" || scraper.BaseURL != "https://acme.atlassian.net" || scraper.Email != "ci@acme.com" || scraper.APIToken != "ci-token" {
+		t.Errorf("scraper config = %q %q %q %q, want the environment's", scraper.AuthMethod, scraper.BaseURL, scraper.Email, scraper.APIToken)
+	}
+
+	// The environment alone is validated like a config file
+	t.Setenv("SCRAPER_API_TOKEN", "")
+	if _, err := LoadConfig(""); err == nil {
+		t.Error("LoadConfig accepted api_token auth without a token")
+	}
+}
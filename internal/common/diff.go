@@ -0,0 +1,193 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UnifiedDiff compares two texts line by line and returns a unified diff with
+// the given number of context lines. An empty string is returned for equal texts.
+func UnifiedDiff(fromName, toName, from, to string, context int) string {
+	a := splitLines(from)
+	b := splitLines(to)
+	ops := diffLines(a, b)
+
+	hunks := diffHunks(ops, context)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	for _, hunk := range hunks {
+		aStart, aLen, bStart, bLen := hunkRange(hunk)
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", formatRange(aStart, aLen), formatRange(bStart, bLen))
+		for _, op := range hunk {
+			switch op.kind {
+			case diffEqual:
+				out.WriteString(" " + a[op.a] + "\n")
+			case diffDelete:
+				out.WriteString("-" + a[op.a] + "\n")
+			case diffInsert:
+				out.WriteString("+" + b[op.b] + "\n")
+			}
+		}
+	}
+	return out.String()
+}
+
+type diffKind int
+
+const (
+	diffEqual diffKind = iota
+	diffDelete
+	diffInsert
+)
+
+// diffOp is one line of an edit script; a and b are the line indexes in each text
+type diffOp struct {
+	kind diffKind
+	a    int
+	b    int
+}
+
+// splitLines splits text into lines, ignoring the newline that ends the last line
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines computes a shortest edit script with Myers' algorithm
+// Each step only keeps the diagonals it can reach, so memory grows with the
+// square of the number of differences rather than with the text size
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	trace := [][]int{}
+
+	for d := 0; d <= max; d++ {
+		snapshot := make([]int, 2*d+1)
+		copy(snapshot, v[offset-d:offset+d+1])
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrackDiff(trace, n, m)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrackDiff walks the recorded steps back from the end to build the edit script
+func backtrackDiff(trace [][]int, n, m int) []diffOp {
+	ops := []diffOp{}
+	x, y := n, m
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := 0
+		if d > 0 {
+			prevX = at(prevK)
+		}
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{kind: diffEqual, a: x, b: y})
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, diffOp{kind: diffInsert, a: x, b: y})
+		} else {
+			x--
+			ops = append(ops, diffOp{kind: diffDelete, a: x, b: y})
+		}
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// diffHunks groups changes with their surrounding context, merging hunks whose context overlaps
+func diffHunks(ops []diffOp, context int) [][]diffOp {
+	hunks := [][]diffOp{}
+	start, end := -1, -1
+
+	for i, op := range ops {
+		if op.kind == diffEqual {
+			continue
+		}
+		if start >= 0 && i-context <= end {
+			end = min(i+context+1, len(ops))
+			continue
+		}
+		if start >= 0 {
+			hunks = append(hunks, ops[start:end])
+		}
+		start = max(i-context, 0)
+		end = min(i+context+1, len(ops))
+	}
+	if start >= 0 {
+		hunks = append(hunks, ops[start:end])
+	}
+	return hunks
+}
+
+// hunkRange returns the 0-based start and length of a hunk in each text
+func hunkRange(hunk []diffOp) (int, int, int, int) {
+	aStart, bStart := hunk[0].a, hunk[0].b
+	aLen, bLen := 0, 0
+	for _, op := range hunk {
+		if op.kind != diffInsert {
+			aLen++
+		}
+		if op.kind != diffDelete {
+			bLen++
+		}
+	}
+	return aStart, aLen, bStart, bLen
+}
+
+// formatRange formats a hunk range; empty ranges point at the line before them
+func formatRange(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
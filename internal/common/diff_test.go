@@ -0,0 +1,83 @@
+package common
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		context int
+		want    string
+	}{
+		{
+			name: "both empty",
+			want: "",
+		},
+		{
+			name: "identical",
+			from: "a\nb\nc\n",
+			to:   "a\nb\nc\n",
+			want: "",
+		},
+		{
+			name: "missing final newline is ignored",
+			from: "a\nb",
+			to:   "a\nb\n",
+			want: "",
+		},
+		{
+			name: "from empty",
+			to:   "a\nb\n",
+			want: "--- v1\n+++ v2\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "to empty",
+			from: "a\nb\n",
+			want: "--- v1\n+++ v2\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			name:    "insert only",
+			from:    "a\nb\nc\nd\n",
+			to:      "a\nb\nx\nc\nd\n",
+			context: 1,
+			want:    "--- v1\n+++ v2\n@@ -2,2 +2,3 @@\n b\n+x\n c\n",
+		},
+		{
+			name:    "delete only",
+			from:    "a\nb\nc\nd\n",
+			to:      "a\nc\nd\n",
+			context: 1,
+			want:    "--- v1\n+++ v2\n@@ -1,3 +1,2 @@\n a\n-b\n c\n",
+		},
+		{
+			name:    "replace at the start without context",
+			from:    "a\nb\n",
+			to:      "x\nb\n",
+			context: 0,
+			want:    "--- v1\n+++ v2\n@@ -1 +1 @@\n-a\n+x\n",
+		},
+		{
+			name:    "distant changes make separate hunks",
+			from:    "1\n2\n3\n4\n5\n6\n7\n8\n",
+			to:      "1\nx\n3\n4\n5\n6\ny\n8\n",
+			context: 1,
+			want:    "--- v1\n+++ v2\n@@ -1,3 +1,3 @@\n 1\n-2\n+x\n 3\n@@ -6,3 +6,3 @@\n 6\n-7\n+y\n 8\n",
+		},
+		{
+			name:    "overlapping context merges hunks",
+			from:    "1\n2\n3\n4\n5\n",
+			to:      "1\nx\n3\ny\n5\n",
+			context: 1,
+			want:    "--- v1\n+++ v2\n@@ -1,5 +1,5 @@\n 1\n-2\n+x\n 3\n-4\n+y\n 5\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff("v1", "v2", tt.from, tt.to, tt.context); got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
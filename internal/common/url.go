@@ -0,0 +1,15 @@
+package common
+
+import (
+	"net/url"
+	"strings"
+)
+
+// SiteHost returns the lower-cased host of a site URL, or "" when it has none
+func SiteHost(siteURL string) string {
+	parsed, err := url.Parse(siteURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Host)
+}
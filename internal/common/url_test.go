@@ -0,0 +1,19 @@
+package common
+
+import "testing"
+
+func TestSiteHost(t *testing.T) {
+	tests := map[string]string{
+		"https://Example.atlassian.net/":     "example.atlassian.net",
+		"https://example.atlassian.net/wiki": "example.atlassian.net",
+		"http://localhost:8080":              "localhost:8080",
+		"example.atlassian.net":              "",
+		"":                                   "",
+		"://bad":                             "",
+	}
+	for siteURL, want := range tests {
+		if got := SiteHost(siteURL); got != want {
+			t.Errorf("SiteHost(%q) = %q, want %q", siteURL, got, want)
+		}
+	}
+}
@@ -0,0 +1,478 @@
+package converters
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ADF (Atlassian Document Format) is the JSON tree Jira Cloud v3 uses for rich text
+// such as descriptions and comments. The renderers below accept the decoded JSON
+// (map[string]interface{}) and ignore node types they do not know, keeping their text.
+
+// IsADF reports whether a decoded JSON value is an ADF document
+func IsADF(value interface{}) bool {
+	node, ok := value.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	nodeType, _ := node["type"].(string)
+	_, hasContent := node["content"]
+	return nodeType == "doc" && hasContent
+}
+
+// ADFToMarkdown renders an ADF document as GitHub-flavored Markdown
+func ADFToMarkdown(doc interface{}) string {
+	r := &adfRenderer{markdown: true}
+	return strings.TrimSpace(r.blocks(adfContent(doc)))
+}
+
+// ADFToText renders an ADF document as plain text
+func ADFToText(doc interface{}) string {
+	r := &adfRenderer{markdown: false}
+	return strings.TrimSpace(r.blocks(adfContent(doc)))
+}
+
+// panelLabels maps ADF panel types to the label shown in front of the panel
+var panelLabels = map[string]string{
+	"info":    "Info",
+	"note":    "Note",
+	"tip":     "Tip",
+	"success": "Success",
+	"warning": "Warning",
+	"error":   "Error",
+}
+
+// markdownEscaper escapes characters that would otherwise be read as Markdown syntax
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"_", `\_`,
+	"`", "\\`",
+	"[", `\[`,
+	"]", `\]`,
+)
+
+type adfRenderer struct {
+	markdown bool
+}
+
+// blocks renders a list of block nodes separated by blank lines
+func (r *adfRenderer) blocks(nodes []map[string]interface{}) string {
+	parts := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		if text := r.block(node); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// block renders a single block node
+func (r *adfRenderer) block(node map[string]interface{}) string {
+	nodeType, _ := node["type"].(string)
+	attrs, _ := node["attrs"].(map[string]interface{})
+	content := adfContent(node)
+
+	switch nodeType {
+	case "paragraph":
+		return r.inlines(content)
+
+	case "heading":
+		text := r.inlines(content)
+		if !r.markdown {
+			return text
+		}
+		level := int(adfNumber(attrs["level"], 1))
+		if level < 1 || level > 6 {
+			level = 1
+		}
+		return strings.Repeat("#", level) + " " + text
+
+	case "bulletList":
+		items := make([]string, 0, len(content))
+		for _, item := range content {
+			items = append(items, r.listItem("- ", item))
+		}
+		return strings.Join(items, "\n")
+
+	case "orderedList":
+		start := int(adfNumber(attrs["order"], 1))
+		items := make([]string, 0, len(content))
+		for i, item := range content {
+			items = append(items, r.listItem(strconv.Itoa(start+i)+". ", item))
+		}
+		return strings.Join(items, "\n")
+
+	case "taskList":
+		items := make([]string, 0, len(content))
+		for _, item := range content {
+			itemType, _ := item["type"].(string)
+			if itemType == "taskList" {
+				items = append(items, indent(r.block(item), "  "))
+				continue
+			}
+			itemAttrs, _ := item["attrs"].(map[string]interface{})
+			prefix := "- [ ] "
+			if state, _ := itemAttrs["state"].(string); state == "DONE" {
+				prefix = "- [x] "
+			}
+			if !r.markdown {
+				prefix = strings.TrimPrefix(prefix, "- ")
+			}
+			items = append(items, prefix+r.inlines(adfContent(item)))
+		}
+		return strings.Join(items, "\n")
+
+	case "decisionList":
+		items := make([]string, 0, len(content))
+		for _, item := range content {
+			items = append(items, "- Decision: "+r.inlines(adfContent(item)))
+		}
+		return strings.Join(items, "\n")
+
+	case "codeBlock":
+		code := r.plainText(content)
+		if !r.markdown {
+			return code
+		}
+		language, _ := attrs["language"].(string)
+		return codeFence(code, language)
+
+	case "blockquote":
+		text := r.blocks(content)
+		if !r.markdown {
+			return text
+		}
+		return quote(text)
+
+	case "panel":
+		panelType, _ := attrs["panelType"].(string)
+		label, ok := panelLabels[panelType]
+		if !ok {
+			label = "Note"
+		}
+		text := r.blocks(content)
+		if !r.markdown {
+			return label + ": " + text
+		}
+		return quote("**" + label + ":** " + text)
+
+	case "expand", "nestedExpand":
+		title, _ := attrs["title"].(string)
+		text := r.blocks(content)
+		if title == "" {
+			return text
+		}
+		if r.markdown {
+			title = "**" + markdownEscaper.Replace(title) + "**"
+		}
+		return title + "\n\n" + text
+
+	case "rule":
+		if r.markdown {
+			return "---"
+		}
+		return ""
+
+	case "table":
+		return r.table(content)
+
+	case "mediaSingle", "mediaGroup":
+		items := make([]string, 0, len(content))
+		for _, media := range content {
+			if text := r.media(media); text != "" {
+				items = append(items, text)
+			}
+		}
+		return strings.Join(items, "\n")
+
+	case "media":
+		return r.media(node)
+
+	case "blockCard", "embedCard":
+		url, _ := attrs["url"].(string)
+		if r.markdown && url != "" {
+			return "<" + url + ">"
+		}
+		return url
+	}
+
+	// Unknown block nodes keep whatever their children render to
+	if len(content) > 0 {
+		if adfIsInline(content[0]) {
+			return r.inlines(content)
+		}
+		return r.blocks(content)
+	}
+	return ""
+}
+
+// listItem renders a list item, indenting continuation lines under the marker
+func (r *adfRenderer) listItem(marker string, item map[string]interface{}) string {
+	children := adfContent(item)
+	parts := make([]string, 0, len(children))
+	for _, child := range children {
+		if text := r.block(child); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return listMarker(marker, strings.Join(parts, "\n"))
+}
+
+// table renders a table as a GFM table, using the first row as the header
+func (r *adfRenderer) table(rows []map[string]interface{}) string {
+	lines := make([]string, 0, len(rows)+1)
+	columns := 0
+
+	for i, row := range rows {
+		cells := adfContent(row)
+		values := make([]string, 0, len(cells))
+		for _, cell := range cells {
+			text := r.blocks(adfContent(cell))
+			if r.markdown {
+				text = strings.ReplaceAll(text, "|", `\|`)
+				text = strings.ReplaceAll(strings.ReplaceAll(text, "\n\n", "<br>"), "\n", "<br>")
+			} else {
+				text = strings.ReplaceAll(text, "\n", " ")
+			}
+			values = append(values, text)
+		}
+
+		if !r.markdown {
+			lines = append(lines, strings.Join(values, " | "))
+			continue
+		}
+
+		if i == 0 {
+			columns = len(values)
+		}
+		for len(values) < columns {
+			values = append(values, "")
+		}
+		lines = append(lines, "| "+strings.Join(values, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", columns))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// media renders a media node as an image reference
+// Attachments are referenced by their media id since the download URL needs authentication
+func (r *adfRenderer) media(node map[string]interface{}) string {
+	attrs, _ := node["attrs"].(map[string]interface{})
+	alt, _ := attrs["alt"].(string)
+	target, _ := attrs["url"].(string)
+	if target == "" {
+		if id, _ := attrs["id"].(string); id != "" {
+			target = "media:" + id
+		}
+	}
+	if target == "" {
+		return ""
+	}
+
+	if !r.markdown {
+		if alt == "" {
+			return "[media]"
+		}
+		return "[media: " + alt + "]"
+	}
+	return "![" + markdownEscaper.Replace(alt) + "](" + target + ")"
+}
+
+// inlines renders a list of inline nodes
+func (r *adfRenderer) inlines(nodes []map[string]interface{}) string {
+	var b strings.Builder
+	for _, node := range nodes {
+		b.WriteString(r.inline(node))
+	}
+	return b.String()
+}
+
+// inline renders a single inline node
+func (r *adfRenderer) inline(node map[string]interface{}) string {
+	nodeType, _ := node["type"].(string)
+	attrs, _ := node["attrs"].(map[string]interface{})
+
+	switch nodeType {
+	case "text":
+		text, _ := node["text"].(string)
+		return r.marks(text, node["marks"])
+
+	case "hardBreak":
+		if r.markdown {
+			return "  \n"
+		}
+		return "\n"
+
+	case "mention":
+		text, _ := attrs["text"].(string)
+		if text == "" {
+			text, _ = attrs["id"].(string)
+		}
+		if !strings.HasPrefix(text, "@") {
+			text = "@" + text
+		}
+		return text
+
+	case "emoji":
+		if text, _ := attrs["text"].(string); text != "" {
+			return text
+		}
+		shortName, _ := attrs["shortName"].(string)
+		return shortName
+
+	case "inlineCard":
+		url, _ := attrs["url"].(string)
+		if r.markdown && url != "" {
+			return "<" + url + ">"
+		}
+		return url
+
+	case "date":
+		timestamp, err := strconv.ParseInt(fmt.Sprint(attrs["timestamp"]), 10, 64)
+		if err != nil {
+			return ""
+		}
+		return time.UnixMilli(timestamp).UTC().Format("2006-01-02")
+
+	case "status":
+		text, _ := attrs["text"].(string)
+		return "[" + strings.ToUpper(text) + "]"
+
+	case "mediaInline":
+		return r.media(node)
+
+	case "placeholder":
+		return ""
+	}
+
+	return r.inlines(adfContent(node))
+}
+
+// marks applies text marks such as strong, em, code and link
+func (r *adfRenderer) marks(text string, marks interface{}) string {
+	list := adfNodes(marks)
+
+	if !r.markdown {
+		for _, mark := range list {
+			if markType, _ := mark["type"].(string); markType == "link" {
+				markAttrs, _ := mark["attrs"].(map[string]interface{})
+				if href, _ := markAttrs["href"].(string); href != "" && href != text {
+					return text + " (" + href + ")"
+				}
+			}
+		}
+		return text
+	}
+
+	isCode := false
+	for _, mark := range list {
+		if markType, _ := mark["type"].(string); markType == "code" {
+			isCode = true
+		}
+	}
+	if isCode {
+		text = codeSpan(text)
+	} else {
+		text = markdownEscaper.Replace(text)
+	}
+
+	var href string
+	for _, mark := range list {
+		markType, _ := mark["type"].(string)
+		markAttrs, _ := mark["attrs"].(map[string]interface{})
+		switch markType {
+		case "strong":
+			text = "**" + text + "**"
+		case "em":
+			text = "*" + text + "*"
+		case "strike":
+			text = "~~" + text + "~~"
+		case "link":
+			href, _ = markAttrs["href"].(string)
+		}
+	}
+	if href != "" {
+		text = "[" + text + "](" + href + ")"
+	}
+	return text
+}
+
+// plainText concatenates the text of inline nodes without any formatting
+func (r *adfRenderer) plainText(nodes []map[string]interface{}) string {
+	var b strings.Builder
+	for _, node := range nodes {
+		if text, ok := node["text"].(string); ok {
+			b.WriteString(text)
+		} else if nodeType, _ := node["type"].(string); nodeType == "hardBreak" {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// adfContent returns the child nodes of a node
+func adfContent(node interface{}) []map[string]interface{} {
+	m, ok := node.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return adfNodes(m["content"])
+}
+
+// adfNodes converts a decoded JSON array into nodes, skipping other values
+func adfNodes(value interface{}) []map[string]interface{} {
+	items, _ := value.([]interface{})
+	nodes := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		if node, ok := item.(map[string]interface{}); ok {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// adfIsInline reports whether a node is an inline node
+func adfIsInline(node map[string]interface{}) bool {
+	switch nodeType, _ := node["type"].(string); nodeType {
+	case "text", "hardBreak", "mention", "emoji", "inlineCard", "date", "status", "mediaInline", "placeholder":
+		return true
+	}
+	return false
+}
+
+// adfNumber reads a numeric attribute, falling back to a default
+func adfNumber(value interface{}, fallback float64) float64 {
+	if n, ok := value.(float64); ok {
+		return n
+	}
+	return fallback
+}
+
+// indent prefixes every non-empty line of text
+func indent(text, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// quote turns text into a Markdown blockquote
+func quote(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = ">"
+		} else {
+			lines[i] = "> " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
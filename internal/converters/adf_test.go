@@ -0,0 +1,141 @@
+package converters
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// decodeADF decodes an ADF document the way stored issues hold it
+func decodeADF(t *testing.T, doc string) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal([]byte(doc), &value); err != nil {
+		t.Fatalf("invalid test document: %v", err)
+	}
+	return value
+}
+
+func TestADFToMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{
+			name: "paragraph with marks",
+			doc: `{"type":"doc","content":[{"type":"paragraph","content":[
+				{"type":"text","text":"bold","marks":[{"type":"strong"}]},
+				{"type":"text","text":" and "},
+				{"type":"text","text":"code","marks":[{"type":"code"}]},
+				{"type":"text","text":" and "},
+				{"type":"text","text":"a link","marks":[{"type":"link","attrs":{"href":"https://example.com"}}]}]}]}`,
+			want: "**bold** and `code` and [a link](https://example.com)",
+		},
+		{
+			name: "markdown characters are escaped",
+			doc:  `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"a_b*c[d]"}]}]}`,
+			want: `a\_b\*c\[d\]`,
+		},
+		{
+			name: "heading",
+			doc:  `{"type":"doc","content":[{"type":"heading","attrs":{"level":2},"content":[{"type":"text","text":"Title"}]}]}`,
+			want: "## Title",
+		},
+		{
+			name: "nested lists",
+			doc: `{"type":"doc","content":[{"type":"bulletList","content":[
+				{"type":"listItem","content":[
+					{"type":"paragraph","content":[{"type":"text","text":"one"}]},
+					{"type":"orderedList","attrs":{"order":3},"content":[
+						{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"three"}]}]},
+						{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"four"}]}]}]}]},
+				{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"two"}]}]}]}]}`,
+			want: "- one\n  3. three\n  4. four\n- two",
+		},
+		{
+			name: "table with a pipe and a short row",
+			doc: `{"type":"doc","content":[{"type":"table","content":[
+				{"type":"tableRow","content":[
+					{"type":"tableHeader","content":[{"type":"paragraph","content":[{"type":"text","text":"A"}]}]},
+					{"type":"tableHeader","content":[{"type":"paragraph","content":[{"type":"text","text":"B"}]}]}]},
+				{"type":"tableRow","content":[
+					{"type":"tableCell","content":[{"type":"paragraph","content":[{"type":"text","text":"x|y"}]}]}]}]}]}`,
+			want: "| A | B |\n| --- | --- |\n| x\\|y |  |",
+		},
+		{
+			name: "code block",
+			doc:  `{"type":"doc","content":[{"type":"codeBlock","attrs":{"language":"go"},"content":[{"type":"text","text":"fmt.Println(\"*\")"}]}]}`,
+			want: "```go\nfmt.Println(\"*\")\n```",
+		},
+		{
+			name: "panel",
+			doc:  `{"type":"doc","content":[{"type":"panel","attrs":{"panelType":"warning"},"content":[{"type":"paragraph","content":[{"type":"text","text":"Careful"}]}]}]}`,
+			want: "> **Warning:** Careful",
+		},
+		{
+			name: "task list",
+			doc: `{"type":"doc","content":[{"type":"taskList","content":[
+				{"type":"taskItem","attrs":{"state":"DONE"},"content":[{"type":"text","text":"done"}]},
+				{"type":"taskItem","attrs":{"state":"TODO"},"content":[{"type":"text","text":"todo"}]}]}]}`,
+			want: "- [x] done\n- [ ] todo",
+		},
+		{
+			name: "mention, status and media",
+			doc: `{"type":"doc","content":[
+				{"type":"paragraph","content":[{"type":"mention","attrs":{"text":"Jane"}},{"type":"text","text":" "},{"type":"status","attrs":{"text":"done"}}]},
+				{"type":"mediaSingle","content":[{"type":"media","attrs":{"id":"abc","alt":"shot"}}]}]}`,
+			want: "@Jane [DONE]\n\n![shot](media:abc)",
+		},
+		{
+			name: "unknown nodes keep their text",
+			doc:  `{"type":"doc","content":[{"type":"bodiedExtension","content":[{"type":"paragraph","content":[{"type":"text","text":"inside"}]}]}]}`,
+			want: "inside",
+		},
+		{
+			name: "malformed content is skipped",
+			doc:  `{"type":"doc","content":[42,"text",{"type":"paragraph","content":"not a list"},{"type":"paragraph","content":[{"type":"text","text":"kept"}]}]}`,
+			want: "kept",
+		},
+		{
+			name: "not a document",
+			doc:  `"plain string"`,
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ADFToMarkdown(decodeADF(t, tt.doc)); got != tt.want {
+				t.Errorf("ADFToMarkdown() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestADFToText(t *testing.T) {
+	doc := `{"type":"doc","content":[
+		{"type":"heading","attrs":{"level":1},"content":[{"type":"text","text":"Title"}]},
+		{"type":"paragraph","content":[{"type":"text","text":"see "},{"type":"text","text":"docs","marks":[{"type":"link","attrs":{"href":"https://example.com"}}]}]},
+		{"type":"codeBlock","content":[{"type":"text","text":"a*b"}]}]}`
+	want := "Title\n\nsee docs (https://example.com)\n\na*b"
+	if got := ADFToText(decodeADF(t, doc)); got != want {
+		t.Errorf("ADFToText() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestIsADF(t *testing.T) {
+	tests := []struct {
+		doc  string
+		want bool
+	}{
+		{`{"type":"doc","version":1,"content":[]}`, true},
+		{`{"type":"paragraph","content":[]}`, false},
+		{`{"type":"doc"}`, false},
+		{`"text"`, false},
+	}
+	for _, tt := range tests {
+		if got := IsADF(decodeADF(t, tt.doc)); got != tt.want {
+			t.Errorf("IsADF(%s) = %v, want %v", tt.doc, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,547 @@
+package converters
+
+import (
+	"encoding/xml"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Confluence storage format is XHTML extended with ac: (macros, links, images) and
+// ri: (resource identifiers) elements. Pages are fragments without namespace
+// declarations, so they are parsed leniently with the HTML entity table.
+
+// StorageToMarkdown converts Confluence storage-format XHTML into Markdown
+// Malformed markup is converted as far as it parses; unknown macros keep their body text
+func StorageToMarkdown(storage string) string {
+	root := parseStorage(storage)
+	r := &storageRenderer{}
+	return strings.TrimSpace(collapseBlankLines.ReplaceAllString(r.blocks(root.children, "\n\n"), "\n\n"))
+}
+
+// collapseBlankLines squeezes the blank lines left behind by dropped elements
+var collapseBlankLines = regexp.MustCompile(`\n{3,}`)
+
+// whitespaceRun matches the whitespace collapsed to a single space outside <pre>
+var whitespaceRun = regexp.MustCompile(`\s+`)
+
+// storageNode is an element or text node of a parsed storage document
+type storageNode struct {
+	name     string // "prefix:local" for ac:/ri: elements, empty for text nodes
+	attrs    map[string]string
+	children []*storageNode
+	text     string
+}
+
+// attr returns an attribute by its qualified name, e.g. "ac:name" or "ri:content-title"
+func (n *storageNode) attr(name string) string {
+	return n.attrs[name]
+}
+
+// child returns the first direct child element with the given name
+func (n *storageNode) child(name string) *storageNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// textContent returns the concatenated text of a node and its descendants
+func (n *storageNode) textContent() string {
+	if n.name == "" {
+		return n.text
+	}
+	var b strings.Builder
+	for _, c := range n.children {
+		b.WriteString(c.textContent())
+	}
+	return b.String()
+}
+
+// parameter returns the value of an ac:parameter of a macro
+func (n *storageNode) parameter(name string) string {
+	for _, c := range n.children {
+		if c.name == "ac:parameter" && c.attr("ac:name") == name {
+			return strings.TrimSpace(c.textContent())
+		}
+	}
+	return ""
+}
+
+// parseStorage builds a node tree from a storage-format fragment
+func parseStorage(storage string) *storageNode {
+	root := &storageNode{name: "root"}
+
+	decoder := xml.NewDecoder(strings.NewReader("<root>" + storage + "</root>"))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	// The wrapping <root> element itself is represented by root
+	if _, err := decoder.RawToken(); err != nil {
+		return root
+	}
+
+	// RawToken leaves matching end tags to the loop below; Token would instead close every open
+	// element at a stray end tag and then fail, dropping the rest of the page
+	stack := []*storageNode{root}
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF || err != nil {
+			break
+		}
+
+		parent := stack[len(stack)-1]
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &storageNode{name: qualifiedName(t.Name), attrs: make(map[string]string, len(t.Attr))}
+			for _, a := range t.Attr {
+				node.attrs[qualifiedName(a.Name)] = a.Value
+			}
+			parent.children = append(parent.children, node)
+			if !voidElements[node.name] {
+				stack = append(stack, node)
+			}
+		case xml.EndElement:
+			// Unbalanced end tags only close the element they match
+			name := qualifiedName(t.Name)
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].name == name {
+					stack = stack[:i]
+					break
+				}
+			}
+		case xml.CharData:
+			parent.children = append(parent.children, &storageNode{text: string(t)})
+		}
+	}
+	return root
+}
+
+// voidElements lists HTML elements that may appear without a closing tag, so they never hold children
+// xml.HTMLAutoClose cannot be used as it includes "link" and "param", which would match ac:link and ac:parameter
+var voidElements = map[string]bool{"br": true, "hr": true, "img": true, "col": true, "area": true, "input": true, "wbr": true}
+
+// qualifiedName turns an xml.Name back into the "prefix:local" form used in the source
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return strings.ToLower(name.Local)
+	}
+	return name.Space + ":" + name.Local
+}
+
+// storageBlockElements lists the elements rendered as blocks rather than inline
+var storageBlockElements = map[string]bool{
+	"p": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "li": true, "pre": true, "blockquote": true, "hr": true,
+	"table": true, "thead": true, "tbody": true, "tfoot": true, "tr": true,
+	"div": true, "section": true,
+	"ac:task-list": true, "ac:layout": true, "ac:layout-section": true, "ac:layout-cell": true,
+	"ac:rich-text-body": true, "ac:plain-text-body": true,
+}
+
+// inlineMacros lists macros rendered inline with the surrounding text
+var inlineMacros = map[string]bool{
+	"status": true,
+	"jira":   true,
+	"anchor": true,
+}
+
+// macroPanelLabels maps panel-like macros to the label shown in front of the panel
+var macroPanelLabels = map[string]string{
+	"info":    "Info",
+	"note":    "Note",
+	"tip":     "Tip",
+	"warning": "Warning",
+	"panel":   "Note",
+}
+
+// droppedMacros lists macros that only make sense inside Confluence
+var droppedMacros = map[string]bool{
+	"toc":              true,
+	"children":         true,
+	"pagetree":         true,
+	"recently-updated": true,
+	"contentbylabel":   true,
+}
+
+type storageRenderer struct{}
+
+// isBlock reports whether a node renders as a block
+func isBlock(n *storageNode) bool {
+	if n.name == "ac:structured-macro" || n.name == "ac:macro" {
+		return !inlineMacros[n.attr("ac:name")]
+	}
+	return storageBlockElements[n.name]
+}
+
+// blocks renders mixed content, grouping runs of inline nodes into paragraphs
+func (r *storageRenderer) blocks(nodes []*storageNode, separator string) string {
+	parts := []string{}
+	var inline strings.Builder
+
+	flush := func() {
+		if text := strings.TrimSpace(inline.String()); text != "" {
+			parts = append(parts, text)
+		}
+		inline.Reset()
+	}
+
+	for _, n := range nodes {
+		if n.name != "" && isBlock(n) {
+			flush()
+			if text := r.block(n); text != "" {
+				parts = append(parts, text)
+			}
+			continue
+		}
+		inline.WriteString(r.inline(n))
+	}
+	flush()
+
+	return strings.Join(parts, separator)
+}
+
+// block renders a block element
+func (r *storageRenderer) block(n *storageNode) string {
+	switch n.name {
+	case "p", "div", "section", "ac:layout", "ac:layout-section", "ac:layout-cell", "ac:rich-text-body":
+		return r.blocks(n.children, "\n\n")
+
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level, _ := strconv.Atoi(n.name[1:])
+		return strings.Repeat("#", level) + " " + strings.TrimSpace(r.inlines(n.children))
+
+	case "ul", "ol":
+		items := []string{}
+		number := 1
+		if start, err := strconv.Atoi(n.attr("start")); err == nil {
+			number = start
+		}
+		for _, c := range n.children {
+			if c.name != "li" {
+				continue
+			}
+			marker := "- "
+			if n.name == "ol" {
+				marker = strconv.Itoa(number) + ". "
+				number++
+			}
+			items = append(items, listMarker(marker, r.blocks(c.children, "\n")))
+		}
+		return strings.Join(items, "\n")
+
+	case "li":
+		return listMarker("- ", r.blocks(n.children, "\n"))
+
+	case "pre", "ac:plain-text-body":
+		return codeFence(n.textContent(), "")
+
+	case "blockquote":
+		return quote(r.blocks(n.children, "\n\n"))
+
+	case "hr":
+		return "---"
+
+	case "table":
+		return r.table(n)
+
+	case "ac:task-list":
+		return r.taskList(n)
+
+	case "ac:structured-macro", "ac:macro":
+		return r.macro(n)
+	}
+
+	return r.blocks(n.children, "\n\n")
+}
+
+// macro renders a block macro
+func (r *storageRenderer) macro(n *storageNode) string {
+	name := n.attr("ac:name")
+	if droppedMacros[name] {
+		return ""
+	}
+
+	body := ""
+	if rich := n.child("ac:rich-text-body"); rich != nil {
+		body = r.blocks(rich.children, "\n\n")
+	} else if plain := n.child("ac:plain-text-body"); plain != nil {
+		body = plain.textContent()
+	}
+
+	switch name {
+	case "code", "noformat":
+		return codeFence(body, n.parameter("language"))
+
+	case "info", "note", "tip", "warning", "panel":
+		label := macroPanelLabels[name]
+		if title := n.parameter("title"); title != "" {
+			label = title
+		}
+		return quote("**" + markdownEscaper.Replace(label) + ":** " + body)
+
+	case "expand":
+		title := n.parameter("title")
+		if title == "" {
+			title = "Click here to expand..."
+		}
+		return "**" + markdownEscaper.Replace(title) + "**\n\n" + body
+	}
+
+	return body
+}
+
+// inlineMacro renders a macro that appears inside text
+func (r *storageRenderer) inlineMacro(n *storageNode) string {
+	switch n.attr("ac:name") {
+	case "status":
+		return "[" + strings.ToUpper(n.parameter("title")) + "]"
+	case "jira":
+		if key := n.parameter("key"); key != "" {
+			return "[" + key + "](jira:" + key + ")"
+		}
+		if jql := n.parameter("jqlQuery"); jql != "" {
+			return "Jira issues: `" + jql + "`"
+		}
+	}
+	return ""
+}
+
+// taskList renders an ac:task-list as a Markdown task list
+func (r *storageRenderer) taskList(n *storageNode) string {
+	items := []string{}
+	for _, task := range n.children {
+		if task.name != "ac:task" {
+			continue
+		}
+		marker := "- [ ] "
+		if status := task.child("ac:task-status"); status != nil && strings.TrimSpace(status.textContent()) == "complete" {
+			marker = "- [x] "
+		}
+		body := ""
+		if taskBody := task.child("ac:task-body"); taskBody != nil {
+			body = r.blocks(taskBody.children, "\n")
+		}
+		items = append(items, listMarker(marker, body))
+	}
+	return strings.Join(items, "\n")
+}
+
+// table renders a table as a GFM table, using the first row as the header
+func (r *storageRenderer) table(n *storageNode) string {
+	rows := []*storageNode{}
+	var collect func(*storageNode)
+	collect = func(node *storageNode) {
+		for _, c := range node.children {
+			switch c.name {
+			case "tr":
+				rows = append(rows, c)
+			case "thead", "tbody", "tfoot":
+				collect(c)
+			}
+		}
+	}
+	collect(n)
+
+	lines := []string{}
+	columns := 0
+	for i, row := range rows {
+		values := []string{}
+		for _, cell := range row.children {
+			if cell.name != "td" && cell.name != "th" {
+				continue
+			}
+			text := r.blocks(cell.children, "\n")
+			text = strings.ReplaceAll(text, "|", `\|`)
+			text = strings.ReplaceAll(text, "\n", "<br>")
+			values = append(values, text)
+		}
+		if i == 0 {
+			columns = len(values)
+		}
+		for len(values) < columns {
+			values = append(values, "")
+		}
+		lines = append(lines, "| "+strings.Join(values, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", columns))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// inlines renders a list of inline nodes
+func (r *storageRenderer) inlines(nodes []*storageNode) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		b.WriteString(r.inline(n))
+	}
+	return b.String()
+}
+
+// inline renders an inline node
+func (r *storageRenderer) inline(n *storageNode) string {
+	if n.name == "" {
+		return markdownEscaper.Replace(whitespaceRun.ReplaceAllString(n.text, " "))
+	}
+
+	switch n.name {
+	case "strong", "b":
+		return wrapInline("**", r.inlines(n.children))
+	case "em", "i":
+		return wrapInline("*", r.inlines(n.children))
+	case "s", "del", "strike":
+		return wrapInline("~~", r.inlines(n.children))
+	case "code":
+		return codeSpan(n.textContent())
+	case "br":
+		return "  \n"
+	case "a":
+		text := strings.TrimSpace(r.inlines(n.children))
+		href := n.attr("href")
+		if href == "" {
+			return text
+		}
+		if text == "" {
+			text = href
+		}
+		return "[" + text + "](" + href + ")"
+	case "time":
+		return n.attr("datetime")
+	case "ac:link":
+		return r.link(n)
+	case "ac:image":
+		return r.image(n)
+	case "ac:emoticon":
+		if fallback := n.attr("ac:emoji-fallback"); fallback != "" {
+			return fallback
+		}
+		return ":" + n.attr("ac:name") + ":"
+	case "ac:structured-macro", "ac:macro":
+		return r.inlineMacro(n)
+	case "ac:placeholder", "ac:parameter":
+		return ""
+	}
+
+	return r.inlines(n.children)
+}
+
+// link renders an ac:link to a page, attachment, user or URL
+func (r *storageRenderer) link(n *storageNode) string {
+	text := ""
+	if body := n.child("ac:plain-text-link-body"); body != nil {
+		text = markdownEscaper.Replace(body.textContent())
+	} else if body := n.child("ac:link-body"); body != nil {
+		text = r.inlines(body.children)
+	}
+
+	target := ""
+	for _, c := range n.children {
+		switch c.name {
+		case "ri:page", "ri:blog-post":
+			title := c.attr("ri:content-title")
+			if text == "" {
+				text = markdownEscaper.Replace(title)
+			}
+			target = "page:" + title
+			if space := c.attr("ri:space-key"); space != "" {
+				target = "page:" + space + "/" + title
+			}
+		case "ri:attachment":
+			filename := c.attr("ri:filename")
+			if text == "" {
+				text = markdownEscaper.Replace(filename)
+			}
+			target = "attachment:" + filename
+		case "ri:user":
+			if text == "" {
+				return "@" + c.attr("ri:account-id")
+			}
+			return "@" + text
+		case "ri:url":
+			target = c.attr("ri:value")
+		}
+	}
+
+	if anchor := n.attr("ac:anchor"); anchor != "" {
+		target += "#" + anchor
+	}
+	if target == "" {
+		return text
+	}
+	if text == "" {
+		text = target
+	}
+	return "[" + text + "](" + markdownURL(target) + ")"
+}
+
+// image renders an ac:image referencing an attachment or external URL
+func (r *storageRenderer) image(n *storageNode) string {
+	alt := n.attr("ac:alt")
+	target := ""
+	if attachment := n.child("ri:attachment"); attachment != nil {
+		target = "attachment:" + attachment.attr("ri:filename")
+		if alt == "" {
+			alt = attachment.attr("ri:filename")
+		}
+	} else if url := n.child("ri:url"); url != nil {
+		target = url.attr("ri:value")
+	}
+	if target == "" {
+		return ""
+	}
+	return "![" + markdownEscaper.Replace(alt) + "](" + markdownURL(target) + ")"
+}
+
+// markdownURL wraps link targets containing spaces in angle brackets
+func markdownURL(target string) string {
+	if strings.ContainsAny(target, " ()") {
+		return "<" + target + ">"
+	}
+	return target
+}
+
+// wrapInline wraps text in an emphasis marker, keeping surrounding spaces outside the marker
+func wrapInline(marker, text string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	leading := text[:strings.Index(text, trimmed)]
+	trailing := text[len(leading)+len(trimmed):]
+	return leading + marker + trimmed + marker + trailing
+}
+
+// listMarker prefixes the first line of a list item and indents the rest under it
+func listMarker(marker, text string) string {
+	lines := strings.SplitN(text, "\n", 2)
+	if len(lines) == 1 {
+		return marker + lines[0]
+	}
+	return marker + lines[0] + "\n" + indent(lines[1], strings.Repeat(" ", len(marker)))
+}
+
+// codeFence renders a fenced code block long enough not to clash with the code
+func codeFence(code, language string) string {
+	code = strings.Trim(code, "\n")
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + language + "\n" + code + "\n" + fence
+}
+
+// codeSpan renders inline code with enough backticks not to clash with the code
+func codeSpan(code string) string {
+	fence := "`"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		code = " " + code + " "
+	}
+	return fence + code + fence
+}
@@ -0,0 +1,80 @@
+package converters
+
+import "testing"
+
+func TestStorageToMarkdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		storage string
+		want    string
+	}{
+		{
+			name:    "headings and inline formatting",
+			storage: `<h2>Title</h2><p>Some <strong>bold</strong>, <em>italic</em> and <code>a_b</code> with a <a href="https://example.com">link</a>.</p>`,
+			want:    "## Title\n\nSome **bold**, *italic* and `a_b` with a [link](https://example.com).",
+		},
+		{
+			name:    "entities and escaping",
+			storage: `<p>a &amp; b&nbsp;c *not bold*</p>`,
+			want:    "a & b c \\*not bold\\*",
+		},
+		{
+			name:    "nested lists",
+			storage: `<ul><li>one<ol start="3"><li>three</li><li>four</li></ol></li><li>two</li></ul>`,
+			want:    "- one\n  3. three\n  4. four\n- two",
+		},
+		{
+			name:    "table with header and pipe",
+			storage: `<table><tbody><tr><th>A</th><th>B</th></tr><tr><td>x|y</td><td><p>1</p><p>2</p></td></tr></tbody></table>`,
+			want:    "| A | B |\n| --- | --- |\n| x\\|y | 1<br>2 |",
+		},
+		{
+			name:    "code macro",
+			storage: `<ac:structured-macro ac:name="code"><ac:parameter ac:name="language">go</ac:parameter><ac:plain-text-body><![CDATA[if a < b {}]]></ac:plain-text-body></ac:structured-macro>`,
+			want:    "```go\nif a < b {}\n```",
+		},
+		{
+			name:    "panel macro with title",
+			storage: `<ac:structured-macro ac:name="warning"><ac:parameter ac:name="title">Heads up</ac:parameter><ac:rich-text-body><p>Careful</p></ac:rich-text-body></ac:structured-macro>`,
+			want:    "> **Heads up:** Careful",
+		},
+		{
+			name:    "inline status and jira macros",
+			storage: `<p>State <ac:structured-macro ac:name="status"><ac:parameter ac:name="title">done</ac:parameter></ac:structured-macro> for <ac:structured-macro ac:name="jira"><ac:parameter ac:name="key">DEV-1</ac:parameter></ac:structured-macro></p>`,
+			want:    "State [DONE] for [DEV-1](jira:DEV-1)",
+		},
+		{
+			name:    "dropped and unknown macros",
+			storage: `<ac:structured-macro ac:name="toc"/><ac:structured-macro ac:name="custom"><ac:rich-text-body><p>kept</p></ac:rich-text-body></ac:structured-macro>`,
+			want:    "kept",
+		},
+		{
+			name:    "page link and image",
+			storage: `<p><ac:link><ri:page ri:space-key="DEV" ri:content-title="Other Page"/></ac:link> <ac:image><ri:attachment ri:filename="shot.png"/></ac:image></p>`,
+			want:    "[Other Page](<page:DEV/Other Page>) ![shot.png](attachment:shot.png)",
+		},
+		{
+			name:    "task list",
+			storage: `<ac:task-list><ac:task><ac:task-status>complete</ac:task-status><ac:task-body>done</ac:task-body></ac:task><ac:task><ac:task-status>incomplete</ac:task-status><ac:task-body>todo</ac:task-body></ac:task></ac:task-list>`,
+			want:    "- [x] done\n- [ ] todo",
+		},
+		{
+			name:    "unclosed and stray tags",
+			storage: `<p>first<br>line</span><p>second <strong>bold`,
+			want:    "first  \nline\n\nsecond **bold**",
+		},
+		{
+			name:    "empty",
+			storage: "",
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StorageToMarkdown(tt.storage); got != tt.want {
+				t.Errorf("StorageToMarkdown() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,405 @@
+package converters
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Jira Server and Data Center return rich text such as descriptions and comments as wiki
+// markup strings rather than ADF. The renderers below cover the common notation: headings,
+// lists, tables, code, quote and panel macros, links, images and text effects. Macros they do
+// not know are dropped while their contents are kept.
+
+// WikiToMarkdown renders Jira wiki markup as GitHub-flavored Markdown
+func WikiToMarkdown(markup string) string {
+	r := &wikiRenderer{markdown: true}
+	return strings.TrimSpace(r.blocks(markup))
+}
+
+// WikiToText renders Jira wiki markup as plain text
+func WikiToText(markup string) string {
+	r := &wikiRenderer{markdown: false}
+	return strings.TrimSpace(r.blocks(markup))
+}
+
+var (
+	wikiMacroStart  = regexp.MustCompile(`^\{(code|noformat|quote|panel|info|note|tip|warning)(?::([^}]*))?\}`)
+	wikiHeading     = regexp.MustCompile(`^h([1-6])\.\s+(.*)$`)
+	wikiListItem    = regexp.MustCompile(`^([*#]+|-)\s+(.*)$`)
+	wikiLink        = regexp.MustCompile(`\[([^\[\]\n]+)\]`)
+	wikiImage       = regexp.MustCompile(`!([^!\s|]+)(?:\|[^!\n]*)?!`)
+	wikiMonospace   = regexp.MustCompile(`\{\{(.+?)\}\}`)
+	wikiEscape      = regexp.MustCompile(`\\([*_\-+^~?{}\[\]!|])`)
+	wikiColor       = regexp.MustCompile(`\{color(?::[^}]*)?\}`)
+	wikiPlaceholder = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+// wikiEffects are the text effects in the order they are applied
+// Bold runs first so the single asterisks written for italics are not read as bold again
+var wikiEffects = []struct {
+	pattern  *regexp.Regexp
+	markdown string
+}{
+	{regexp.MustCompile(`(^|[^\w*])\*([^\s*](?:[^*\n]*[^\s*])?)\*($|[^\w*])`), "**"},
+	{regexp.MustCompile(`(^|[^\w_])_([^\s_](?:[^_\n]*[^\s_])?)_($|[^\w_])`), "*"},
+	{regexp.MustCompile(`(^|[^\w-])-([^\s-](?:[^-\n]*[^\s-])?)-($|[^\w-])`), "~~"},
+	{regexp.MustCompile(`(^|[^\w+])\+([^\s+](?:[^+\n]*[^\s+])?)\+($|[^\w+])`), ""},
+	{regexp.MustCompile(`(^|[^\w^])\^([^\s^](?:[^^\n]*[^\s^])?)\^($|[^\w^])`), ""},
+	{regexp.MustCompile(`(^|[^\w~])~([^\s~](?:[^~\n]*[^\s~])?)~($|[^\w~])`), ""},
+	{regexp.MustCompile(`(^|\W)\?\?(\S(?:.*?\S)?)\?\?($|\W)`), ""},
+}
+
+// wikiPanelLabels maps wiki panel macros to the label shown in front of the panel
+var wikiPanelLabels = map[string]string{
+	"info":    "Info",
+	"note":    "Note",
+	"tip":     "Tip",
+	"warning": "Warning",
+}
+
+type wikiRenderer struct {
+	markdown bool
+}
+
+// blocks renders markup line by line, grouping paragraphs, lists and tables
+func (r *wikiRenderer) blocks(markup string) string {
+	lines := strings.Split(strings.ReplaceAll(markup, "\r\n", "\n"), "\n")
+	var blocks, paragraph, list, table []string
+
+	flush := func() {
+		if len(paragraph) > 0 {
+			blocks = append(blocks, r.inline(strings.Join(paragraph, "\n")))
+			paragraph = nil
+		}
+		if len(list) > 0 {
+			blocks = append(blocks, r.list(list))
+			list = nil
+		}
+		if len(table) > 0 {
+			blocks = append(blocks, r.table(table))
+			table = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+
+		if match := wikiMacroStart.FindStringSubmatch(line); match != nil {
+			flush()
+			body, rest, next := readWikiMacro(lines, i, match[1], line[len(match[0]):])
+			blocks = append(blocks, r.macro(match[1], match[2], body))
+			if rest != "" {
+				lines[next] = rest
+				i = next - 1
+			} else {
+				i = next
+			}
+			continue
+		}
+
+		switch {
+		case line == "":
+			flush()
+		case wikiHeading.MatchString(line):
+			flush()
+			match := wikiHeading.FindStringSubmatch(line)
+			text := r.inline(match[2])
+			if r.markdown {
+				level, _ := strconv.Atoi(match[1])
+				text = strings.Repeat("#", level) + " " + text
+			}
+			blocks = append(blocks, text)
+		case strings.HasPrefix(line, "bq. "):
+			flush()
+			text := r.inline(strings.TrimPrefix(line, "bq. "))
+			if r.markdown {
+				text = quote(text)
+			}
+			blocks = append(blocks, text)
+		case strings.Trim(line, "-") == "" && len(line) >= 4:
+			flush()
+			if r.markdown {
+				blocks = append(blocks, "---")
+			}
+		case wikiListItem.MatchString(line):
+			if len(paragraph) > 0 || len(table) > 0 {
+				flush()
+			}
+			list = append(list, line)
+		case strings.HasPrefix(line, "|"):
+			if len(paragraph) > 0 || len(list) > 0 {
+				flush()
+			}
+			table = append(table, line)
+		default:
+			if len(list) > 0 || len(table) > 0 {
+				flush()
+			}
+			paragraph = append(paragraph, line)
+		}
+	}
+	flush()
+
+	return strings.Join(blocks, "\n\n")
+}
+
+// readWikiMacro collects the body of a block macro opened on line start
+// It returns the body, any text following the closing tag and the line that text is on
+func readWikiMacro(lines []string, start int, name, first string) (string, string, int) {
+	closing := "{" + name + "}"
+	var body []string
+	text := first
+	for i := start; i < len(lines); i++ {
+		if i > start {
+			text = lines[i]
+		}
+		if end := strings.Index(text, closing); end >= 0 {
+			body = append(body, text[:end])
+			return strings.Join(body, "\n"), strings.TrimSpace(text[end+len(closing):]), i
+		}
+		body = append(body, text)
+	}
+	// An unclosed macro runs to the end of the markup
+	return strings.Join(body, "\n"), "", len(lines) - 1
+}
+
+// macro renders a code, noformat, quote or panel macro body
+func (r *wikiRenderer) macro(name, params, body string) string {
+	switch name {
+	case "code", "noformat":
+		body = strings.Trim(body, "\n")
+		if !r.markdown {
+			return body
+		}
+		language := ""
+		if name == "code" {
+			language = wikiCodeLanguage(params)
+		}
+		return codeFence(body, language)
+
+	case "quote":
+		text := r.blocks(body)
+		if !r.markdown {
+			return text
+		}
+		return quote(text)
+	}
+
+	label := wikiPanelLabels[name]
+	if title := wikiMacroParam(params, "title"); title != "" {
+		label = title
+	}
+	text := r.blocks(body)
+	if label == "" {
+		if r.markdown {
+			return quote(text)
+		}
+		return text
+	}
+	if !r.markdown {
+		return label + ": " + text
+	}
+	return quote("**" + label + ":** " + text)
+}
+
+// list renders consecutive list lines, nesting items by the length of their marker
+func (r *wikiRenderer) list(lines []string) string {
+	items := make([]string, 0, len(lines))
+	counters := []int{}
+	markers := []string{}
+	for _, line := range lines {
+		match := wikiListItem.FindStringSubmatch(line)
+		marker := match[1]
+		depth := len(marker) - 1
+
+		for len(counters) <= depth {
+			counters = append(counters, 0)
+			markers = append(markers, "")
+		}
+		counters = counters[:depth+1]
+		markers = markers[:depth+1]
+		// Numbering restarts when a numbered list follows a bulleted one at the same depth
+		if markers[depth] != marker {
+			counters[depth] = 0
+			markers[depth] = marker
+		}
+		counters[depth]++
+
+		prefix := "- "
+		if strings.HasSuffix(marker, "#") {
+			prefix = strconv.Itoa(counters[depth]) + ". "
+		}
+		items = append(items, strings.Repeat("   ", depth)+prefix+r.inline(match[2]))
+	}
+	return strings.Join(items, "\n")
+}
+
+// table renders table lines as a GFM table, using the first row as the header
+func (r *wikiRenderer) table(lines []string) string {
+	rows := make([]string, 0, len(lines)+1)
+	columns := 0
+	for i, line := range lines {
+		separator := "|"
+		if strings.HasPrefix(line, "||") {
+			separator = "||"
+		}
+		cells := splitWikiCells(strings.Trim(line, "|"), separator)
+		values := make([]string, 0, len(cells))
+		for _, cell := range cells {
+			text := r.inline(strings.TrimSpace(cell))
+			if r.markdown {
+				text = strings.ReplaceAll(text, "|", `\|`)
+			}
+			values = append(values, text)
+		}
+
+		if !r.markdown {
+			rows = append(rows, strings.Join(values, " | "))
+			continue
+		}
+
+		if i == 0 {
+			columns = len(values)
+		}
+		for len(values) < columns {
+			values = append(values, "")
+		}
+		rows = append(rows, "| "+strings.Join(values, " | ")+" |")
+		if i == 0 {
+			rows = append(rows, "|"+strings.Repeat(" --- |", columns))
+		}
+	}
+	return strings.Join(rows, "\n")
+}
+
+// splitWikiCells splits a table row on its cell separator, ignoring separators inside links and macros
+func splitWikiCells(row, separator string) []string {
+	var cells []string
+	depth := 0
+	last := 0
+	for i := 0; i < len(row); i++ {
+		switch row[i] {
+		case '[', '{':
+			depth++
+		case ']', '}':
+			if depth > 0 {
+				depth--
+			}
+		case '|':
+			if depth == 0 && strings.HasPrefix(row[i:], separator) {
+				cells = append(cells, row[last:i])
+				i += len(separator) - 1
+				last = i + 1
+			}
+		}
+	}
+	return append(cells, row[last:])
+}
+
+// inline renders links, images, monospace and text effects
+// Rendered links and code are swapped for placeholders so later patterns cannot rewrite them
+func (r *wikiRenderer) inline(text string) string {
+	var protected []string
+	protect := func(value string) string {
+		protected = append(protected, value)
+		return "\x00" + strconv.Itoa(len(protected)-1) + "\x00"
+	}
+
+	text = wikiEscape.ReplaceAllStringFunc(text, func(match string) string {
+		if r.markdown {
+			return protect(markdownEscaper.Replace(match[1:]))
+		}
+		return protect(match[1:])
+	})
+	text = wikiMonospace.ReplaceAllStringFunc(text, func(match string) string {
+		code := wikiMonospace.FindStringSubmatch(match)[1]
+		if r.markdown {
+			return protect(codeSpan(code))
+		}
+		return protect(code)
+	})
+	text = wikiLink.ReplaceAllStringFunc(text, func(match string) string {
+		return protect(r.link(match[1 : len(match)-1]))
+	})
+	text = wikiImage.ReplaceAllStringFunc(text, func(match string) string {
+		name := wikiImage.FindStringSubmatch(match)[1]
+		if !r.markdown {
+			return protect("[image: " + name + "]")
+		}
+		return protect("![" + markdownEscaper.Replace(name) + "](" + markdownURL(name) + ")")
+	})
+	text = wikiColor.ReplaceAllString(text, "")
+	text = strings.ReplaceAll(text, `\\`, "\n")
+
+	for _, effect := range wikiEffects {
+		marker := effect.markdown
+		if !r.markdown {
+			marker = ""
+		}
+		for {
+			replaced := effect.pattern.ReplaceAllString(text, "${1}"+marker+"${2}"+marker+"${3}")
+			if replaced == text {
+				break
+			}
+			text = replaced
+		}
+	}
+
+	return wikiPlaceholder.ReplaceAllStringFunc(text, func(match string) string {
+		index, _ := strconv.Atoi(match[1 : len(match)-1])
+		return protected[index]
+	})
+}
+
+// link renders the inside of a [text|target] link
+// Targets may be URLs, user mentions (~name), attachments (^file), anchors (#name) or issue keys
+func (r *wikiRenderer) link(inner string) string {
+	text, target, hasText := strings.Cut(inner, "|")
+	if !hasText {
+		target = text
+	}
+	text = strings.TrimSpace(text)
+	target = strings.TrimSpace(target)
+
+	if strings.HasPrefix(target, "~") {
+		return "@" + strings.TrimPrefix(target, "~")
+	}
+	if !hasText {
+		text = strings.TrimLeft(target, "^#")
+	}
+
+	isURL := strings.Contains(target, "://") || strings.HasPrefix(target, "mailto:")
+	if !r.markdown || (!isURL && !strings.HasPrefix(target, "^")) {
+		return text
+	}
+	if !hasText && isURL {
+		return "<" + target + ">"
+	}
+	return "[" + markdownEscaper.Replace(text) + "](" + markdownURL(strings.TrimPrefix(target, "^")) + ")"
+}
+
+// wikiCodeLanguage returns the language of a code macro from "java" or "language=java|title=x" parameters
+func wikiCodeLanguage(params string) string {
+	if params == "" {
+		return ""
+	}
+	if language := wikiMacroParam(params, "language"); language != "" {
+		return language
+	}
+	first, _, _ := strings.Cut(params, "|")
+	if strings.Contains(first, "=") {
+		return ""
+	}
+	return first
+}
+
+// wikiMacroParam returns a named macro parameter from "key=value|key=value" parameters
+func wikiMacroParam(params, name string) string {
+	for _, param := range strings.Split(params, "|") {
+		if key, value, ok := strings.Cut(param, "="); ok && strings.TrimSpace(key) == name {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,93 @@
+package converters
+
+import "testing"
+
+func TestWikiToMarkdown(t *testing.T) {
+	tests := []struct {
+		name   string
+		markup string
+		want   string
+	}{
+		{
+			name:   "headings and paragraphs",
+			markup: "h1. Title\nfirst line\nsecond line\n\nh3. Sub",
+			want:   "# Title\n\nfirst line\nsecond line\n\n### Sub",
+		},
+		{
+			name:   "text effects",
+			markup: "*bold* _italic_ -struck- +under+ and snake_case_name",
+			want:   "**bold** *italic* ~~struck~~ under and snake_case_name",
+		},
+		{
+			name:   "nested lists",
+			markup: "# one\n## one a\n## one b\n# two\n* bullet",
+			want:   "1. one\n   1. one a\n   2. one b\n2. two\n- bullet",
+		},
+		{
+			name:   "table with a link in a cell and a short row",
+			markup: "||Key||Summary||\n|[DEV-1|https://jira.example.com/browse/DEV-1]|Fix it|\n|DEV-2|",
+			want:   "| Key | Summary |\n| --- | --- |\n| [DEV-1](https://jira.example.com/browse/DEV-1) | Fix it |\n| DEV-2 |  |",
+		},
+		{
+			name:   "code macro keeps markup",
+			markup: "{code:java}\nint *a* = 1;\n{code}",
+			want:   "```java\nint *a* = 1;\n```",
+		},
+		{
+			name:   "monospace and escapes",
+			markup: "run {{go test ./...}} with \\*stars\\*",
+			want:   "run `go test ./...` with \\*stars\\*",
+		},
+		{
+			name:   "panel macro with title",
+			markup: "{warning:title=Heads up}Careful{warning}",
+			want:   "> **Heads up:** Careful",
+		},
+		{
+			name:   "quote macro and text after it",
+			markup: "{quote}quoted{quote} after",
+			want:   "> quoted\n\nafter",
+		},
+		{
+			name:   "links, mentions and images",
+			markup: "see [docs|https://example.com], [~jane] and !shot.png|thumbnail!",
+			want:   "see [docs](https://example.com), @jane and ![shot.png](shot.png)",
+		},
+		{
+			name:   "unknown macros keep their text",
+			markup: "{color:red}red text{color}",
+			want:   "red text",
+		},
+		{
+			name:   "unclosed macro runs to the end",
+			markup: "before\n{noformat}\nraw *text*",
+			want:   "before\n\n```\nraw *text*\n```",
+		},
+		{
+			name:   "unbalanced markers stay literal",
+			markup: "a *half bold and [broken link",
+			want:   "a *half bold and [broken link",
+		},
+		{
+			name:   "empty",
+			markup: "",
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WikiToMarkdown(tt.markup); got != tt.want {
+				t.Errorf("WikiToMarkdown() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWikiToText(t *testing.T) {
+	markup := "h2. Title\n*bold* [docs|https://example.com]\n{info}Note this{info}"
+	want := "Title\n\nbold docs\n\nInfo: Note this"
+	if got := WikiToText(markup); got != want {
+		t.Errorf("WikiToText() =\n%q\nwant\n%q", got, want)
+	}
+}
@@ -56,3 +56,13 @@ func (h *APIHandler) NotFoundHandler(w http.ResponseWriter, r *http.Request) {
 		"message": "The requested endpoint does not exist",
 	})
 }
+
+// writeJSONError writes a {"status":"error"} JSON response with the given status and message
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "error",
+		"message": message,
+	})
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"mime"
+	"net/http"
+
+	"aktis-parser/internal/common"
+	"aktis-parser/internal/interfaces"
+	"github.com/ternarybob/arbor"
+)
+
+type AttachmentHandler struct {
+	attachments interfaces.AttachmentReader
+	logger      arbor.ILogger
+}
+
+func NewAttachmentHandler(attachments interfaces.AttachmentReader) *AttachmentHandler {
+	return &AttachmentHandler{
+		attachments: attachments,
+		logger:      common.GetLogger(),
+	}
+}
+
+// GetAttachmentHandler serves a stored attachment blob by attachment id
+func (h *AttachmentHandler) GetAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "attachment id required", http.StatusBadRequest)
+		return
+	}
+
+	attachment, err := h.attachments.GetAttachment(id)
+	if err != nil {
+		h.logger.Error().Err(err).Str("attachment", id).Msg("Failed to fetch attachment metadata")
+		http.Error(w, "Failed to fetch attachment", http.StatusInternalServerError)
+		return
+	}
+
+	if attachment == nil {
+		writeJSONError(w, http.StatusNotFound, "No attachment stored with id "+id)
+		return
+	}
+
+	file, err := h.attachments.OpenAttachment(attachment)
+	if err != nil {
+		h.logger.Error().Err(err).Str("attachment", id).Msg("Failed to open attachment blob")
+		http.Error(w, "Failed to open attachment", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	// The type and bytes come from the remote site and are served from the origin that holds the
+	// captured session, so only raster images are shown inline and nothing may run or be sniffed
+	contentType, disposition := attachmentContentType(attachment.MimeType)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{
+		"filename": attachment.Filename,
+	}))
+
+	http.ServeContent(w, r, attachment.Filename, attachment.StoredAt, file)
+}
+
+// inlineImageTypes are the raster image types safe to display inline; SVG is excluded since it can carry script
+var inlineImageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+	"image/bmp":  true,
+	"image/avif": true,
+}
+
+// attachmentContentType returns the Content-Type and disposition to serve an attachment with
+// Raster images are shown inline, everything else is downloaded; an unknown type is never sniffed
+func attachmentContentType(mimeType string) (string, string) {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil || mediaType == "" {
+		return "application/octet-stream", "attachment"
+	}
+	if inlineImageTypes[mediaType] {
+		return mediaType, "inline"
+	}
+	return mimeType, "attachment"
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"aktis-parser/internal/interfaces"
+)
+
+func TestAttachmentContentType(t *testing.T) {
+	tests := []struct {
+		mimeType        string
+		wantType        string
+		wantDisposition string
+	}{
+		{"image/png", "image/png", "inline"},
+		{"IMAGE/JPEG", "image/jpeg", "inline"},
+		{"image/gif; name=x.gif", "image/gif", "inline"},
+		{"image/svg+xml", "image/svg+xml", "attachment"},
+		{"text/html", "text/html", "attachment"},
+		{"text/html; charset=utf-8", "text/html; charset=utf-8", "attachment"},
+		{"application/pdf", "application/pdf", "attachment"},
+		{"", "application/octet-stream", "attachment"},
+		{"not a type", "application/octet-stream", "attachment"},
+	}
+
+	for _, tt := range tests {
+		gotType, gotDisposition := attachmentContentType(tt.mimeType)
+		if gotType != tt.wantType || gotDisposition != tt.wantDisposition {
+			t.Errorf("attachmentContentType(%q) = %q, %q; want %q, %q", tt.mimeType, gotType, gotDisposition, tt.wantType, tt.wantDisposition)
+		}
+	}
+}
+
+// memoryAttachments serves attachments held in memory
+type memoryAttachments map[string]*interfaces.Attachment
+
+func (m memoryAttachments) GetAttachment(id string) (*interfaces.Attachment, error) {
+	return m[id], nil
+}
+
+func (m memoryAttachments) OpenAttachment(attachment *interfaces.Attachment) (io.ReadSeekCloser, error) {
+	return nopSeekCloser{strings.NewReader("<script>alert(1)</script> in " + attachment.Filename)}, nil
+}
+
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error { return nil }
+
+func TestGetAttachmentHandler(t *testing.T) {
+	tests := []struct {
+		filename        string
+		mimeType        string
+		wantType        string
+		wantDisposition string
+	}{
+		{"shot.png", "image/png", "image/png", "inline"},
+		{"page.html", "text/html", "text/html", "attachment"},
+		{"logo.svg", "image/svg+xml", "image/svg+xml", "attachment"},
+		{`odd "name"; x.bin`, "", "application/octet-stream", "attachment"},
+	}
+
+	attachments := memoryAttachments{}
+	for i, tt := range tests {
+		id := strconv.Itoa(i + 1)
+		attachments[id] = &interfaces.Attachment{ID: id, Filename: tt.filename, MimeType: tt.mimeType, StoredAt: time.Now()}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/attachments/{id}", NewAttachmentHandler(attachments).GetAttachmentHandler)
+
+	for i, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/attachments/"+strconv.Itoa(i+1), nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", rec.Code)
+			}
+			if got := rec.Body.String(); got != "<script>alert(1)</script> in "+tt.filename {
+				t.Errorf("body = %q", got)
+			}
+
+			header := rec.Header()
+			if got := header.Get("Content-Type"); got != tt.wantType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantType)
+			}
+			if got := header.Get("X-Content-Type-Options"); got != "nosniff" {
+				t.Errorf("X-Content-Type-Options = %q, want nosniff", got)
+			}
+			if got := header.Get("Content-Security-Policy"); got != "sandbox" {
+				t.Errorf("Content-Security-Policy = %q, want sandbox", got)
+			}
+			disposition, params, err := mime.ParseMediaType(header.Get("Content-Disposition"))
+			if err != nil || disposition != tt.wantDisposition || params["filename"] != tt.filename {
+				t.Errorf("Content-Disposition = %q (%v), want %s with filename %q", header.Get("Content-Disposition"), err, tt.wantDisposition, tt.filename)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/attachments/unknown", nil))
+	var result map[string]interface{}
+	if rec.Code != http.StatusNotFound || json.NewDecoder(rec.Body).Decode(&result) != nil || result["status"] != "error" {
+		t.Errorf("unknown attachment = %d %v, want a 404 JSON error", rec.Code, result)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/attachments/1", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE status = %d, want 405", rec.Code)
+	}
+}
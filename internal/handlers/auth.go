@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"aktis-parser/internal/common"
+	"aktis-parser/internal/interfaces"
+	"github.com/ternarybob/arbor"
+)
+
+type AuthHandler struct {
+	validator interfaces.AuthValidator
+	history   interfaces.AuthHistoryStore
+	logger    arbor.ILogger
+}
+
+func NewAuthHandler(validator interfaces.AuthValidator) *AuthHandler {
+	return &AuthHandler{
+		validator: validator,
+		logger:    common.GetLogger(),
+	}
+}
+
+// SetAuthHistory sets the auth history; without one, as with API token and OAuth auth, it is unavailable
+func (h *AuthHandler) SetAuthHistory(history interfaces.AuthHistoryStore) {
+	h.history = history
+}
+
+// AuthStatusHandler returns the validated identity and session expiry of the current credentials
+// ?refresh=true validates again before answering instead of returning the last result
+func (h *AuthHandler) AuthStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status := h.validator.Status()
+	if r.URL.Query().Get("refresh") == "true" {
+		status = h.validator.Validate()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// AuthHistoryHandler lists the captured, rotated and restored sessions, newest first
+func (h *AuthHandler) AuthHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.history == nil {
+		writeJSONError(w, http.StatusConflict, "Auth history is only kept for browser extension authentication")
+		return
+	}
+
+	entries, err := h.history.AuthHistory()
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to read auth history")
+		writeJSONError(w, http.StatusInternalServerError, "Failed to read auth history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
+
+// RestoreAuthHandler makes an auth history entry the current auth and validates it straight away
+func (h *AuthHandler) RestoreAuthHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.history == nil {
+		writeJSONError(w, http.StatusConflict, "Auth history is only kept for browser extension authentication")
+		return
+	}
+
+	id := r.PathValue("id")
+	if _, err := h.history.RestoreAuth(id); err != nil {
+		if errors.Is(err, interfaces.ErrAuthHistoryNotFound) {
+			writeJSONError(w, http.StatusNotFound, "Auth history entry not found: "+id)
+			return
+		}
+		h.logger.Error().Err(err).Str("id", id).Msg("Failed to restore authentication")
+		writeJSONError(w, http.StatusInternalServerError, "Failed to restore authentication: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "restored",
+		"auth":   h.validator.Validate(),
+	})
+}
+
+// authStatusLabel summarizes a validation result for the status table, such as "VALID (Jane Doe)"
+func authStatusLabel(provider interfaces.AuthStatusProvider) string {
+	if provider == nil {
+		return interfaces.AuthStateWaiting
+	}
+	status := provider.Status()
+	if identity := status.Identity(); status.State == interfaces.AuthStateValid && identity != nil && identity.DisplayName != "" {
+		return status.State + " (" + identity.DisplayName + ")"
+	}
+	return status.State
+}
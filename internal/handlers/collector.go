@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"aktis-parser/internal/interfaces"
 	"github.com/ternarybob/arbor"
 )
 
@@ -19,11 +20,13 @@ type CollectorHandler struct {
 // JiraDataProvider interface for accessing Jira data
 type JiraDataProvider interface {
 	GetJiraData() (map[string]interface{}, error)
+	GetJiraDataWithOptions(options interfaces.JiraDataOptions) (map[string]interface{}, error)
 }
 
 // ConfluenceDataProvider interface for accessing Confluence data
 type ConfluenceDataProvider interface {
 	GetConfluenceData() (map[string]interface{}, error)
+	GetConfluenceDataWithOptions(options interfaces.ConfluenceDataOptions) (map[string]interface{}, error)
 }
 
 // PaginationResponse contains pagination metadata
@@ -148,7 +151,14 @@ func (h *CollectorHandler) GetIssuesHandler(w http.ResponseWriter, r *http.Reque
 
 	page, pageSize := h.getPaginationParams(r)
 
-	data, err := h.jiraScraper.GetJiraData()
+	// Optional switches to embed each issue's comments, worklogs and sprints
+	options := interfaces.JiraDataOptions{
+		IncludeComments: r.URL.Query().Get("includeComments") == "true",
+		IncludeWorklogs: r.URL.Query().Get("includeWorklogs") == "true",
+		IncludeSprints:  r.URL.Query().Get("includeSprints") == "true",
+	}
+
+	data, err := h.jiraScraper.GetJiraDataWithOptions(options)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("Failed to get Jira data")
 		http.Error(w, "Failed to get issues", http.StatusInternalServerError)
@@ -198,7 +208,12 @@ func (h *CollectorHandler) GetPagesHandler(w http.ResponseWriter, r *http.Reques
 
 	page, pageSize := h.getPaginationParams(r)
 
-	data, err := h.confluenceScraper.GetConfluenceData()
+	// Optional switch to embed each page's comment threads
+	options := interfaces.ConfluenceDataOptions{
+		IncludeComments: r.URL.Query().Get("includeComments") == "true",
+	}
+
+	data, err := h.confluenceScraper.GetConfluenceDataWithOptions(options)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("Failed to get Confluence data")
 		http.Error(w, "Failed to get pages", http.StatusInternalServerError)
@@ -3,8 +3,10 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"aktis-parser/internal/common"
+	"aktis-parser/internal/converters"
 	"aktis-parser/internal/interfaces"
 	"github.com/ternarybob/arbor"
 )
@@ -30,7 +32,14 @@ func (h *DataHandler) GetJiraDataHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	data, err := h.jiraScraper.GetJiraData()
+	options := interfaces.JiraDataOptions{
+		IncludeHistory:  r.URL.Query().Get("includeHistory") == "true",
+		IncludeComments: r.URL.Query().Get("includeComments") == "true",
+		IncludeWorklogs: r.URL.Query().Get("includeWorklogs") == "true",
+		IncludeSprints:  r.URL.Query().Get("includeSprints") == "true",
+	}
+
+	data, err := h.jiraScraper.GetJiraDataWithOptions(options)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("Failed to fetch Jira data")
 		http.Error(w, "Failed to fetch Jira data", http.StatusInternalServerError)
@@ -41,7 +50,127 @@ func (h *DataHandler) GetJiraDataHandler(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(data)
 }
 
-// GetJiraIssuesHandler returns issues optionally filtered by project keys
+// GetIssueHistoryHandler returns the stored changelog for a single issue
+func (h *DataHandler) GetIssueHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	issueKey := r.PathValue("key")
+	if issueKey == "" {
+		http.Error(w, "issue key required", http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.jiraScraper.GetIssueHistory(issueKey)
+	if err != nil {
+		h.logger.Error().Err(err).Str("issue", issueKey).Msg("Failed to fetch issue history")
+		http.Error(w, "Failed to fetch issue history", http.StatusInternalServerError)
+		return
+	}
+
+	if history == nil {
+		writeJSONError(w, http.StatusNotFound, "No history stored for issue "+issueKey)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// GetJiraBoardsHandler returns all stored Agile boards
+func (h *DataHandler) GetJiraBoardsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	boards, err := h.jiraScraper.GetBoards()
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to fetch boards")
+		http.Error(w, "Failed to fetch boards", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"boards": boards,
+	})
+}
+
+// GetBoardSprintsHandler returns the stored sprints of a single board
+func (h *DataHandler) GetBoardSprintsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	boardID := r.PathValue("id")
+	if boardID == "" {
+		http.Error(w, "board id required", http.StatusBadRequest)
+		return
+	}
+
+	sprints, err := h.jiraScraper.GetBoardSprints(boardID)
+	if err != nil {
+		h.logger.Error().Err(err).Str("board", boardID).Msg("Failed to fetch board sprints")
+		http.Error(w, "Failed to fetch board sprints", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"boardId": boardID,
+		"sprints": sprints,
+	})
+}
+
+// GetJiraPruneReportsHandler returns the reconciliation reports of issue syncs, newest first
+// Query params: projectKey (limit to one project)
+func (h *DataHandler) GetJiraPruneReportsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	projectKey := r.URL.Query().Get("projectKey")
+	reports, err := h.jiraScraper.GetPruneReports(projectKey)
+	if err != nil {
+		h.logger.Error().Err(err).Str("project", projectKey).Msg("Failed to fetch prune reports")
+		http.Error(w, "Failed to fetch prune reports", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"reports": reports,
+	})
+}
+
+// GetJiraTombstonesHandler returns the tombstones of pruned issues, newest first
+// Query params: projectKey (limit to one project)
+func (h *DataHandler) GetJiraTombstonesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	projectKey := r.URL.Query().Get("projectKey")
+	tombstones, err := h.jiraScraper.GetTombstones(projectKey)
+	if err != nil {
+		h.logger.Error().Err(err).Str("project", projectKey).Msg("Failed to fetch tombstones")
+		http.Error(w, "Failed to fetch tombstones", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tombstones": tombstones,
+	})
+}
+
+// GetJiraIssuesHandler returns issues optionally filtered by project keys and saved query tags
 func (h *DataHandler) GetJiraIssuesHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -50,6 +179,7 @@ func (h *DataHandler) GetJiraIssuesHandler(w http.ResponseWriter, r *http.Reques
 
 	// Get optional project keys filter from query params
 	projectKeys := r.URL.Query()["projectKey"]
+	queries := r.URL.Query()["query"]
 
 	h.logger.Info().Strs("projectKeys", projectKeys).Msg("GetJiraIssuesHandler called")
 
@@ -134,6 +264,13 @@ func (h *DataHandler) GetJiraIssuesHandler(w http.ResponseWriter, r *http.Reques
 			Msg("Filtered issues by project")
 	}
 
+	// Issues match when any of the requested saved queries tagged them
+	if len(queries) > 0 {
+		issues = filterPages(issues, func(issue map[string]interface{}) bool {
+			return taggedByQuery(issue, queries)
+		})
+	}
+
 	// Log what we're returning
 	returnCount := 0
 	if issueList, ok := issues.([]interface{}); ok {
@@ -150,6 +287,160 @@ func (h *DataHandler) GetJiraIssuesHandler(w http.ResponseWriter, r *http.Reques
 	})
 }
 
+// GetSpaceTreeHandler returns the page tree of a space
+// Query params: parentId (start below this page), depth (levels to expand, default 1)
+func (h *DataHandler) GetSpaceTreeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	spaceKey := r.PathValue("key")
+	if spaceKey == "" {
+		http.Error(w, "space key required", http.StatusBadRequest)
+		return
+	}
+
+	depth := 1
+	if value := r.URL.Query().Get("depth"); value != "" {
+		var err error
+		if depth, err = strconv.Atoi(value); err != nil || depth < 1 {
+			http.Error(w, "depth must be a positive integer", http.StatusBadRequest)
+			return
+		}
+	}
+	parentID := r.URL.Query().Get("parentId")
+
+	nodes, err := h.confluenceScraper.GetSpaceTree(spaceKey, parentID, depth)
+	if err != nil {
+		h.logger.Error().Err(err).Str("space", spaceKey).Msg("Failed to fetch space tree")
+		http.Error(w, "Failed to fetch space tree", http.StatusInternalServerError)
+		return
+	}
+
+	if nodes == nil {
+		message := "No page tree stored for space " + spaceKey
+		if parentID != "" {
+			message = "Page " + parentID + " not found in space " + spaceKey
+		}
+		writeJSONError(w, http.StatusNotFound, message)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"spaceKey": spaceKey,
+		"parentId": parentID,
+		"depth":    depth,
+		"children": nodes,
+	})
+}
+
+// GetPageVersionsHandler returns the stored versions of a page, newest first
+func (h *DataHandler) GetPageVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	pageID := r.PathValue("id")
+	versions, err := h.confluenceScraper.GetPageVersions(pageID)
+	if err != nil {
+		h.logger.Error().Err(err).Str("page", pageID).Msg("Failed to fetch page versions")
+		http.Error(w, "Failed to fetch page versions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"pageId":   pageID,
+		"versions": versions,
+	})
+}
+
+// GetPageDiffHandler returns a unified diff between two stored versions of a page
+// Query params: from, to (version numbers; default to the latest version and the one before it)
+func (h *DataHandler) GetPageDiffHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	pageID := r.PathValue("id")
+	versions := map[string]int{"from": 0, "to": 0}
+	for name := range versions {
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			continue
+		}
+		number, err := strconv.Atoi(value)
+		if err != nil || number < 1 {
+			http.Error(w, name+" must be a positive version number", http.StatusBadRequest)
+			return
+		}
+		versions[name] = number
+	}
+
+	diff, err := h.confluenceScraper.GetPageVersionDiff(pageID, versions["from"], versions["to"])
+	if err != nil {
+		h.logger.Error().Err(err).Str("page", pageID).Msg("Failed to diff page versions")
+		http.Error(w, "Failed to diff page versions", http.StatusInternalServerError)
+		return
+	}
+
+	if diff == nil {
+		writeJSONError(w, http.StatusNotFound, "Requested versions of page "+pageID+" are not stored")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(diff)
+}
+
+// GetConfluencePruneReportsHandler returns the reconciliation reports of page syncs, newest first
+// Query params: spaceKey (limit to one space)
+func (h *DataHandler) GetConfluencePruneReportsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	spaceKey := r.URL.Query().Get("spaceKey")
+	reports, err := h.confluenceScraper.GetPruneReports(spaceKey)
+	if err != nil {
+		h.logger.Error().Err(err).Str("space", spaceKey).Msg("Failed to fetch prune reports")
+		http.Error(w, "Failed to fetch prune reports", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"reports": reports,
+	})
+}
+
+// GetConfluenceTombstonesHandler returns the tombstones of pruned pages, newest first
+// Query params: spaceKey (limit to one space)
+func (h *DataHandler) GetConfluenceTombstonesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	spaceKey := r.URL.Query().Get("spaceKey")
+	tombstones, err := h.confluenceScraper.GetTombstones(spaceKey)
+	if err != nil {
+		h.logger.Error().Err(err).Str("space", spaceKey).Msg("Failed to fetch tombstones")
+		http.Error(w, "Failed to fetch tombstones", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tombstones": tombstones,
+	})
+}
+
 // GetConfluenceDataHandler returns all Confluence data (spaces and pages)
 func (h *DataHandler) GetConfluenceDataHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
@@ -157,7 +448,11 @@ func (h *DataHandler) GetConfluenceDataHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	data, err := h.confluenceScraper.GetConfluenceData()
+	options := interfaces.ConfluenceDataOptions{
+		IncludeComments: r.URL.Query().Get("includeComments") == "true",
+	}
+
+	data, err := h.confluenceScraper.GetConfluenceDataWithOptions(options)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("Failed to fetch Confluence data")
 		http.Error(w, "Failed to fetch Confluence data", http.StatusInternalServerError)
@@ -168,7 +463,7 @@ func (h *DataHandler) GetConfluenceDataHandler(w http.ResponseWriter, r *http.Re
 	json.NewEncoder(w).Encode(data)
 }
 
-// GetConfluencePagesHandler returns pages optionally filtered by space keys
+// GetConfluencePagesHandler returns pages optionally filtered by space keys, labels, saved query tags and content types
 func (h *DataHandler) GetConfluencePagesHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -176,10 +471,30 @@ func (h *DataHandler) GetConfluencePagesHandler(w http.ResponseWriter, r *http.R
 	}
 
 	spaceKeys := r.URL.Query()["spaceKey"]
+	labels := r.URL.Query()["label"]
+	queries := r.URL.Query()["query"]
+	contentTypes := r.URL.Query()["type"]
+	for _, contentType := range contentTypes {
+		if contentType != "page" && contentType != "blogpost" {
+			http.Error(w, "type must be page or blogpost", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// format=markdown replaces each page's storage-format body with Markdown
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "storage" && format != "markdown" {
+		http.Error(w, "format must be storage or markdown", http.StatusBadRequest)
+		return
+	}
+
+	options := interfaces.ConfluenceDataOptions{
+		IncludeComments: r.URL.Query().Get("includeComments") == "true",
+	}
 
 	h.logger.Info().Strs("spaceKeys", spaceKeys).Msg("GetConfluencePagesHandler called")
 
-	data, err := h.confluenceScraper.GetConfluenceData()
+	data, err := h.confluenceScraper.GetConfluenceDataWithOptions(options)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("Failed to fetch Confluence data")
 		http.Error(w, "Failed to fetch Confluence data", http.StatusInternalServerError)
@@ -227,6 +542,54 @@ func (h *DataHandler) GetConfluencePagesHandler(w http.ResponseWriter, r *http.R
 		}
 	}
 
+	// Pages match when they carry any of the requested labels and any of the requested types
+	if len(labels) > 0 {
+		pages = filterPages(pages, func(page map[string]interface{}) bool {
+			for _, label := range pageLabels(page) {
+				for _, wanted := range labels {
+					if label == wanted {
+						return true
+					}
+				}
+			}
+			return false
+		})
+	}
+	if len(queries) > 0 {
+		pages = filterPages(pages, func(page map[string]interface{}) bool {
+			return taggedByQuery(page, queries)
+		})
+	}
+	if len(contentTypes) > 0 {
+		pages = filterPages(pages, func(page map[string]interface{}) bool {
+			// Pages stored before content types were tracked have no type and are regular pages
+			pageType, _ := page["type"].(string)
+			if pageType == "" {
+				pageType = "page"
+			}
+			for _, contentType := range contentTypes {
+				if pageType == contentType {
+					return true
+				}
+			}
+			return false
+		})
+	}
+
+	if format == "markdown" {
+		if pageList, ok := pages.([]map[string]interface{}); ok {
+			for _, page := range pageList {
+				renderPageMarkdown(page)
+			}
+		} else if pageList, ok := pages.([]interface{}); ok {
+			for _, page := range pageList {
+				if pageMap, ok := page.(map[string]interface{}); ok {
+					renderPageMarkdown(pageMap)
+				}
+			}
+		}
+	}
+
 	returnCount := 0
 	if pageList, ok := pages.([]interface{}); ok {
 		returnCount = len(pageList)
@@ -241,3 +604,77 @@ func (h *DataHandler) GetConfluencePagesHandler(w http.ResponseWriter, r *http.R
 		"pages": pages,
 	})
 }
+
+// filterPages keeps the pages (or issues) accepted by keep, for either record list type
+func filterPages(pages interface{}, keep func(map[string]interface{}) bool) interface{} {
+	filtered := []interface{}{}
+	if pageList, ok := pages.([]map[string]interface{}); ok {
+		for _, page := range pageList {
+			if keep(page) {
+				filtered = append(filtered, page)
+			}
+		}
+	} else if pageList, ok := pages.([]interface{}); ok {
+		for _, page := range pageList {
+			if pageMap, ok := page.(map[string]interface{}); ok && keep(pageMap) {
+				filtered = append(filtered, page)
+			}
+		}
+	}
+	return filtered
+}
+
+// taggedByQuery reports whether any of the named saved queries tagged a stored issue or page
+func taggedByQuery(record map[string]interface{}, queries []string) bool {
+	tags, _ := record["queries"].([]interface{})
+	for _, tag := range tags {
+		for _, name := range queries {
+			if tag == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pageLabels returns the label names of a stored page
+func pageLabels(page map[string]interface{}) []string {
+	names := []string{}
+	labels, _ := page["labels"].([]interface{})
+	for _, label := range labels {
+		if labelMap, ok := label.(map[string]interface{}); ok {
+			if name, ok := labelMap["name"].(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// renderPageMarkdown replaces a page's body.storage with body.markdown in the same shape
+// Comments attached with includeComments are rendered too, along with their replies
+func renderPageMarkdown(page map[string]interface{}) {
+	for _, key := range []string{"comments", "replies"} {
+		if comments, ok := page[key].([]map[string]interface{}); ok {
+			for _, comment := range comments {
+				renderPageMarkdown(comment)
+			}
+		}
+	}
+
+	body, ok := page["body"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	storage, ok := body["storage"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	value, _ := storage["value"].(string)
+
+	delete(body, "storage")
+	body["markdown"] = map[string]interface{}{
+		"value":          converters.StorageToMarkdown(value),
+		"representation": "markdown",
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"aktis-parser/internal/common"
+	"aktis-parser/internal/interfaces"
+	"github.com/ternarybob/arbor"
+)
+
+type GraphHandler struct {
+	jiraScraper interfaces.JiraScraper
+	logger      arbor.ILogger
+}
+
+func NewGraphHandler(jira interfaces.JiraScraper) *GraphHandler {
+	return &GraphHandler{
+		jiraScraper: jira,
+		logger:      common.GetLogger(),
+	}
+}
+
+// GetIssueGraphHandler returns the link neighborhood of an issue
+// Query params: depth (default 1), type (repeatable or comma separated), view=blocked-by
+func (h *GraphHandler) GetIssueGraphHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	issueKey := r.PathValue("key")
+	if issueKey == "" {
+		http.Error(w, "issue key required", http.StatusBadRequest)
+		return
+	}
+
+	var result interface{}
+	var err error
+
+	switch view := r.URL.Query().Get("view"); view {
+	case "blocked-by":
+		var chain *interfaces.BlockedByChain
+		chain, err = h.jiraScraper.GetBlockedByChain(issueKey)
+		if chain != nil {
+			result = chain
+		}
+	case "", "neighborhood":
+		depth := 1
+		if value := r.URL.Query().Get("depth"); value != "" {
+			if depth, err = strconv.Atoi(value); err != nil || depth < 1 {
+				http.Error(w, "depth must be a positive integer", http.StatusBadRequest)
+				return
+			}
+		}
+
+		var linkTypes []string
+		for _, value := range r.URL.Query()["type"] {
+			for _, linkType := range strings.Split(value, ",") {
+				if linkType = strings.TrimSpace(linkType); linkType != "" {
+					linkTypes = append(linkTypes, linkType)
+				}
+			}
+		}
+
+		var graph *interfaces.IssueGraph
+		graph, err = h.jiraScraper.GetIssueGraph(issueKey, depth, linkTypes)
+		if graph != nil {
+			result = graph
+		}
+	default:
+		http.Error(w, "unknown view: "+view, http.StatusBadRequest)
+		return
+	}
+
+	if err != nil {
+		h.logger.Error().Err(err).Str("issue", issueKey).Msg("Failed to build issue graph")
+		http.Error(w, "Failed to build issue graph", http.StatusInternalServerError)
+		return
+	}
+
+	if result == nil {
+		writeJSONError(w, http.StatusNotFound, "No issue or links stored for "+issueKey)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
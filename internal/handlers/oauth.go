@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"aktis-parser/internal/common"
+	"aktis-parser/internal/interfaces"
+	"github.com/ternarybob/arbor"
+)
+
+type OAuthHandler struct {
+	authorizer interfaces.OAuthAuthorizer
+	logger     arbor.ILogger
+}
+
+// NewOAuthHandler creates the OAuth handler; authorizer is nil unless auth_method is "oauth"
+func NewOAuthHandler(authorizer interfaces.OAuthAuthorizer) *OAuthHandler {
+	return &OAuthHandler{
+		authorizer: authorizer,
+		logger:     common.GetLogger(),
+	}
+}
+
+// StartHandler redirects to the Atlassian consent screen to start an OAuth 2.0 (3LO) authorization
+func (h *OAuthHandler) StartHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.authorizer == nil {
+		writeJSONError(w, http.StatusConflict, "OAuth is not enabled, set auth_method = \"oauth\"")
+		return
+	}
+
+	authURL, err := h.authorizer.AuthorizationURL()
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to start OAuth authorization")
+		http.Error(w, "Failed to start OAuth authorization", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// CallbackHandler completes an authorization with the code Atlassian redirects back with
+func (h *OAuthHandler) CallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.authorizer == nil {
+		writeJSONError(w, http.StatusConflict, "OAuth is not enabled, set auth_method = \"oauth\"")
+		return
+	}
+
+	query := r.URL.Query()
+	if denied := query.Get("error"); denied != "" {
+		h.logger.Warn().Str("error", denied).Str("description", query.Get("error_description")).Msg("OAuth authorization denied")
+		writeJSONError(w, http.StatusBadRequest, "Authorization denied: "+denied)
+		return
+	}
+
+	code := query.Get("code")
+	if code == "" {
+		writeJSONError(w, http.StatusBadRequest, "code parameter required")
+		return
+	}
+
+	if err := h.authorizer.Exchange(query.Get("state"), code); err != nil {
+		if errors.Is(err, interfaces.ErrOAuthState) {
+			writeJSONError(w, http.StatusBadRequest, "Unknown or expired authorization, start again from /api/oauth/start")
+			return
+		}
+		h.logger.Error().Err(err).Msg("Failed to complete OAuth authorization")
+		writeJSONError(w, http.StatusBadGateway, "Failed to complete authorization: "+err.Error())
+		return
+	}
+
+	// Back to the dashboard, which now shows the authorized site
+	http.Redirect(w, r, "/", http.StatusFound)
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"aktis-parser/internal/interfaces"
+)
+
+// stubAuthorizer accepts the code "good" for the state it handed out
+type stubAuthorizer struct{}
+
+func (stubAuthorizer) AuthorizationURL() (string, error) {
+	return "https://auth.atlassian.com/authorize?state=s1", nil
+}
+
+func (stubAuthorizer) Exchange(state, code string) error {
+	if state != "s1" {
+		return interfaces.ErrOAuthState
+	}
+	if code != "good" {
+		return fmt.Errorf("token endpoint rejected the code")
+	}
+	return nil
+}
+
+func TestOAuthHandlers(t *testing.T) {
+	tests := []struct {
+		name         string
+		authorizer   interfaces.OAuthAuthorizer
+		target       string
+		wantStatus   int
+		wantLocation string
+	}{
+		{name: "start redirects to consent", authorizer: stubAuthorizer{}, target: "/api/oauth/start", wantStatus: http.StatusFound, wantLocation: "https://auth.atlassian.com/authorize?state=s1"},
+		{name: "callback returns to the dashboard", authorizer: stubAuthorizer{}, target: "/api/oauth/callback?state=s1&code=good", wantStatus: http.StatusFound, wantLocation: "/"},
+		{name: "unknown state", authorizer: stubAuthorizer{}, target: "/api/oauth/callback?state=other&code=good", wantStatus: http.StatusBadRequest},
+		{name: "failed exchange", authorizer: stubAuthorizer{}, target: "/api/oauth/callback?state=s1&code=bad", wantStatus: http.StatusBadGateway},
+		{name: "denied consent", authorizer: stubAuthorizer{}, target: "/api/oauth/callback?error=access_denied", wantStatus: http.StatusBadRequest},
+		{name: "missing code", authorizer: stubAuthorizer{}, target: "/api/oauth/callback?state=s1", wantStatus: http.StatusBadRequest},
+		{name: "start without oauth", target: "/api/oauth/start", wantStatus: http.StatusConflict},
+		{name: "callback without oauth", target: "/api/oauth/callback?state=s1&code=good", wantStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewOAuthHandler(tt.authorizer)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/oauth/start", handler.StartHandler)
+			mux.HandleFunc("/api/oauth/callback", handler.CallbackHandler)
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest("GET", tt.target, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := rec.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"aktis-parser/internal/common"
+	"aktis-parser/internal/interfaces"
+	"github.com/ternarybob/arbor"
+)
+
+const (
+	// ProfileHeader selects the site profile for one API call, as does the profile query parameter
+	ProfileHeader = "X-Aktis-Profile"
+	// profileCookie holds the profile selected in the UI
+	profileCookie = "aktis_profile"
+)
+
+// Profile is one Atlassian site with its own auth, database and routes
+type Profile struct {
+	Name       string `json:"name"`
+	BaseURL    string `json:"baseUrl"`
+	AuthMethod string `json:"authMethod"`
+	// Handler serves every route against this profile's services
+	Handler http.Handler `json:"-"`
+}
+
+// ProfileRouter sends each request to the routes of the selected site profile
+// The profile is taken from ?profile=, then the X-Aktis-Profile header, then the UI cookie
+type ProfileRouter struct {
+	profiles    map[string]*Profile
+	names       []string
+	defaultName string
+	logger      arbor.ILogger
+}
+
+func NewProfileRouter(defaultName string) *ProfileRouter {
+	return &ProfileRouter{
+		profiles:    make(map[string]*Profile),
+		defaultName: defaultName,
+		logger:      common.GetLogger(),
+	}
+}
+
+// Add registers a profile; all profiles are added before the server starts
+func (pr *ProfileRouter) Add(profile *Profile) {
+	pr.profiles[profile.Name] = profile
+	pr.names = append(pr.names, profile.Name)
+}
+
+// ServeHTTP dispatches the request to the selected profile's routes
+func (pr *ProfileRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	profile, ok := pr.resolve(r)
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "Unknown profile: "+requestedProfile(r))
+		return
+	}
+
+	// The extension does not know about profiles, so captured auth goes to the profile for its site
+	if r.URL.Path == "/api/auth" && r.Method == "POST" && requestedProfile(r) == "" {
+		profile = pr.profileForAuth(r, profile)
+	}
+
+	// The OAuth callback carries no profile of its own, so the cookie keeps it across the consent screen
+	if r.URL.Path == "/api/oauth/start" {
+		http.SetCookie(w, &http.Cookie{
+			Name:     profileCookie,
+			Value:    profile.Name,
+			Path:     "/",
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	w.Header().Set(ProfileHeader, profile.Name)
+	profile.Handler.ServeHTTP(w, r)
+}
+
+// ProfilesHandler lists the site profiles and which one the request selects
+func (pr *ProfileRouter) ProfilesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	profiles := make([]*Profile, 0, len(pr.names))
+	for _, name := range pr.names {
+		profiles = append(profiles, pr.profiles[name])
+	}
+
+	selected := pr.defaultName
+	if profile, ok := pr.resolve(r); ok {
+		selected = profile.Name
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"profiles": profiles,
+		"selected": selected,
+		"default":  pr.defaultName,
+	})
+}
+
+// resolve returns the profile a request selects
+// An unknown name in the query or header is an error, a stale UI cookie falls back to the default
+func (pr *ProfileRouter) resolve(r *http.Request) (*Profile, bool) {
+	if name := requestedProfile(r); name != "" {
+		profile, ok := pr.profiles[name]
+		return profile, ok
+	}
+
+	if cookie, err := r.Cookie(profileCookie); err == nil {
+		if profile, ok := pr.profiles[cookie.Value]; ok {
+			return profile, true
+		}
+	}
+
+	return pr.profiles[pr.defaultName], true
+}
+
+// profileForAuth picks the profile whose base URL has the same host as the captured auth
+// The body is restored so the auth handler can decode it again
+func (pr *ProfileRouter) profileForAuth(r *http.Request, fallback *Profile) *Profile {
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return fallback
+	}
+
+	var authData interfaces.AuthData
+	if err := json.Unmarshal(body, &authData); err != nil {
+		return fallback
+	}
+
+	host := common.SiteHost(authData.BaseURL)
+	if host == "" {
+		return fallback
+	}
+	for _, name := range pr.names {
+		if profile := pr.profiles[name]; common.SiteHost(profile.BaseURL) == host {
+			if profile != fallback {
+				pr.logger.Info().Str("profile", profile.Name).Str("baseURL", authData.BaseURL).Msg("Routing captured auth to the profile for its site")
+			}
+			return profile
+		}
+	}
+	return fallback
+}
+
+// requestedProfile returns the profile named explicitly by the request, if any
+func requestedProfile(r *http.Request) string {
+	if name := r.URL.Query().Get("profile"); name != "" {
+		return name
+	}
+	return r.Header.Get(ProfileHeader)
+}
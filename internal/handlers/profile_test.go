@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestProfileRouter returns a router over two profiles whose handlers echo the profile name
+// and the request body they were given
+func newTestProfileRouter() *ProfileRouter {
+	router := NewProfileRouter("default")
+	for _, profile := range []*Profile{
+		{Name: "default", BaseURL: "https://main.atlassian.net"},
+		{Name: "staging", BaseURL: "https://Staging.atlassian.net/"},
+	} {
+		name := profile.Name
+		profile.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			w.Write([]byte(name + ":" + string(body)))
+		})
+		router.Add(profile)
+	}
+	return router
+}
+
+func TestProfileRouterSelectsProfile(t *testing.T) {
+	router := newTestProfileRouter()
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		header     string
+		cookie     string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "default without a choice", target: "/api/data/jira", wantStatus: http.StatusOK, wantBody: "default:"},
+		{name: "query parameter", target: "/api/data/jira?profile=staging", wantStatus: http.StatusOK, wantBody: "staging:"},
+		{name: "header", target: "/api/data/jira", header: "staging", wantStatus: http.StatusOK, wantBody: "staging:"},
+		{name: "cookie", target: "/api/data/jira", cookie: "staging", wantStatus: http.StatusOK, wantBody: "staging:"},
+		{name: "query parameter beats header", target: "/api/data/jira?profile=default", header: "staging", wantStatus: http.StatusOK, wantBody: "default:"},
+		{name: "header beats cookie", target: "/api/data/jira", header: "default", cookie: "staging", wantStatus: http.StatusOK, wantBody: "default:"},
+		{name: "unknown query parameter", target: "/api/data/jira?profile=nope", wantStatus: http.StatusNotFound},
+		{name: "unknown header", target: "/api/data/jira", header: "nope", wantStatus: http.StatusNotFound},
+		{name: "stale cookie falls back to default", target: "/api/data/jira", cookie: "nope", wantStatus: http.StatusOK, wantBody: "default:"},
+		{
+			name:       "captured auth goes to the profile for its site",
+			method:     "POST",
+			target:     "/api/auth",
+			body:       `{"baseUrl":"https://staging.atlassian.net"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `staging:{"baseUrl":"https://staging.atlassian.net"}`,
+		},
+		{
+			name:       "captured auth for an unknown site stays on the selected profile",
+			method:     "POST",
+			target:     "/api/auth",
+			body:       `{"baseUrl":"https://other.atlassian.net"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `default:{"baseUrl":"https://other.atlassian.net"}`,
+		},
+		{
+			name:       "an explicit profile wins over the auth site",
+			method:     "POST",
+			target:     "/api/auth?profile=default",
+			body:       `{"baseUrl":"https://staging.atlassian.net"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `default:{"baseUrl":"https://staging.atlassian.net"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = "GET"
+			}
+			req := httptest.NewRequest(method, tt.target, strings.NewReader(tt.body))
+			if tt.header != "" {
+				req.Header.Set(ProfileHeader, tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: profileCookie, Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				var result map[string]interface{}
+				if err := json.NewDecoder(rec.Body).Decode(&result); err != nil || result["status"] != "error" {
+					t.Errorf("error body = %v, %v; want a JSON error", result, err)
+				}
+				return
+			}
+			if got := rec.Body.String(); got != tt.wantBody {
+				t.Errorf("served %q, want %q", got, tt.wantBody)
+			}
+			if got := rec.Header().Get(ProfileHeader); !strings.HasPrefix(tt.wantBody, got+":") {
+				t.Errorf("%s response header = %q", ProfileHeader, got)
+			}
+		})
+	}
+}
+
+func TestProfileRouterKeepsProfileAcrossOAuth(t *testing.T) {
+	router := newTestProfileRouter()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/oauth/start?profile=staging", nil))
+
+	var cookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == profileCookie {
+			cookie = c
+		}
+	}
+	if cookie == nil || cookie.Value != "staging" {
+		t.Fatalf("oauth start cookies = %v, want %s=staging", rec.Result().Cookies(), profileCookie)
+	}
+
+	// The callback carries only the cookie
+	callback := httptest.NewRequest("GET", "/api/oauth/callback?code=x", nil)
+	callback.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, callback)
+	if got := rec.Body.String(); got != "staging:" {
+		t.Errorf("callback served by %q, want staging", got)
+	}
+}
+
+func TestProfilesHandler(t *testing.T) {
+	router := newTestProfileRouter()
+
+	req := httptest.NewRequest("GET", "/api/profiles", nil)
+	req.AddCookie(&http.Cookie{Name: profileCookie, Value: "staging"})
+	rec := httptest.NewRecorder()
+	router.ProfilesHandler(rec, req)
+
+	var result struct {
+		Profiles []Profile `json:"profiles"`
+		Selected string    `json:"selected"`
+		Default  string    `json:"default"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(result.Profiles) != 2 || result.Profiles[0].Name != "default" || result.Profiles[1].Name != "staging" {
+		t.Errorf("profiles = %+v, want default then staging", result.Profiles)
+	}
+	if result.Selected != "staging" || result.Default != "default" {
+		t.Errorf("selected %q, default %q", result.Selected, result.Default)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ProfilesHandler(rec, httptest.NewRequest("POST", "/api/profiles", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", rec.Code)
+	}
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"aktis-parser/internal/common"
+	"aktis-parser/internal/interfaces"
+	"aktis-parser/internal/services"
+	"github.com/ternarybob/arbor"
+)
+
+type QueryHandler struct {
+	queryService *services.QueryService
+	authService  interfaces.AuthService
+	logger       arbor.ILogger
+}
+
+func NewQueryHandler(queryService *services.QueryService, authService interfaces.AuthService) *QueryHandler {
+	return &QueryHandler{
+		queryService: queryService,
+		authService:  authService,
+		logger:       common.GetLogger(),
+	}
+}
+
+// savedQueryRequest is the body of a saved query create or update
+type savedQueryRequest struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Query string `json:"query"`
+}
+
+// QueriesHandler lists saved queries (GET) or creates one (POST)
+func (h *QueryHandler) QueriesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		queries, err := h.queryService.ListQueries()
+		if err != nil {
+			h.logger.Error().Err(err).Msg("Failed to list saved queries")
+			http.Error(w, "Failed to list saved queries", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"queries": queries,
+		})
+
+	case "POST":
+		var request savedQueryRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		if err := common.ValidateSavedQuery(request.Name, request.Type, request.Query); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		query, err := h.queryService.CreateQuery(request.Name, request.Type, request.Query)
+		if errors.Is(err, services.ErrQueryExists) {
+			writeJSONError(w, http.StatusConflict, "Saved query "+request.Name+" already exists")
+			return
+		}
+		if err != nil {
+			h.logger.Error().Err(err).Str("query", request.Name).Msg("Failed to create saved query")
+			http.Error(w, "Failed to create saved query", http.StatusInternalServerError)
+			return
+		}
+
+		h.logger.Info().Str("query", query.Name).Str("type", query.Type).Msg("Saved query created")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(query)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// SavedQueryHandler returns (GET), updates (PUT) or deletes (DELETE) a saved query by name
+// Queries defined in the config file are read-only and answer PUT and DELETE with 409
+func (h *QueryHandler) SavedQueryHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if name == "" {
+		http.Error(w, "query name required", http.StatusBadRequest)
+		return
+	}
+
+	var query *interfaces.SavedQuery
+	var err error
+
+	switch r.Method {
+	case "GET":
+		query, err = h.queryService.GetQuery(name)
+
+	case "PUT":
+		var request savedQueryRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		// The name comes from the path, queries cannot be renamed
+		if err := common.ValidateSavedQuery(name, request.Type, request.Query); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		query, err = h.queryService.UpdateQuery(name, request.Type, request.Query)
+
+	case "DELETE":
+		var deleted bool
+		deleted, err = h.queryService.DeleteQuery(name)
+		if err == nil && deleted {
+			h.logger.Info().Str("query", name).Msg("Saved query deleted")
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]string{
+				"status":  "success",
+				"message": "Saved query " + name + " deleted",
+			})
+			return
+		}
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if errors.Is(err, services.ErrQueryReadOnly) {
+		writeJSONError(w, http.StatusConflict, "Saved query "+name+" is defined in the config file and is read-only")
+		return
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("query", name).Str("method", r.Method).Msg("Failed to handle saved query")
+		http.Error(w, "Failed to handle saved query", http.StatusInternalServerError)
+		return
+	}
+	if query == nil {
+		writeJSONError(w, http.StatusNotFound, "No saved query named "+name)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(query)
+}
+
+// RunQueryHandler runs a saved query in the background and tags the matching records
+func (h *QueryHandler) RunQueryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := r.PathValue("name")
+	if name == "" {
+		http.Error(w, "query name required", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authService.IsAuthenticated() {
+		writeJSONError(w, http.StatusUnauthorized, "Not authenticated. Please capture authentication first.")
+		return
+	}
+
+	query, err := h.queryService.GetQuery(name)
+	if err != nil {
+		h.logger.Error().Err(err).Str("query", name).Msg("Failed to fetch saved query")
+		http.Error(w, "Failed to fetch saved query", http.StatusInternalServerError)
+		return
+	}
+	if query == nil {
+		writeJSONError(w, http.StatusNotFound, "No saved query named "+name)
+		return
+	}
+
+	go func() {
+		if _, err := h.queryService.RunQuery(name); err != nil {
+			h.logger.Error().Err(err).Str("query", name).Msg("Failed to run saved query")
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "started",
+		"message": "Running saved query " + name,
+	})
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"sync"
 
@@ -16,6 +17,7 @@ type ScraperHandler struct {
 	confluenceScraper interfaces.ConfluenceScraper
 	logger            arbor.ILogger
 	wsHandler         *WebSocketHandler
+	authValidator     interfaces.AuthValidator
 }
 
 func NewScraperHandler(authService interfaces.AuthService, jira interfaces.JiraScraper, confluence interfaces.ConfluenceScraper, ws *WebSocketHandler) *ScraperHandler {
@@ -28,6 +30,11 @@ func NewScraperHandler(authService interfaces.AuthService, jira interfaces.JiraS
 	}
 }
 
+// SetAuthValidator sets the validator run whenever the extension captures new auth
+func (h *ScraperHandler) SetAuthValidator(validator interfaces.AuthValidator) {
+	h.authValidator = validator
+}
+
 // AuthUpdateHandler handles authentication updates from Chrome extension
 func (h *ScraperHandler) AuthUpdateHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
@@ -44,6 +51,10 @@ func (h *ScraperHandler) AuthUpdateHandler(w http.ResponseWriter, r *http.Reques
 
 	// Update auth via centralized AuthService (shared by both scrapers)
 	if err := h.authService.UpdateAuth(&authData); err != nil {
+		if errors.Is(err, interfaces.ErrExtensionAuthDisabled) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		h.logger.Error().Err(err).Msg("Failed to update authentication")
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -51,6 +62,11 @@ func (h *ScraperHandler) AuthUpdateHandler(w http.ResponseWriter, r *http.Reques
 
 	h.logger.Info().Str("baseURL", authData.BaseURL).Msg("Authentication updated successfully")
 
+	// Check the captured session in the background, the status table picks up the result
+	if h.authValidator != nil {
+		go h.authValidator.Validate()
+	}
+
 	// Broadcast auth data to WebSocket clients
 	if h.wsHandler != nil {
 		h.wsHandler.BroadcastAuth(&authData)
@@ -120,6 +136,37 @@ func (h *ScraperHandler) ScrapeProjectsHandler(w http.ResponseWriter, r *http.Re
 	})
 }
 
+// ScrapeBoardsHandler scrapes Agile boards, sprints and epics in the background
+func (h *ScraperHandler) ScrapeBoardsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.authService.IsAuthenticated() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "error",
+			"message": "Not authenticated. Please capture authentication first.",
+		})
+		return
+	}
+
+	go func() {
+		if err := h.jiraScraper.ScrapeBoards(); err != nil {
+			h.logger.Error().Err(err).Msg("Board scrape error")
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "started",
+		"message": "Jira boards scraping started",
+	})
+}
+
 // ScrapeSpacesHandler triggers scraping of Confluence spaces only
 func (h *ScraperHandler) ScrapeSpacesHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
@@ -220,7 +267,12 @@ func (h *ScraperHandler) GetProjectIssuesHandler(w http.ResponseWriter, r *http.
 	}
 
 	var request struct {
-		ProjectKeys []string `json:"projectKeys"`
+		ProjectKeys      []string `json:"projectKeys"`
+		FullSync         bool     `json:"fullSync"`
+		Fields           []string `json:"fields"`
+		IncludeChangelog bool     `json:"includeChangelog"`
+		IncludeComments  bool     `json:"includeComments"`
+		IncludeWorklogs  bool     `json:"includeWorklogs"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
@@ -232,14 +284,22 @@ func (h *ScraperHandler) GetProjectIssuesHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	// Type assertion to access GetProjectIssues method
-	type projectIssueGetter interface {
-		GetProjectIssues(projectKey string) error
+	// Type assertion to access SyncProjectIssues method
+	type projectIssueSyncer interface {
+		SyncProjectIssues(projectKey string, options interfaces.IssueSyncOptions) error
+	}
+
+	options := interfaces.IssueSyncOptions{
+		FullSync:         request.FullSync,
+		Fields:           request.Fields,
+		IncludeChangelog: request.IncludeChangelog,
+		IncludeComments:  request.IncludeComments,
+		IncludeWorklogs:  request.IncludeWorklogs,
 	}
 
 	// Fetch issues for each project in parallel using goroutines
 	go func() {
-		if getter, ok := h.jiraScraper.(projectIssueGetter); ok {
+		if syncer, ok := h.jiraScraper.(projectIssueSyncer); ok {
 			var wg sync.WaitGroup
 
 			for _, projectKey := range request.ProjectKeys {
@@ -251,7 +311,7 @@ func (h *ScraperHandler) GetProjectIssuesHandler(w http.ResponseWriter, r *http.
 
 					h.logger.Info().Str("project", key).Msg("Starting parallel fetch for project")
 
-					if err := getter.GetProjectIssues(key); err != nil {
+					if err := syncer.SyncProjectIssues(key, options); err != nil {
 						h.logger.Error().Err(err).Str("project", key).Msg("Failed to get project issues")
 					} else {
 						h.logger.Info().Str("project", key).Msg("Completed parallel fetch for project")
@@ -344,6 +404,7 @@ func (h *ScraperHandler) GetSpacePagesHandler(w http.ResponseWriter, r *http.Req
 
 	var request struct {
 		SpaceKeys []string `json:"spaceKeys"`
+		FullSync  bool     `json:"fullSync"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		h.logger.Error().Err(err).Msg("Failed to decode request body")
@@ -359,12 +420,17 @@ func (h *ScraperHandler) GetSpacePagesHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	type spacePageGetter interface {
-		GetSpacePages(spaceKey string) error
+	// Type assertion to access SyncSpacePages method
+	type spacePageSyncer interface {
+		SyncSpacePages(spaceKey string, options interfaces.PageSyncOptions) error
+	}
+
+	options := interfaces.PageSyncOptions{
+		FullSync: request.FullSync,
 	}
 
 	go func() {
-		if getter, ok := h.confluenceScraper.(spacePageGetter); ok {
+		if syncer, ok := h.confluenceScraper.(spacePageSyncer); ok {
 			var wg sync.WaitGroup
 
 			for _, spaceKey := range request.SpaceKeys {
@@ -375,7 +441,7 @@ func (h *ScraperHandler) GetSpacePagesHandler(w http.ResponseWriter, r *http.Req
 
 					h.logger.Info().Str("space", key).Msg("Starting parallel fetch for space")
 
-					if err := getter.GetSpacePages(key); err != nil {
+					if err := syncer.SyncSpacePages(key, options); err != nil {
 						h.logger.Error().Err(err).Str("space", key).Msg("Failed to get space pages")
 					} else {
 						h.logger.Info().Str("space", key).Msg("Completed parallel fetch for space")
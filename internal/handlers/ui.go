@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"fmt"
+	"html"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -16,6 +17,7 @@ type UIHandler struct {
 	staticDir         string
 	jiraScraper       interfaces.JiraScraper
 	confluenceScraper interfaces.ConfluenceScraper
+	authStatus        interfaces.AuthStatusProvider
 }
 
 func NewUIHandler(jira interfaces.JiraScraper, confluence interfaces.ConfluenceScraper) *UIHandler {
@@ -27,6 +29,11 @@ func NewUIHandler(jira interfaces.JiraScraper, confluence interfaces.ConfluenceS
 	}
 }
 
+// SetAuthStatusProvider sets the source of the validated auth state shown in the status table
+func (h *UIHandler) SetAuthStatusProvider(provider interfaces.AuthStatusProvider) {
+	h.authStatus = provider
+}
+
 // getStaticDir finds the pages directory
 func getStaticDir() string {
 	dirs := []string{
@@ -60,7 +67,8 @@ func (h *UIHandler) IndexHandler(w http.ResponseWriter, r *http.Request) {
 func (h *UIHandler) StatusHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html")
 
-	html := `
+	authLabel := html.EscapeString(authStatusLabel(h.authStatus))
+	html := fmt.Sprintf(`
 		<tr>
 			<td class="status-label">Parser Service</td>
 			<td class="status-value status-online">ONLINE</td>
@@ -71,9 +79,9 @@ func (h *UIHandler) StatusHandler(w http.ResponseWriter, r *http.Request) {
 		</tr>
 		<tr>
 			<td class="status-label">Extension Auth</td>
-			<td class="status-value">WAITING</td>
+			<td class="status-value">%s</td>
 		</tr>
-	`
+	`, authLabel)
 
 	fmt.Fprint(w, html)
 }
@@ -127,12 +135,13 @@ func (h *UIHandler) ConfluencePageHandler(w http.ResponseWriter, r *http.Request
 	http.ServeFile(w, r, confluencePath)
 }
 
-// StaticFileHandler serves static files (CSS, favicon) from the pages/static directory
+// StaticFileHandler serves static files (CSS, scripts, favicon) from the pages/static directory
 func (h *UIHandler) StaticFileHandler(w http.ResponseWriter, r *http.Request) {
 	// List of allowed static files
 	allowedFiles := map[string]string{
-		"/static/common.css": "static/common.css",
-		"/favicon.ico":       "favicon.ico",
+		"/static/common.css":  "static/common.css",
+		"/static/profiles.js": "static/profiles.js",
+		"/favicon.ico":        "favicon.ico",
 	}
 
 	// Check if the requested path is allowed
@@ -156,6 +165,8 @@ func (h *UIHandler) StaticFileHandler(w http.ResponseWriter, r *http.Request) {
 	switch ext {
 	case ".css":
 		w.Header().Set("Content-Type", "text/css")
+	case ".js":
+		w.Header().Set("Content-Type", "text/javascript")
 	case ".ico":
 		w.Header().Set("Content-Type", "image/x-icon")
 	}
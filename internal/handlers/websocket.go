@@ -34,6 +34,7 @@ type WebSocketHandler struct {
 	lastLogKeys map[string]bool
 	logKeysMu   sync.RWMutex
 	authLoader  AuthLoader
+	authStatus  interfaces.AuthStatusProvider
 }
 
 func NewWebSocketHandler() *WebSocketHandler {
@@ -50,6 +51,11 @@ func (h *WebSocketHandler) SetAuthLoader(loader AuthLoader) {
 	h.authLoader = loader
 }
 
+// SetAuthStatusProvider sets the source of the validated auth state shown in status updates
+func (h *WebSocketHandler) SetAuthStatusProvider(provider interfaces.AuthStatusProvider) {
+	h.authStatus = provider
+}
+
 // BroadcastUILog sends a formatted log message directly to UI clients
 // This bypasses the arbor logger and sends complete, formatted messages
 func (h *WebSocketHandler) BroadcastUILog(level, message string) {
@@ -77,6 +83,9 @@ type StatusUpdate struct {
 	IssuesCount   int    `json:"issuesCount"`
 	PagesCount    int    `json:"pagesCount"`
 	LastScrape    string `json:"lastScrape"`
+
+	// Auth is the full validation result behind ExtensionAuth
+	Auth *interfaces.AuthStatus `json:"auth,omitempty"`
 }
 
 type LogEntry struct {
@@ -262,7 +271,8 @@ func (h *WebSocketHandler) sendStatus(conn *websocket.Conn) {
 		Service:       "ONLINE",
 		Status:        "ONLINE",
 		Database:      "CONNECTED",
-		ExtensionAuth: "WAITING",
+		ExtensionAuth: authStatusLabel(h.authStatus),
+		Auth:          h.currentAuthStatus(),
 		ProjectsCount: 0,
 		IssuesCount:   0,
 		PagesCount:    0,
@@ -295,6 +305,14 @@ func (h *WebSocketHandler) sendStatus(conn *websocket.Conn) {
 	}
 }
 
+// currentAuthStatus returns the last auth validation result, nil until a provider is set
+func (h *WebSocketHandler) currentAuthStatus() *interfaces.AuthStatus {
+	if h.authStatus == nil {
+		return nil
+	}
+	return h.authStatus.Status()
+}
+
 // StartStatusBroadcaster starts periodic status updates
 func (h *WebSocketHandler) StartStatusBroadcaster() {
 	ticker := time.NewTicker(5 * time.Second)
@@ -309,7 +327,8 @@ func (h *WebSocketHandler) StartStatusBroadcaster() {
 					Service:       "ONLINE",
 					Status:        "ONLINE",
 					Database:      "CONNECTED",
-					ExtensionAuth: "WAITING",
+					ExtensionAuth: authStatusLabel(h.authStatus),
+					Auth:          h.currentAuthStatus(),
 					ProjectsCount: 0,
 					IssuesCount:   0,
 					PagesCount:    0,
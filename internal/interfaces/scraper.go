@@ -1,7 +1,10 @@
 package interfaces
 
 import (
+	"errors"
+	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -56,6 +59,9 @@ type JiraScraper interface {
 	// GetProjectIssues retrieves all issues for a given project
 	GetProjectIssues(projectKey string) error
 
+	// SyncProjectIssues syncs issues for a project, incrementally when a watermark exists
+	SyncProjectIssues(projectKey string, options IssueSyncOptions) error
+
 	// GetProjectIssueCount returns the total count of issues for a project
 	GetProjectIssueCount(projectKey string) (int, error)
 
@@ -68,6 +74,37 @@ type JiraScraper interface {
 	// GetJiraData returns all Jira data (projects and issues)
 	GetJiraData() (map[string]interface{}, error)
 
+	// GetJiraDataWithOptions returns all Jira data, optionally enriched with related records
+	GetJiraDataWithOptions(options JiraDataOptions) (map[string]interface{}, error)
+
+	// GetIssueHistory returns the stored changelog for an issue, or nil if none is stored
+	GetIssueHistory(issueKey string) (map[string]interface{}, error)
+
+	// GetIssueGraph returns the link neighborhood of an issue up to the given depth, or nil if the issue is unknown
+	GetIssueGraph(issueKey string, depth int, linkTypes []string) (*IssueGraph, error)
+
+	// GetBlockedByChain returns the blockers of an issue and its children in dependency order, or nil if the issue is unknown
+	GetBlockedByChain(issueKey string) (*BlockedByChain, error)
+
+	// ScrapeBoards scrapes Agile boards with their sprints, sprint issues and epics
+	ScrapeBoards() error
+
+	// GetBoards returns all stored Agile boards
+	GetBoards() ([]map[string]interface{}, error)
+
+	// GetBoardSprints returns the stored sprints of a board
+	GetBoardSprints(boardID string) ([]map[string]interface{}, error)
+
+	// GetPruneReports returns the reconciliation reports of a project, or of every project when empty, newest first
+	GetPruneReports(projectKey string) ([]PruneReport, error)
+
+	// GetTombstones returns the tombstones of issues pruned from a project, or from every project when empty
+	GetTombstones(projectKey string) ([]Tombstone, error)
+
+	// RunJQLQuery stores every issue matching a JQL query and tags them with the query name
+	// Issues tagged by an earlier run that no longer match lose the tag; returns the number of matches
+	RunJQLQuery(name, jql string) (int, error)
+
 	// GetProjectCount returns the count of projects in the database
 	GetProjectCount() int
 
@@ -75,6 +112,124 @@ type JiraScraper interface {
 	GetIssueCount() int
 }
 
+// IssueSyncOptions controls how a project's issues are synced
+type IssueSyncOptions struct {
+	// FullSync discards the stored watermark and re-downloads every issue
+	FullSync bool `json:"fullSync"`
+
+	// Fields overrides the configured field selection (presets, field ids or names)
+	Fields []string `json:"fields"`
+
+	// IncludeChangelog fetches each issue's changelog into the issue_history bucket
+	IncludeChangelog bool `json:"includeChangelog"`
+
+	// IncludeComments fetches each issue's comments into the issue_comments bucket
+	IncludeComments bool `json:"includeComments"`
+
+	// IncludeWorklogs fetches each issue's worklogs into the issue_worklogs bucket
+	IncludeWorklogs bool `json:"includeWorklogs"`
+}
+
+// JiraDataOptions selects the related records included by GetJiraDataWithOptions
+type JiraDataOptions struct {
+	// IncludeHistory attaches each issue's stored changelog as "history"
+	IncludeHistory bool
+
+	// IncludeComments attaches each issue's stored comments as "comments"
+	IncludeComments bool
+
+	// IncludeWorklogs attaches each issue's stored worklogs as "worklogs"
+	IncludeWorklogs bool
+
+	// IncludeSprints attaches the sprints each issue belongs to as "sprints"
+	IncludeSprints bool
+}
+
+// Tombstone records an issue or page that was removed from the local store because it
+// no longer exists where it was synced from
+type Tombstone struct {
+	// Source is "jira" or "confluence"
+	Source string `json:"source"`
+	// Key is the issue key or page id the record was stored under
+	Key   string `json:"key"`
+	ID    string `json:"id,omitempty"`
+	Title string `json:"title,omitempty"`
+	// Container is the project or space key the record was synced from
+	Container string `json:"container"`
+	// Reason is "deleted", or "moved" when the record now lives elsewhere
+	Reason string `json:"reason"`
+	// MovedTo is the new issue key, or the new space key of a page
+	MovedTo   string    `json:"movedTo,omitempty"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// PruneReport describes what one sync's reconciliation pass pruned from a project or space
+type PruneReport struct {
+	Source    string    `json:"source"`
+	Container string    `json:"container"`
+	SyncedAt  time.Time `json:"syncedAt"`
+	FullSync  bool      `json:"fullSync"`
+	// LocalCount is the number of records stored before the sync, RemoteCount the number found remotely
+	LocalCount  int         `json:"localCount"`
+	RemoteCount int         `json:"remoteCount"`
+	Deleted     []Tombstone `json:"deleted"`
+	Moved       []Tombstone `json:"moved"`
+	// Errors lists records that could not be checked and were left in place
+	Errors []string `json:"errors,omitempty"`
+}
+
+// SavedQuery is a named JQL or CQL query whose matches are stored and tagged with its name
+type SavedQuery struct {
+	Name string `json:"name"`
+	// Type is "jql" for Jira issues or "cql" for Confluence pages and blog posts
+	Type  string `json:"type"`
+	Query string `json:"query"`
+	// Source is "config" for queries defined in the config file, which are read-only, or "api"
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	LastRun   time.Time `json:"lastRun,omitempty"`
+	LastCount int       `json:"lastCount"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+// IssueLink is a directed edge of the issue link graph, from the outward to the inward issue
+// Parent/subtask relations use the "Parent" type with the parent as From
+type IssueLink struct {
+	Type    string `json:"type"`    // Link type name, e.g. "Blocks", "Cloners" or "Parent"
+	From    string `json:"from"`    // Issue on the outward side ("blocks")
+	To      string `json:"to"`      // Issue on the inward side ("is blocked by")
+	Outward string `json:"outward"` // Description read from From, e.g. "blocks"
+	Inward  string `json:"inward"`  // Description read from To, e.g. "is blocked by"
+}
+
+// IssueGraphNode is an issue in a graph response, with the fields needed to render it
+type IssueGraphNode struct {
+	Key            string `json:"key"`
+	Summary        string `json:"summary,omitempty"`
+	Status         string `json:"status,omitempty"`
+	StatusCategory string `json:"statusCategory,omitempty"` // "new", "indeterminate" or "done"
+	IssueType      string `json:"issueType,omitempty"`
+	Depth          int    `json:"depth"`
+	Stored         bool   `json:"stored"` // False for linked issues outside the synced projects
+}
+
+// IssueGraph is the neighborhood of an issue in the link graph
+type IssueGraph struct {
+	Root  string           `json:"root"`
+	Depth int              `json:"depth"`
+	Nodes []IssueGraphNode `json:"nodes"`
+	Links []IssueLink      `json:"links"`
+}
+
+// BlockedByChain lists everything blocking an issue or its children, blockers first
+type BlockedByChain struct {
+	Root  string           `json:"root"`
+	Order []IssueGraphNode `json:"order"` // Topological order, an issue appears after all of its blockers
+	Links []IssueLink      `json:"links"` // The "Blocks" and "Parent" edges that make up the chain
+	Cycle []string         `json:"cycle"` // Issues that block each other and cannot be ordered
+}
+
 // ConfluenceScraper defines the interface for Confluence scraping operations
 type ConfluenceScraper interface {
 	BaseScraper
@@ -85,6 +240,9 @@ type ConfluenceScraper interface {
 	// GetSpacePages fetches pages for a specific Confluence space
 	GetSpacePages(spaceKey string) error
 
+	// SyncSpacePages syncs a space's pages, incrementally when a watermark exists
+	SyncSpacePages(spaceKey string, options PageSyncOptions) error
+
 	// GetSpacePageCount returns the total count of pages for a space
 	GetSpacePageCount(spaceKey string) (int, error)
 
@@ -94,6 +252,30 @@ type ConfluenceScraper interface {
 	// GetConfluenceData returns all Confluence data (spaces and pages)
 	GetConfluenceData() (map[string]interface{}, error)
 
+	// GetConfluenceDataWithOptions returns all Confluence data with the selected related records attached to each page
+	GetConfluenceDataWithOptions(options ConfluenceDataOptions) (map[string]interface{}, error)
+
+	// GetSpaceTree returns the page tree of a space below parentID (the space roots when empty)
+	// down to depth levels, or nil if no tree is stored for the space
+	GetSpaceTree(spaceKey, parentID string, depth int) ([]PageTreeNode, error)
+
+	// GetPageVersions returns the stored versions of a page, newest first, without their bodies
+	GetPageVersions(pageID string) ([]PageVersion, error)
+
+	// GetPageVersionDiff returns a unified diff between two stored versions of a page, or nil if either is missing
+	// A zero to selects the latest stored version and a zero from the stored version before it
+	GetPageVersionDiff(pageID string, from, to int) (*PageVersionDiff, error)
+
+	// GetPruneReports returns the reconciliation reports of a space, or of every space when empty, newest first
+	GetPruneReports(spaceKey string) ([]PruneReport, error)
+
+	// GetTombstones returns the tombstones of pages pruned from a space, or from every space when empty
+	GetTombstones(spaceKey string) ([]Tombstone, error)
+
+	// RunCQLQuery stores every page or blog post matching a CQL query and tags them with the query name
+	// Records tagged by an earlier run that no longer match lose the tag; returns the number of matches
+	RunCQLQuery(name, cql string) (int, error)
+
 	// GetSpaceCount returns the count of Confluence spaces in the database
 	GetSpaceCount() int
 
@@ -101,6 +283,82 @@ type ConfluenceScraper interface {
 	GetPageCount() int
 }
 
+// PageSyncOptions controls how a space's pages are synced
+type PageSyncOptions struct {
+	// FullSync ignores the stored watermark and re-downloads every page
+	FullSync bool `json:"fullSync"`
+}
+
+// ConfluenceDataOptions selects the related records included by GetConfluenceDataWithOptions
+type ConfluenceDataOptions struct {
+	// IncludeComments attaches each page's footer and inline comment threads as "comments",
+	// with replies nested under "replies"
+	IncludeComments bool
+}
+
+// PageTreeNode is a page in a space's navigation tree
+// Children are only filled down to the requested depth; HasChildren tells clients whether to load more
+type PageTreeNode struct {
+	ID          string         `json:"id"`
+	Title       string         `json:"title"`
+	ParentID    string         `json:"parentId,omitempty"`
+	Position    int            `json:"position"`
+	HasChildren bool           `json:"hasChildren"`
+	ChildCount  int            `json:"childCount"`
+	Children    []PageTreeNode `json:"children,omitempty"`
+}
+
+// PageVersion is one stored version of a Confluence page
+type PageVersion struct {
+	PageID    string `json:"pageId"`
+	Number    int    `json:"number"`
+	Title     string `json:"title"`
+	Message   string `json:"message,omitempty"`
+	MinorEdit bool   `json:"minorEdit"`
+	AuthorID  string `json:"authorId,omitempty"`
+	CreatedAt string `json:"createdAt,omitempty"`
+	Body      string `json:"body,omitempty"`
+}
+
+// PageVersionDiff is a unified diff between two versions of a page, compared as Markdown
+type PageVersionDiff struct {
+	PageID string `json:"pageId"`
+	From   int    `json:"from"`
+	To     int    `json:"to"`
+	Diff   string `json:"diff"`
+}
+
+// AttachmentStore downloads attachments into local storage for the scrapers
+type AttachmentStore interface {
+	// Enabled reports whether attachment downloads are turned on
+	Enabled() bool
+
+	// StoreAttachment downloads an attachment and records its metadata
+	StoreAttachment(attachment *Attachment) error
+}
+
+// AttachmentReader serves the attachments in local storage
+type AttachmentReader interface {
+	// GetAttachment returns the stored metadata for an attachment, or nil if it is unknown
+	GetAttachment(id string) (*Attachment, error)
+
+	// OpenAttachment opens the stored content of an attachment
+	OpenAttachment(attachment *Attachment) (io.ReadSeekCloser, error)
+}
+
+// Attachment describes a downloaded Jira or Confluence attachment
+type Attachment struct {
+	ID          string    `json:"id"`
+	Source      string    `json:"source"`   // "jira" or "confluence"
+	OwnerKey    string    `json:"ownerKey"` // Issue key or page ID the attachment belongs to
+	Filename    string    `json:"filename"`
+	MimeType    string    `json:"mimeType"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	DownloadURL string    `json:"downloadUrl"`
+	StoredAt    time.Time `json:"storedAt"`
+}
+
 // ClearableData defines interface for services that can clear their data
 type ClearableData interface {
 	ClearAllData() error
@@ -168,6 +426,121 @@ func (ad *AuthData) GetHTTPCookies() []*http.Cookie {
 	return cookies
 }
 
+// SessionCookieExpiry returns the earliest expiry of the session cookies, and the cookie's name
+// Session cookies are those named like a session (cloud.session.token, JSESSIONID) or seraph.*;
+// the zero time is returned when none of them carries an expiry
+func (ad *AuthData) SessionCookieExpiry() (time.Time, string) {
+	var earliest time.Time
+	name := ""
+	for _, cookie := range ad.Cookies {
+		if cookie.Expires <= 0 || !IsSessionCookie(cookie.Name) {
+			continue
+		}
+		expires := time.Unix(cookie.Expires, 0)
+		if earliest.IsZero() || expires.Before(earliest) {
+			earliest = expires
+			name = cookie.Name
+		}
+	}
+	return earliest, name
+}
+
+// IsSessionCookie reports whether a cookie carries the login session rather than preferences or tracking
+func IsSessionCookie(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.Contains(lower, "session") || strings.HasPrefix(lower, "seraph.")
+}
+
+// Auth validation states
+const (
+	AuthStateWaiting = "WAITING"
+	AuthStateValid   = "VALID"
+	AuthStateExpired = "EXPIRED"
+	AuthStateError   = "ERROR"
+)
+
+// AuthIdentity is the account an API reports the credentials belong to
+type AuthIdentity struct {
+	AccountID   string `json:"accountId"`
+	DisplayName string `json:"displayName"`
+	Email       string `json:"email,omitempty"`
+}
+
+// AuthStatus is the result of actively validating the current credentials against Jira and Confluence
+type AuthStatus struct {
+	// State is WAITING (no credentials), VALID (either product accepted them), EXPIRED or ERROR
+	State   string `json:"state"`
+	BaseURL string `json:"baseUrl,omitempty"`
+	// Jira and Confluence are set when the product accepted the credentials, otherwise the error says why
+	Jira            *AuthIdentity `json:"jira,omitempty"`
+	JiraError       string        `json:"jiraError,omitempty"`
+	Confluence      *AuthIdentity `json:"confluence,omitempty"`
+	ConfluenceError string        `json:"confluenceError,omitempty"`
+	// CookieExpiresAt is when the earliest session cookie expires, for extension captured auth
+	CookieExpiresAt *time.Time `json:"cookieExpiresAt,omitempty"`
+	ExpiringCookie  string     `json:"expiringCookie,omitempty"`
+	CheckedAt       *time.Time `json:"checkedAt,omitempty"`
+}
+
+// Identity returns the Jira identity, or the Confluence one when Jira rejected the credentials
+func (as *AuthStatus) Identity() *AuthIdentity {
+	if as.Jira != nil {
+		return as.Jira
+	}
+	return as.Confluence
+}
+
+// AuthHistoryEntry describes one session kept in the auth history, without its cookies
+type AuthHistoryEntry struct {
+	ID string `json:"id"`
+	// Source is "capture" (from the extension), "rotation" (session cookies rotated by Atlassian),
+	// "restore" (restored from an earlier entry) or "stored" (found in storage on startup)
+	Source           string     `json:"source"`
+	BaseURL          string     `json:"baseUrl"`
+	Cookies          int        `json:"cookies"`
+	SessionExpiresAt *time.Time `json:"sessionExpiresAt,omitempty"`
+	// ValidatedAt is the last time the session was validated while current, so it is known to have worked
+	ValidatedAt *time.Time `json:"validatedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+var (
+	// ErrExtensionAuthDisabled is returned when the extension posts auth while another auth method is configured
+	ErrExtensionAuthDisabled = errors.New("browser extension authentication is disabled by auth_method")
+	// ErrAuthHistoryNotFound is returned when restoring an auth history entry that does not exist
+	ErrAuthHistoryNotFound = errors.New("auth history entry not found")
+	// ErrOAuthState is returned when a callback does not match an authorization started by this service
+	ErrOAuthState = errors.New("unknown or expired OAuth state")
+)
+
+// AuthStatusProvider returns the result of the last authentication validation
+type AuthStatusProvider interface {
+	Status() *AuthStatus
+}
+
+// AuthValidator checks the current credentials against Jira and Confluence
+type AuthValidator interface {
+	AuthStatusProvider
+
+	// Validate checks the credentials now, records the result and returns it
+	Validate() *AuthStatus
+}
+
+// AuthHistoryStore lists earlier sessions and restores one of them as the current auth
+type AuthHistoryStore interface {
+	AuthHistory() ([]*AuthHistoryEntry, error)
+	RestoreAuth(id string) (*AuthData, error)
+}
+
+// OAuthAuthorizer runs the OAuth 2.0 (3LO) authorization code flow
+type OAuthAuthorizer interface {
+	// AuthorizationURL starts an authorization and returns the Atlassian consent URL to send the user to
+	AuthorizationURL() (string, error)
+
+	// Exchange completes an authorization with the state and code of the callback
+	Exchange(state, code string) error
+}
+
 // LoggingService interface defines methods for application logging
 type LoggingService interface {
 	// Core logging methods
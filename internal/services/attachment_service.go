@@ -0,0 +1,249 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"aktis-parser/internal/common"
+	"aktis-parser/internal/interfaces"
+	. "github.com/ternarybob/arbor"
+	bolt "go.etcd.io/bbolt"
+)
+
+// AttachmentService downloads attachments into a content-addressed blob store
+// Blobs are stored on disk under their SHA-256 hash, metadata in the attachments bucket
+type AttachmentService struct {
+	authService interfaces.AuthService
+	config      *common.AttachmentsConfig
+	db          *bolt.DB
+	dir         string
+	log         ILogger
+}
+
+// NewAttachmentService creates a new attachment service storing blobs next to the database file
+func NewAttachmentService(db *bolt.DB, authService interfaces.AuthService, config *common.AttachmentsConfig, logger ILogger) (*AttachmentService, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte("attachments"))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attachments bucket: %w", err)
+	}
+
+	dir := config.Directory
+	if dir == "" {
+		dir = filepath.Join(filepath.Dir(db.Path()), "attachments")
+	}
+
+	return &AttachmentService{
+		authService: authService,
+		config:      config,
+		db:          db,
+		dir:         dir,
+		log:         logger,
+	}, nil
+}
+
+// Enabled reports whether attachment downloads are turned on
+func (s *AttachmentService) Enabled() bool {
+	return s.config != nil && s.config.Enabled
+}
+
+// StoreAttachment downloads an attachment and records its metadata
+// Attachments that are too large, have a disallowed MIME type or are already stored are skipped
+func (s *AttachmentService) StoreAttachment(attachment *interfaces.Attachment) error {
+	if !s.Enabled() {
+		return nil
+	}
+
+	maxBytes := int64(s.config.MaxSizeMB) * 1024 * 1024
+
+	if !s.mimeTypeAllowed(attachment.MimeType) {
+		s.log.Debug().
+			Str("id", attachment.ID).
+			Str("mimeType", attachment.MimeType).
+			Msg("Skipping attachment with disallowed MIME type")
+		return nil
+	}
+
+	if maxBytes > 0 && attachment.Size > maxBytes {
+		s.log.Debug().
+			Str("id", attachment.ID).
+			Int64("size", attachment.Size).
+			Msg("Skipping attachment over size limit")
+		return nil
+	}
+
+	// Skip attachments whose blob is already stored; the content URL changes with a new version,
+	// while the listed size may differ from the stored bytes and is no sign of new content
+	if existing, err := s.GetAttachment(attachment.ID); err == nil && existing != nil {
+		if existing.DownloadURL == attachment.DownloadURL {
+			if _, err := os.Stat(s.blobPath(existing.SHA256)); err == nil {
+				return nil
+			}
+		}
+	}
+
+	hash, size, err := s.download(attachment.DownloadURL, maxBytes)
+	if err != nil {
+		return fmt.Errorf("failed to download attachment %s: %w", attachment.ID, err)
+	}
+
+	attachment.SHA256 = hash
+	attachment.Size = size
+	attachment.StoredAt = time.Now()
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("attachments"))
+		value, err := json.Marshal(attachment)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(attachment.ID), value)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store attachment metadata %s: %w", attachment.ID, err)
+	}
+
+	s.log.Debug().
+		Str("id", attachment.ID).
+		Str("filename", attachment.Filename).
+		Str("sha256", hash).
+		Msg("Stored attachment")
+
+	return nil
+}
+
+// download streams a URL into the blob store and returns its SHA-256 hash and size
+func (s *AttachmentService) download(downloadURL string, maxBytes int64) (string, int64, error) {
+	req, err := http.NewRequest("GET", downloadURL, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("User-Agent", s.authService.GetUserAgent())
+
+	resp, err := s.authService.GetHTTPClient().Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		if resp.StatusCode == 401 || resp.StatusCode == 403 {
+			return "", 0, fmt.Errorf("auth expired (status %d)", resp.StatusCode)
+		}
+		return "", 0, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return "", 0, fmt.Errorf("failed to create attachments directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, "download-*")
+	if err != nil {
+		return "", 0, err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	// Read one byte past the limit so oversized bodies can be detected
+	var reader io.Reader = resp.Body
+	if maxBytes > 0 {
+		reader = io.LimitReader(resp.Body, maxBytes+1)
+	}
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), reader)
+	closeErr := tmp.Close()
+	if err != nil {
+		return "", 0, err
+	}
+	if closeErr != nil {
+		return "", 0, closeErr
+	}
+
+	if maxBytes > 0 && size > maxBytes {
+		return "", 0, fmt.Errorf("attachment exceeds size limit of %d bytes", maxBytes)
+	}
+
+	hash := hex.EncodeToString(hasher.Sum(nil))
+	blobPath := s.blobPath(hash)
+
+	// Identical content is only stored once
+	if _, err := os.Stat(blobPath); err == nil {
+		return hash, size, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0755); err != nil {
+		return "", 0, err
+	}
+	if err := os.Rename(tmpPath, blobPath); err != nil {
+		return "", 0, err
+	}
+
+	return hash, size, nil
+}
+
+// GetAttachment returns the stored metadata for an attachment, or nil if it is unknown
+func (s *AttachmentService) GetAttachment(id string) (*interfaces.Attachment, error) {
+	var attachment *interfaces.Attachment
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("attachments"))
+		if bucket == nil {
+			return nil
+		}
+		data := bucket.Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		attachment = &interfaces.Attachment{}
+		return json.Unmarshal(data, attachment)
+	})
+	return attachment, err
+}
+
+// OpenAttachment opens the stored blob for an attachment
+func (s *AttachmentService) OpenAttachment(attachment *interfaces.Attachment) (io.ReadSeekCloser, error) {
+	return os.Open(s.blobPath(attachment.SHA256))
+}
+
+// blobPath returns the on-disk location of a blob, sharded by the first two hash characters
+func (s *AttachmentService) blobPath(hash string) string {
+	if len(hash) < 2 {
+		return filepath.Join(s.dir, hash)
+	}
+	return filepath.Join(s.dir, hash[:2], hash)
+}
+
+// mimeTypeAllowed checks a MIME type against the allow-list, which supports "type/*" wildcards
+// An empty allow-list permits every type
+func (s *AttachmentService) mimeTypeAllowed(mimeType string) bool {
+	if len(s.config.AllowedMimeTypes) == 0 {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(mimeType))
+	}
+
+	for _, allowed := range s.config.AllowedMimeTypes {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == "*" || allowed == "*/*" || allowed == mediaType {
+			return true
+		}
+		if strings.HasSuffix(allowed, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(allowed, "*")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"aktis-parser/internal/common"
+	"aktis-parser/internal/interfaces"
+)
+
+func TestStoreAttachmentDownloadsNewContentOnly(t *testing.T) {
+	var mu sync.Mutex
+	downloads := 0
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		downloads++
+		mu.Unlock()
+		w.Write([]byte("content of " + r.URL.RawQuery))
+	}))
+	t.Cleanup(site.Close)
+
+	auth := &testAuth{baseURL: site.URL, client: site.Client()}
+	attachments, err := NewAttachmentService(openTestDB(t), auth, &common.AttachmentsConfig{Enabled: true, Directory: t.TempDir()}, common.GetLogger())
+	if err != nil {
+		t.Fatalf("NewAttachmentService: %v", err)
+	}
+
+	store := func(size int64, version string) {
+		t.Helper()
+		err := attachments.StoreAttachment(&interfaces.Attachment{
+			ID:          "att1",
+			Source:      "confluence",
+			Filename:    "notes.txt",
+			Size:        size,
+			DownloadURL: site.URL + "/download/attachments/1/notes.txt?version=" + version,
+		})
+		if err != nil {
+			t.Fatalf("StoreAttachment: %v", err)
+		}
+	}
+	downloadCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return downloads
+	}
+
+	store(10, "1")
+	// The listed size is not the stored size, which alone is no reason to download again
+	store(999, "1")
+	if got := downloadCount(); got != 1 {
+		t.Errorf("downloads after storing the same version twice = %d, want 1", got)
+	}
+
+	store(10, "2")
+	if got := downloadCount(); got != 2 {
+		t.Errorf("downloads after a new version = %d, want 2", got)
+	}
+
+	stored, err := attachments.GetAttachment("att1")
+	if err != nil || stored == nil {
+		t.Fatalf("GetAttachment = %v, %v", stored, err)
+	}
+	file, err := attachments.OpenAttachment(stored)
+	if err != nil {
+		t.Fatalf("OpenAttachment: %v", err)
+	}
+	defer file.Close()
+	if content, _ := io.ReadAll(file); string(content) != "content of version=2" {
+		t.Errorf("stored content = %q, want the new version", content)
+	}
+}
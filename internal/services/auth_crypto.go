@@ -0,0 +1,446 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode"
+
+	"aktis-parser/internal/common"
+	. "github.com/ternarybob/arbor"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Environment variables holding the auth encryption keys as base64; they take precedence over key files
+const (
+	authKeyEnv          = "AUTH_ENCRYPTION_KEY"
+	authPreviousKeysEnv = "AUTH_ENCRYPTION_PREVIOUS_KEYS"
+)
+
+// authKeySize is the size of both the key-encryption keys and the per-record data keys (AES-256)
+const authKeySize = 32
+
+// Envelope versions: version 2 binds the record ciphertext to its profile and its key in the auth
+// bucket as GCM additional data, so a record copied under another key or into another profile's
+// database fails to open; version 1 records have no additional data and are re-sealed as version 2 on startup
+const (
+	authEnvelopeUnbound = 1
+	authEnvelopeBound   = 2
+)
+
+// authEnvelope is the stored form of an encrypted auth record
+// The record is sealed with a fresh data key, which is itself sealed with the key-encryption key
+// named by KeyID, so rotating keys only re-wraps the data key
+type authEnvelope struct {
+	Envelope   int    `json:"envelope"`
+	KeyID      string `json:"keyId"`
+	WrappedKey string `json:"wrappedKey"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// AuthCipher encrypts the records of the auth bucket with AES-GCM envelope encryption
+// A nil *AuthCipher stores records as plain JSON
+type AuthCipher struct {
+	primaryID string
+	keys      map[string][]byte
+	profile   string
+}
+
+// NewAuthCipher loads the key-encryption keys: the primary key from AUTH_ENCRYPTION_KEY, the key file
+// or, on Linux, the keyring file (created on first start), and rotated-out keys from
+// AUTH_ENCRYPTION_PREVIOUS_KEYS and the previous key files
+// It returns nil when encryption is disabled, and fails when it is enabled outside Linux with no key configured
+func NewAuthCipher(config *common.EncryptionConfig, logger ILogger) (*AuthCipher, error) {
+	if !config.Enabled {
+		logger.Warn().Msg("Auth encryption disabled, credentials are stored as plain JSON")
+		return nil, nil
+	}
+
+	primary, source, err := loadPrimaryKey(config)
+	if err != nil {
+		return nil, err
+	}
+	if primary == nil {
+		return nil, fmt.Errorf("auth encryption is enabled but no key is configured on %s: set %s or key_file under [storage.encryption], or set enabled = false to store credentials as plain JSON", runtime.GOOS, authKeyEnv)
+	}
+
+	c := &AuthCipher{
+		primaryID: authKeyID(primary),
+		keys:      map[string][]byte{},
+	}
+	c.keys[c.primaryID] = primary
+
+	previous := splitAuthKeys(os.Getenv(authPreviousKeysEnv))
+	for _, path := range config.PreviousKeyFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read previous key file %s: %w", path, err)
+		}
+		previous = append(previous, splitAuthKeys(string(data))...)
+	}
+	for _, encoded := range previous {
+		key, err := decodeAuthKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid previous auth encryption key: %w", err)
+		}
+		c.keys[authKeyID(key)] = key
+	}
+
+	logger.Info().Str("source", source).Str("keyId", c.primaryID).Int("previousKeys", len(c.keys)-1).Msg("Auth encryption enabled")
+	return c, nil
+}
+
+// splitAuthKeys splits a list of base64 keys separated by commas or newlines, skipping empty entries
+// such as a trailing comma or a blank line
+func splitAuthKeys(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// ForProfile returns the cipher for the records of one site profile, sharing the keys of c
+// Records are bound to the profile they were sealed for; nil stays nil
+func (c *AuthCipher) ForProfile(name string) *AuthCipher {
+	if c == nil {
+		return nil
+	}
+	profileCipher := *c
+	profileCipher.profile = name
+	return &profileCipher
+}
+
+// loadPrimaryKey returns the primary key and where it came from, or nil when none is configured
+// outside Linux, which has no keyring file to fall back to
+func loadPrimaryKey(config *common.EncryptionConfig) ([]byte, string, error) {
+	if value := os.Getenv(authKeyEnv); value != "" {
+		key, err := decodeAuthKey(value)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid %s: %w", authKeyEnv, err)
+		}
+		return key, authKeyEnv, nil
+	}
+
+	if config.KeyFile != "" {
+		data, err := os.ReadFile(config.KeyFile)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read auth key file: %w", err)
+		}
+		key, err := decodeAuthKey(string(data))
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid auth key file %s: %w", config.KeyFile, err)
+		}
+		return key, config.KeyFile, nil
+	}
+
+	if runtime.GOOS != "linux" {
+		return nil, "", nil
+	}
+
+	path := config.KeyringFile
+	if path == "" {
+		path = defaultKeyringFile()
+	}
+	key, err := loadOrCreateKeyFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return key, path, nil
+}
+
+// defaultKeyringFile is the key file in the user's keyrings directory, $XDG_DATA_HOME/keyrings
+func defaultKeyringFile() string {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, _ := os.UserHomeDir()
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "keyrings", "aktis-parser.key")
+}
+
+// loadOrCreateKeyFile reads a key file, generating a new key readable only by the owner when it does not exist
+func loadOrCreateKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key, err := decodeAuthKey(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid auth keyring file %s: %w", path, err)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read auth keyring file: %w", err)
+	}
+
+	key := make([]byte, authKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create auth keyring directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write auth keyring file: %w", err)
+	}
+	return key, nil
+}
+
+// decodeAuthKey decodes a base64 AES-256 key
+func decodeAuthKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("key is not base64: %w", err)
+	}
+	if len(key) != authKeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", authKeySize, len(key))
+	}
+	return key, nil
+}
+
+// authKeyID names a key by a hash prefix, so records can say which key wrapped them without revealing it
+func authKeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Seal encrypts the record stored under key with a fresh data key wrapped with the primary key
+func (c *AuthCipher) Seal(key string, plaintext []byte) ([]byte, error) {
+	dataKey := make([]byte, authKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := gcmSeal(dataKey, plaintext, c.recordAAD(key))
+	if err != nil {
+		return nil, err
+	}
+	wrappedKey, err := gcmSeal(c.keys[c.primaryID], dataKey, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(authEnvelope{
+		Envelope:   authEnvelopeBound,
+		KeyID:      c.primaryID,
+		WrappedKey: base64.StdEncoding.EncodeToString(wrappedKey),
+		Ciphertext: base64.StdEncoding.EncodeToString(ciphertext),
+	})
+}
+
+// Open decrypts the record stored under key
+func (c *AuthCipher) Open(key string, data []byte) ([]byte, error) {
+	envelope, ok := parseAuthEnvelope(data)
+	if !ok {
+		return nil, fmt.Errorf("auth record is not encrypted")
+	}
+	dataKey, err := c.unwrap(envelope)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(envelope.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth record ciphertext: %w", err)
+	}
+
+	switch envelope.Envelope {
+	case authEnvelopeUnbound:
+		return gcmOpen(dataKey, ciphertext, nil)
+	case authEnvelopeBound:
+		return gcmOpen(dataKey, ciphertext, c.recordAAD(key))
+	}
+	return nil, fmt.Errorf("unsupported auth record envelope %d", envelope.Envelope)
+}
+
+// recordAAD is the GCM additional data of the record stored under key in the auth bucket of the profile
+func (c *AuthCipher) recordAAD(key string) []byte {
+	return []byte("auth/" + c.profile + "/" + key)
+}
+
+// rewrap re-seals the data key of a record with the primary key, leaving the record ciphertext as is
+func (c *AuthCipher) rewrap(envelope *authEnvelope) ([]byte, error) {
+	dataKey, err := c.unwrap(envelope)
+	if err != nil {
+		return nil, err
+	}
+	wrappedKey, err := gcmSeal(c.keys[c.primaryID], dataKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	envelope.KeyID = c.primaryID
+	envelope.WrappedKey = base64.StdEncoding.EncodeToString(wrappedKey)
+	return json.Marshal(envelope)
+}
+
+// unwrap decrypts the data key of a record with the key it names
+func (c *AuthCipher) unwrap(envelope *authEnvelope) ([]byte, error) {
+	key, ok := c.keys[envelope.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("auth record is encrypted with unknown key %s", envelope.KeyID)
+	}
+	wrappedKey, err := base64.StdEncoding.DecodeString(envelope.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth record key: %w", err)
+	}
+	return gcmOpen(key, wrappedKey, nil)
+}
+
+// parseAuthEnvelope reports whether a stored record is encrypted, returning its envelope
+func parseAuthEnvelope(data []byte) (*authEnvelope, bool) {
+	var envelope authEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil || envelope.Envelope == 0 || envelope.KeyID == "" {
+		return nil, false
+	}
+	return &envelope, true
+}
+
+// gcmSeal encrypts with AES-GCM, prefixing the random nonce to the ciphertext
+// additionalData is authenticated but not encrypted, and must be passed to gcmOpen again
+func gcmSeal(key, plaintext, additionalData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// gcmOpen decrypts what gcmSeal produced
+func gcmOpen(key, sealed, additionalData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("auth record ciphertext too short")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt auth record: %w", err)
+	}
+	return plaintext, nil
+}
+
+// putAuthRecord stores a record in the auth bucket, encrypted when a cipher is configured
+func putAuthRecord(tx *bolt.Tx, authCipher *AuthCipher, key string, value []byte) error {
+	bucket := tx.Bucket([]byte("auth"))
+	if bucket == nil {
+		return fmt.Errorf("auth bucket not found")
+	}
+	if authCipher != nil {
+		sealed, err := authCipher.Seal(key, value)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt auth record: %w", err)
+		}
+		value = sealed
+	}
+	return bucket.Put([]byte(key), value)
+}
+
+// getAuthRecord reads a record from the auth bucket, decrypting it when needed; nil when missing
+// Encrypted records cannot be read without a cipher
+func getAuthRecord(tx *bolt.Tx, authCipher *AuthCipher, key string) ([]byte, error) {
+	bucket := tx.Bucket([]byte("auth"))
+	if bucket == nil {
+		return nil, fmt.Errorf("auth bucket not found")
+	}
+	data := bucket.Get([]byte(key))
+	if data == nil {
+		return nil, nil
+	}
+	if _, encrypted := parseAuthEnvelope(data); !encrypted {
+		return data, nil
+	}
+	if authCipher == nil {
+		return nil, fmt.Errorf("auth record %s is encrypted but no encryption key is configured", key)
+	}
+	return authCipher.Open(key, data)
+}
+
+// MigrateAuthRecords brings every record of the auth bucket under the primary key: plain JSON records
+// are encrypted, version 1 records are re-sealed bound to their key and records wrapped with a
+// previous key are re-wrapped
+// Records encrypted with an unknown key are left alone and reported
+func MigrateAuthRecords(db *bolt.DB, authCipher *AuthCipher, logger ILogger) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte("auth"))
+		if err != nil {
+			return fmt.Errorf("failed to create auth bucket: %w", err)
+		}
+		if authCipher == nil {
+			return nil
+		}
+
+		// Collect first, bolt does not allow writes while iterating
+		updates := map[string][]byte{}
+		encrypted, resealed, rewrapped := 0, 0, 0
+		err = bucket.ForEach(func(k, v []byte) error {
+			envelope, ok := parseAuthEnvelope(v)
+			if !ok {
+				sealed, err := authCipher.Seal(string(k), v)
+				if err != nil {
+					return err
+				}
+				updates[string(k)] = sealed
+				encrypted++
+				return nil
+			}
+			if envelope.Envelope == authEnvelopeUnbound {
+				plaintext, err := authCipher.Open(string(k), v)
+				if err != nil {
+					logger.Warn().Err(err).Str("record", string(k)).Msg("Cannot re-seal auth record, it stays unreadable until its key is configured")
+					return nil
+				}
+				sealed, err := authCipher.Seal(string(k), plaintext)
+				if err != nil {
+					return err
+				}
+				updates[string(k)] = sealed
+				resealed++
+				return nil
+			}
+			if envelope.KeyID == authCipher.primaryID {
+				return nil
+			}
+			sealed, err := authCipher.rewrap(envelope)
+			if err != nil {
+				logger.Warn().Err(err).Str("record", string(k)).Msg("Cannot re-wrap auth record, it stays unreadable until its key is configured")
+				return nil
+			}
+			updates[string(k)] = sealed
+			rewrapped++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for key, value := range updates {
+			if err := bucket.Put([]byte(key), value); err != nil {
+				return err
+			}
+		}
+		if len(updates) > 0 {
+			logger.Info().Int("encrypted", encrypted).Int("resealed", resealed).Int("rewrapped", rewrapped).Msg("Migrated auth records to the current encryption key")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"aktis-parser/internal/common"
+	bolt "go.etcd.io/bbolt"
+)
+
+// newTestCipher returns a cipher with a random primary key and the given previous ciphers' keys
+func newTestCipher(t *testing.T, previous ...*AuthCipher) *AuthCipher {
+	t.Helper()
+	key := make([]byte, authKeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	c := &AuthCipher{primaryID: authKeyID(key), keys: map[string][]byte{}}
+	c.keys[c.primaryID] = key
+	for _, p := range previous {
+		c.keys[p.primaryID] = p.keys[p.primaryID]
+	}
+	return c
+}
+
+// sealUnbound seals a record the way version 1 envelopes were, without additional data
+func sealUnbound(t *testing.T, c *AuthCipher, plaintext []byte) []byte {
+	t.Helper()
+	dataKey := make([]byte, authKeySize)
+	rand.Read(dataKey)
+	ciphertext, err := gcmSeal(dataKey, plaintext, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrappedKey, err := gcmSeal(c.keys[c.primaryID], dataKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(authEnvelope{
+		Envelope:   authEnvelopeUnbound,
+		KeyID:      c.primaryID,
+		WrappedKey: base64.StdEncoding.EncodeToString(wrappedKey),
+		Ciphertext: base64.StdEncoding.EncodeToString(ciphertext),
+	})
+	return data
+}
+
+func TestAuthCipherSealOpen(t *testing.T) {
+	c := newTestCipher(t)
+	plaintext := []byte(`{"baseUrl":"https://example.atlassian.net"}`)
+
+	sealed, err := c.Seal("current", plaintext)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if bytes.Contains(sealed, []byte("example.atlassian.net")) {
+		t.Fatal("sealed record contains the plaintext")
+	}
+
+	opened, err := c.Open("current", sealed)
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Fatalf("Open = %q, %v", opened, err)
+	}
+
+	// The record is bound to its key, so a copy under another key does not open
+	if _, err := c.Open("oauth", sealed); err == nil {
+		t.Error("record opened under another key")
+	}
+	// Nor does a copy in another profile's database
+	staging := c.ForProfile("staging")
+	if _, err := staging.Open("current", sealed); err == nil {
+		t.Error("record opened in another profile")
+	}
+	stagingSealed, err := staging.Seal("current", plaintext)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if _, err := c.ForProfile("default").Open("current", stagingSealed); err == nil {
+		t.Error("staging record opened in the default profile")
+	}
+	// Nor does it open with an unrelated key
+	if _, err := newTestCipher(t).Open("current", sealed); err == nil {
+		t.Error("record opened without its key")
+	}
+	if _, err := c.Open("current", plaintext); err == nil {
+		t.Error("plain JSON opened as a sealed record")
+	}
+}
+
+func TestNewAuthCipherSkipsEmptyPreviousKeys(t *testing.T) {
+	newKey := func() string {
+		key := make([]byte, authKeySize)
+		rand.Read(key)
+		return base64.StdEncoding.EncodeToString(key)
+	}
+	fromEnv, fromFile, alsoFromFile := newKey(), newKey(), newKey()
+
+	keyFile := filepath.Join(t.TempDir(), "auth.key.old")
+	if err := os.WriteFile(keyFile, []byte(fromFile+"\n\n  "+alsoFromFile+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(authKeyEnv, newKey())
+	t.Setenv(authPreviousKeysEnv, fromEnv+", ,")
+
+	c, err := NewAuthCipher(&common.EncryptionConfig{Enabled: true, PreviousKeyFiles: []string{keyFile}}, common.GetLogger())
+	if err != nil {
+		t.Fatalf("NewAuthCipher: %v", err)
+	}
+	if len(c.keys) != 4 {
+		t.Errorf("loaded %d keys, want the primary and 3 previous", len(c.keys))
+	}
+	for _, encoded := range []string{fromEnv, fromFile, alsoFromFile} {
+		key, _ := decodeAuthKey(encoded)
+		if _, ok := c.keys[authKeyID(key)]; !ok {
+			t.Errorf("previous key %s not loaded", authKeyID(key))
+		}
+	}
+}
+
+func TestAuthCipherOpensUnboundRecords(t *testing.T) {
+	c := newTestCipher(t)
+	sealed := sealUnbound(t, c, []byte("secret"))
+
+	opened, err := c.Open("current", sealed)
+	if err != nil || string(opened) != "secret" {
+		t.Fatalf("Open = %q, %v", opened, err)
+	}
+}
+
+func TestMigrateAuthRecords(t *testing.T) {
+	db := openTestDB(t)
+	old := newTestCipher(t)
+	unknown := newTestCipher(t)
+	current := newTestCipher(t, old)
+
+	records := map[string][]byte{}
+	seal := func(c *AuthCipher, key, value string) []byte {
+		sealed, err := c.Seal(key, []byte(value))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sealed
+	}
+	records["plain"] = []byte(`{"plain":true}`)
+	records["unbound"] = sealUnbound(t, current, []byte("unbound"))
+	records["rotated"] = seal(old, "rotated", "rotated")
+	records["unknown"] = seal(unknown, "unknown", "unknown")
+	records["current"] = seal(current, "current", "current")
+
+	db.Update(func(tx *bolt.Tx) error {
+		bucket, _ := tx.CreateBucketIfNotExists([]byte("auth"))
+		for key, value := range records {
+			bucket.Put([]byte(key), value)
+		}
+		return nil
+	})
+
+	if err := MigrateAuthRecords(db, current, common.GetLogger()); err != nil {
+		t.Fatalf("MigrateAuthRecords: %v", err)
+	}
+
+	want := map[string]string{
+		"plain":   `{"plain":true}`,
+		"unbound": "unbound",
+		"rotated": "rotated",
+		"current": "current",
+	}
+	db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("auth"))
+		for key, value := range want {
+			stored := bucket.Get([]byte(key))
+			envelope, ok := parseAuthEnvelope(stored)
+			if !ok || envelope.Envelope != authEnvelopeBound || envelope.KeyID != current.primaryID {
+				t.Errorf("%s: envelope = %+v, want version %d under the primary key", key, envelope, authEnvelopeBound)
+				continue
+			}
+			if opened, err := getAuthRecord(tx, current, key); err != nil || string(opened) != value {
+				t.Errorf("%s: getAuthRecord = %q, %v; want %q", key, opened, err, value)
+			}
+		}
+
+		// A record under a key that is not configured is left as it was
+		if stored := bucket.Get([]byte("unknown")); !bytes.Equal(stored, records["unknown"]) {
+			t.Error("record under an unknown key was changed")
+		}
+		if _, err := getAuthRecord(tx, current, "unknown"); err == nil {
+			t.Error("record under an unknown key opened")
+		}
+		if _, err := getAuthRecord(tx, nil, "current"); err == nil {
+			t.Error("encrypted record read without a cipher")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,371 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"aktis-parser/internal/interfaces"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Sources of auth history entries
+const (
+	authSourceCapture  = "capture"
+	authSourceRotation = "rotation"
+	authSourceRestore  = "restore"
+	authSourceStored   = "stored"
+)
+
+const (
+	// authHistoryPrefix keys the history in the auth bucket, so it is encrypted and migrated like the rest
+	authHistoryPrefix = "history/"
+	// authHistoryLimit is how many sessions the history keeps, the oldest are dropped first
+	authHistoryLimit = 20
+	// authPersistDelay batches writing rotated cookies back, since a scrape sees many responses in a row
+	authPersistDelay = 10 * time.Second
+)
+
+// rotationJar is a session's cookie jar, reporting the cookies each response sets
+type rotationJar struct {
+	http.CookieJar
+	onSet func(u *url.URL, cookies []*http.Cookie)
+}
+
+// SetCookies stores the cookies and reports them
+func (j *rotationJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.CookieJar.SetCookies(u, cookies)
+	if len(cookies) > 0 {
+		j.onSet(u, cookies)
+	}
+}
+
+// authHistoryRecord is a stored auth history entry
+type authHistoryRecord struct {
+	Source      string               `json:"source"`
+	Auth        *interfaces.AuthData `json:"auth"`
+	ValidatedAt *time.Time           `json:"validatedAt,omitempty"`
+	CreatedAt   time.Time            `json:"createdAt"`
+}
+
+// cookiesRotated returns the jar callback of a session, which merges changed cookies into it and
+// schedules writing it back; callbacks from the jar of a replaced session are ignored
+func (s *AtlassianAuthService) cookiesRotated(session *interfaces.AuthData) func(*url.URL, []*http.Cookie) {
+	return func(u *url.URL, cookies []*http.Cookie) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.session != session {
+			return
+		}
+
+		changed, sessionChanged := mergeSetCookies(session, u, cookies, time.Now())
+		if changed == 0 {
+			return
+		}
+		if sessionChanged {
+			s.sessionRotated = true
+		}
+
+		// A pending write is kept rather than re-armed, so a scrape rotating cookies on every response
+		// still has them written back within authPersistDelay
+		if s.persistTimer == nil {
+			s.persistTimer = time.AfterFunc(authPersistDelay, func() {
+				s.persistSession(session)
+			})
+		}
+	}
+}
+
+// persistSession writes a session's rotated cookies back as the current auth, adding a history entry
+// when the session cookies themselves changed
+// The lock is held throughout so a newer capture cannot be overwritten by this older session
+func (s *AtlassianAuthService) persistSession(session *interfaces.AuthData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.session != session {
+		return
+	}
+	s.persistTimer = nil
+
+	authJSON, err := json.Marshal(session)
+	if err != nil {
+		s.log.Warn().Err(err).Msg("Failed to encode rotated cookies")
+		return
+	}
+
+	rotated := s.sessionRotated
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		if err := putAuthRecord(tx, s.cipher, "current", authJSON); err != nil {
+			return err
+		}
+		if !rotated {
+			return nil
+		}
+		return s.putHistory(tx, cloneAuthData(session), authSourceRotation)
+	})
+	if err != nil {
+		s.log.Warn().Err(err).Msg("Failed to persist rotated cookies")
+		return
+	}
+	s.sessionRotated = false
+
+	if rotated {
+		s.log.Info().Str("baseURL", session.BaseURL).Msg("Persisted rotated session cookies")
+	} else {
+		s.log.Debug().Str("baseURL", session.BaseURL).Msg("Persisted updated cookies")
+	}
+}
+
+// mergeSetCookies applies cookies set by a response for u to the session, returning how many changed
+// and whether any of them was a session cookie
+// Cookies are matched by name, domain and path; expired ones and Max-Age < 0 remove the cookie
+func mergeSetCookies(session *interfaces.AuthData, u *url.URL, cookies []*http.Cookie, now time.Time) (int, bool) {
+	changed, sessionChanged := 0, false
+	for _, cookie := range cookies {
+		domain := cookie.Domain
+		if domain == "" {
+			domain = u.Hostname()
+		}
+		path := cookie.Path
+		if path == "" {
+			path = "/"
+		}
+
+		var expires int64
+		deleted := false
+		switch {
+		case cookie.MaxAge < 0:
+			deleted = true
+		case cookie.MaxAge > 0:
+			expires = now.Add(time.Duration(cookie.MaxAge) * time.Second).Unix()
+		case !cookie.Expires.IsZero():
+			deleted = !cookie.Expires.After(now)
+			expires = cookie.Expires.Unix()
+		}
+
+		index := -1
+		for i, existing := range session.Cookies {
+			if existing.Name == cookie.Name && sameCookieDomain(existing.Domain, domain) && sameCookiePath(existing.Path, path) {
+				index = i
+				break
+			}
+		}
+
+		switch {
+		case deleted && index < 0:
+			continue
+		case deleted:
+			session.Cookies = append(session.Cookies[:index], session.Cookies[index+1:]...)
+		case index >= 0:
+			existing := session.Cookies[index]
+			if existing.Value == cookie.Value && existing.Expires == expires {
+				continue
+			}
+			existing.Value = cookie.Value
+			existing.Expires = expires
+		default:
+			session.Cookies = append(session.Cookies, &interfaces.ExtensionCookie{
+				Name:     cookie.Name,
+				Value:    cookie.Value,
+				Domain:   domain,
+				Path:     path,
+				Expires:  expires,
+				Secure:   cookie.Secure,
+				HTTPOnly: cookie.HttpOnly,
+				SameSite: sameSiteName(cookie.SameSite),
+			})
+		}
+
+		changed++
+		if interfaces.IsSessionCookie(cookie.Name) {
+			sessionChanged = true
+		}
+	}
+	return changed, sessionChanged
+}
+
+// sameCookieDomain compares cookie domains, ignoring case and the leading dot of domain cookies
+func sameCookieDomain(a, b string) bool {
+	return strings.EqualFold(strings.TrimPrefix(a, "."), strings.TrimPrefix(b, "."))
+}
+
+// sameCookiePath compares cookie paths, an empty path being the root
+func sameCookiePath(a, b string) bool {
+	if a == "" {
+		a = "/"
+	}
+	if b == "" {
+		b = "/"
+	}
+	return a == b
+}
+
+// sameSiteName converts a SameSite mode to the string the extension sends
+func sameSiteName(mode http.SameSite) string {
+	switch mode {
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteLaxMode:
+		return "Lax"
+	case http.SameSiteNoneMode:
+		return "None"
+	}
+	return ""
+}
+
+// cloneAuthData copies auth data down to its cookies, which rotations change in place
+func cloneAuthData(authData *interfaces.AuthData) *interfaces.AuthData {
+	clone := *authData
+	clone.Cookies = make([]*interfaces.ExtensionCookie, len(authData.Cookies))
+	for i, cookie := range authData.Cookies {
+		copied := *cookie
+		clone.Cookies[i] = &copied
+	}
+	return &clone
+}
+
+// putHistory adds auth to the history, dropping the oldest entries beyond the limit
+func (s *AtlassianAuthService) putHistory(tx *bolt.Tx, authData *interfaces.AuthData, source string) error {
+	now := time.Now()
+	record, err := json.Marshal(&authHistoryRecord{
+		Source:    source,
+		Auth:      authData,
+		CreatedAt: now,
+	})
+	if err != nil {
+		return err
+	}
+	if err := putAuthRecord(tx, s.cipher, authHistoryPrefix+fmt.Sprintf("%020d", now.UnixNano()), record); err != nil {
+		return err
+	}
+
+	keys := authHistoryKeys(tx)
+	bucket := tx.Bucket([]byte("auth"))
+	for len(keys) > authHistoryLimit {
+		if err := bucket.Delete([]byte(keys[0])); err != nil {
+			return err
+		}
+		keys = keys[1:]
+	}
+	return nil
+}
+
+// authHistoryKeys returns the keys of the auth history, oldest first
+func authHistoryKeys(tx *bolt.Tx) []string {
+	bucket := tx.Bucket([]byte("auth"))
+	if bucket == nil {
+		return nil
+	}
+
+	var keys []string
+	prefix := []byte(authHistoryPrefix)
+	cursor := bucket.Cursor()
+	for k, _ := cursor.Seek(prefix); k != nil && strings.HasPrefix(string(k), authHistoryPrefix); k, _ = cursor.Next() {
+		keys = append(keys, string(k))
+	}
+	return keys
+}
+
+// getHistory reads an auth history record, nil when it does not exist
+func (s *AtlassianAuthService) getHistory(tx *bolt.Tx, key string) (*authHistoryRecord, error) {
+	data, err := getAuthRecord(tx, s.cipher, key)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var record authHistoryRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to parse auth history entry %s: %w", key, err)
+	}
+	if record.Auth == nil {
+		return nil, fmt.Errorf("auth history entry %s has no auth", key)
+	}
+	return &record, nil
+}
+
+// seedHistory records stored auth as the first history entry when the history is empty
+func (s *AtlassianAuthService) seedHistory(authData *interfaces.AuthData) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if len(authHistoryKeys(tx)) > 0 {
+			return nil
+		}
+		return s.putHistory(tx, authData, authSourceStored)
+	})
+}
+
+// AuthHistory lists the sessions in the auth history, newest first, without their cookies
+func (s *AtlassianAuthService) AuthHistory() ([]*interfaces.AuthHistoryEntry, error) {
+	entries := []*interfaces.AuthHistoryEntry{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		keys := authHistoryKeys(tx)
+		for i := len(keys) - 1; i >= 0; i-- {
+			record, err := s.getHistory(tx, keys[i])
+			if err != nil {
+				s.log.Warn().Err(err).Str("record", keys[i]).Msg("Skipping unreadable auth history entry")
+				continue
+			}
+
+			entry := &interfaces.AuthHistoryEntry{
+				ID:          strings.TrimPrefix(keys[i], authHistoryPrefix),
+				Source:      record.Source,
+				BaseURL:     record.Auth.BaseURL,
+				Cookies:     len(record.Auth.Cookies),
+				ValidatedAt: record.ValidatedAt,
+				CreatedAt:   record.CreatedAt,
+			}
+			if expiresAt, _ := record.Auth.SessionCookieExpiry(); !expiresAt.IsZero() {
+				entry.SessionExpiresAt = &expiresAt
+			}
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// RestoreAuth makes an auth history entry the current auth again, recording the restore in the history
+func (s *AtlassianAuthService) RestoreAuth(id string) (*interfaces.AuthData, error) {
+	var record *authHistoryRecord
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		record, err = s.getHistory(tx, authHistoryPrefix+id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, interfaces.ErrAuthHistoryNotFound
+	}
+
+	if err := s.setAuth(record.Auth, authSourceRestore); err != nil {
+		return nil, err
+	}
+	s.log.Info().Str("id", id).Str("source", record.Source).Str("baseURL", record.Auth.BaseURL).Msg("Restored authentication from auth history")
+	return record.Auth, nil
+}
+
+// MarkSessionValid records that the newest history entry, the session in use, passed validation
+func (s *AtlassianAuthService) MarkSessionValid(at time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		keys := authHistoryKeys(tx)
+		if len(keys) == 0 {
+			return nil
+		}
+		key := keys[len(keys)-1]
+
+		record, err := s.getHistory(tx, key)
+		if err != nil || record == nil {
+			return err
+		}
+		record.ValidatedAt = &at
+
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		return putAuthRecord(tx, s.cipher, key, data)
+	})
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"aktis-parser/internal/common"
+	"aktis-parser/internal/interfaces"
+)
+
+func TestMergeSetCookies(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	site, _ := url.Parse("https://example.atlassian.net/rest/api/3/myself")
+
+	existing := func() *interfaces.AuthData {
+		return &interfaces.AuthData{Cookies: []*interfaces.ExtensionCookie{
+			{Name: "tenant.session.token", Value: "old", Domain: ".example.atlassian.net", Path: "/"},
+			{Name: "atlassian.xsrf.token", Value: "xsrf", Domain: "example.atlassian.net", Path: "/"},
+		}}
+	}
+
+	tests := []struct {
+		name           string
+		cookies        []*http.Cookie
+		wantChanged    int
+		wantSession    bool
+		wantCookies    map[string]string
+		wantNewExpires int64
+	}{
+		{
+			name:        "domain cookie matches without its leading dot",
+			cookies:     []*http.Cookie{{Name: "tenant.session.token", Value: "new", Domain: "example.atlassian.net", MaxAge: 60}},
+			wantChanged: 1,
+			wantSession: true,
+			wantCookies: map[string]string{"tenant.session.token": "new", "atlassian.xsrf.token": "xsrf"},
+		},
+		{
+			name:        "host-only cookie matches the request host",
+			cookies:     []*http.Cookie{{Name: "atlassian.xsrf.token", Value: "xsrf2"}},
+			wantChanged: 1,
+			wantCookies: map[string]string{"tenant.session.token": "old", "atlassian.xsrf.token": "xsrf2"},
+		},
+		{
+			name:        "negative Max-Age deletes",
+			cookies:     []*http.Cookie{{Name: "tenant.session.token", Domain: ".example.atlassian.net", MaxAge: -1}},
+			wantChanged: 1,
+			wantSession: true,
+			wantCookies: map[string]string{"atlassian.xsrf.token": "xsrf"},
+		},
+		{
+			name:        "past Expires deletes",
+			cookies:     []*http.Cookie{{Name: "atlassian.xsrf.token", Expires: now.Add(-time.Hour)}},
+			wantChanged: 1,
+			wantCookies: map[string]string{"tenant.session.token": "old"},
+		},
+		{
+			name:        "deleting an unknown cookie changes nothing",
+			cookies:     []*http.Cookie{{Name: "other", MaxAge: -1}},
+			wantChanged: 0,
+			wantCookies: map[string]string{"tenant.session.token": "old", "atlassian.xsrf.token": "xsrf"},
+		},
+		{
+			name:        "same value and expiry changes nothing",
+			cookies:     []*http.Cookie{{Name: "atlassian.xsrf.token", Value: "xsrf"}},
+			wantChanged: 0,
+			wantCookies: map[string]string{"tenant.session.token": "old", "atlassian.xsrf.token": "xsrf"},
+		},
+		{
+			name:        "another path is another cookie",
+			cookies:     []*http.Cookie{{Name: "atlassian.xsrf.token", Value: "wiki", Path: "/wiki"}},
+			wantChanged: 1,
+			wantCookies: map[string]string{"tenant.session.token": "old", "atlassian.xsrf.token": "xsrf"},
+		},
+		{
+			name:           "new cookie is added with its expiry",
+			cookies:        []*http.Cookie{{Name: "ajs_anonymous_id", Value: "a", Expires: now.Add(time.Hour)}},
+			wantChanged:    1,
+			wantCookies:    map[string]string{"tenant.session.token": "old", "atlassian.xsrf.token": "xsrf", "ajs_anonymous_id": "a"},
+			wantNewExpires: now.Add(time.Hour).Unix(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := existing()
+			changed, sessionChanged := mergeSetCookies(session, site, tt.cookies, now)
+			if changed != tt.wantChanged || sessionChanged != tt.wantSession {
+				t.Errorf("mergeSetCookies() = %d, %v; want %d, %v", changed, sessionChanged, tt.wantChanged, tt.wantSession)
+			}
+
+			got := map[string]string{}
+			for _, cookie := range session.Cookies {
+				if cookie.Path == "/" {
+					got[cookie.Name] = cookie.Value
+				}
+				if cookie.Name == "ajs_anonymous_id" && cookie.Expires != tt.wantNewExpires {
+					t.Errorf("new cookie expires %d, want %d", cookie.Expires, tt.wantNewExpires)
+				}
+			}
+			if len(got) != len(tt.wantCookies) {
+				t.Errorf("cookies = %v, want %v", got, tt.wantCookies)
+			}
+			for name, value := range tt.wantCookies {
+				if got[name] != value {
+					t.Errorf("cookie %s = %q, want %q", name, got[name], value)
+				}
+			}
+		})
+	}
+}
+
+// newTestAuthService returns an extension auth service on a fresh database
+func newTestAuthService(t *testing.T) *AtlassianAuthService {
+	t.Helper()
+	service, err := NewAtlassianAuthService(openTestDB(t), newTestCipher(t), common.GetLogger())
+	if err != nil {
+		t.Fatalf("NewAtlassianAuthService: %v", err)
+	}
+	return service
+}
+
+// capturedAuth is auth as the extension sends it for a site
+func capturedAuth(baseURL, session string) *interfaces.AuthData {
+	return &interfaces.AuthData{
+		BaseURL: baseURL,
+		Cookies: []*interfaces.ExtensionCookie{{Name: "tenant.session.token", Value: session, Domain: "example.atlassian.net", Path: "/"}},
+		Tokens:  map[string]interface{}{"cloudId": "cloud", "atlToken": "token"},
+	}
+}
+
+func TestAuthHistoryLimit(t *testing.T) {
+	service := newTestAuthService(t)
+	for i := 0; i < authHistoryLimit+5; i++ {
+		if err := service.UpdateAuth(capturedAuth("https://example.atlassian.net", "s")); err != nil {
+			t.Fatalf("UpdateAuth: %v", err)
+		}
+	}
+
+	entries, err := service.AuthHistory()
+	if err != nil {
+		t.Fatalf("AuthHistory: %v", err)
+	}
+	if len(entries) != authHistoryLimit {
+		t.Fatalf("history has %d entries, want %d", len(entries), authHistoryLimit)
+	}
+	for i := 1; i < len(entries); i++ {
+		if entries[i].ID >= entries[i-1].ID {
+			t.Fatalf("history is not newest first: %s before %s", entries[i-1].ID, entries[i].ID)
+		}
+	}
+}
+
+func TestRestoreAuth(t *testing.T) {
+	service := newTestAuthService(t)
+	if err := service.UpdateAuth(capturedAuth("https://first.atlassian.net", "first")); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.UpdateAuth(capturedAuth("https://second.atlassian.net", "second")); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _ := service.AuthHistory()
+	if len(entries) != 2 || entries[1].BaseURL != "https://first.atlassian.net" {
+		t.Fatalf("history = %+v", entries)
+	}
+
+	if _, err := service.RestoreAuth(entries[1].ID); err != nil {
+		t.Fatalf("RestoreAuth: %v", err)
+	}
+	if got := service.GetBaseURL(); got != "https://first.atlassian.net" {
+		t.Errorf("applied base URL = %s after restore", got)
+	}
+	current, err := service.LoadAuth()
+	if err != nil || current.BaseURL != "https://first.atlassian.net" || current.Cookies[0].Value != "first" {
+		t.Errorf("stored auth after restore = %+v, %v", current, err)
+	}
+
+	entries, _ = service.AuthHistory()
+	if len(entries) != 3 || entries[0].Source != authSourceRestore {
+		t.Errorf("history after restore = %+v, want a restore entry first", entries)
+	}
+
+	if _, err := service.RestoreAuth("00000000000000000000"); !errors.Is(err, interfaces.ErrAuthHistoryNotFound) {
+		t.Errorf("RestoreAuth(unknown) = %v, want ErrAuthHistoryNotFound", err)
+	}
+}
+
+func TestRotatedSessionIsPersisted(t *testing.T) {
+	rotations := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rotations++
+		http.SetCookie(w, &http.Cookie{Name: "tenant.session.token", Value: fmt.Sprintf("rotated-%d", rotations), Path: "/", MaxAge: 3600})
+	}))
+	t.Cleanup(server.Close)
+
+	service := newTestAuthService(t)
+	auth := capturedAuth(server.URL, "captured")
+	auth.Cookies[0].Domain = "127.0.0.1"
+	if err := service.UpdateAuth(auth); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every response rotates the cookie, and the first pending write is kept rather than pushed back
+	var pending *time.Timer
+	for i := 0; i < 3; i++ {
+		resp, err := service.GetHTTPClient().Get(server.URL)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		resp.Body.Close()
+
+		service.mu.Lock()
+		timer := service.persistTimer
+		service.mu.Unlock()
+		if timer == nil || (pending != nil && timer != pending) {
+			t.Fatalf("response %d: write-back timer %p, want the pending %p", i, timer, pending)
+		}
+		pending = timer
+	}
+
+	// Write the rotation back now rather than after the delay
+	service.mu.Lock()
+	session := service.session
+	pending.Stop()
+	service.mu.Unlock()
+	service.persistSession(session)
+
+	service.mu.Lock()
+	if service.persistTimer != nil {
+		t.Error("write-back timer still pending after the write")
+	}
+	service.mu.Unlock()
+
+	stored, err := service.LoadAuth()
+	if err != nil || len(stored.Cookies) != 1 || stored.Cookies[0].Value != "rotated-3" {
+		t.Fatalf("stored auth = %+v, %v; want the rotated session cookie", stored, err)
+	}
+	entries, _ := service.AuthHistory()
+	if len(entries) != 2 || entries[0].Source != authSourceRotation {
+		t.Errorf("history = %+v, want a rotation entry first", entries)
+	}
+	// The captured auth passed in is never changed underneath its caller
+	if auth.Cookies[0].Value != "captured" {
+		t.Errorf("caller's auth changed to %q", auth.Cookies[0].Value)
+	}
+}
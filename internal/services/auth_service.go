@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
+	"sync"
 	"time"
 
 	"aktis-parser/internal/interfaces"
@@ -15,17 +16,28 @@ import (
 
 // AtlassianAuthService implements the AuthService interface
 type AtlassianAuthService struct {
+	db     *bolt.DB
+	cipher *AuthCipher
+	log    ILogger
+
+	// mu guards the applied auth, which a capture or restore replaces while scrapes read it
+	mu        sync.Mutex
 	client    *http.Client
 	baseURL   string
 	userAgent string
 	cloudId   string
 	atlToken  string
-	db        *bolt.DB
-	log       ILogger
+
+	// session is the auth in use; cookies rotated by responses are merged into it and written back
+	// once persistTimer fires, so a restart does not fall back to the cookies first captured
+	session        *interfaces.AuthData
+	persistTimer   *time.Timer
+	sessionRotated bool
 }
 
 // NewAtlassianAuthService creates a new authentication service
-func NewAtlassianAuthService(db *bolt.DB, logger ILogger) (*AtlassianAuthService, error) {
+// Stored auth is encrypted with authCipher, or kept as plain JSON when it is nil
+func NewAtlassianAuthService(db *bolt.DB, authCipher *AuthCipher, logger ILogger) (*AtlassianAuthService, error) {
 	// Create auth bucket
 	err := db.Update(func(tx *bolt.Tx) error {
 		_, err := tx.CreateBucketIfNotExists([]byte("auth"))
@@ -36,16 +48,19 @@ func NewAtlassianAuthService(db *bolt.DB, logger ILogger) (*AtlassianAuthService
 	}
 
 	service := &AtlassianAuthService{
-		db:  db,
-		log: logger,
+		db:     db,
+		cipher: authCipher,
+		log:    logger,
 	}
 
 	// Try to load existing auth
 	if authData, err := service.LoadAuth(); err == nil {
-		if updateErr := service.UpdateAuth(authData); updateErr != nil {
-			logger.Warn().Err(updateErr).Msg("Failed to apply stored authentication")
-		} else {
-			logger.Info().Msg("Successfully loaded and applied stored authentication")
+		service.applyAuth(authData)
+		logger.Info().Msg("Successfully loaded and applied stored authentication")
+
+		// Auth stored before the history existed becomes its first entry, so it can be restored later
+		if err := service.seedHistory(authData); err != nil {
+			logger.Warn().Err(err).Msg("Failed to record stored authentication in auth history")
 		}
 	} else {
 		logger.Debug().Err(err).Msg("No stored authentication found")
@@ -56,45 +71,75 @@ func NewAtlassianAuthService(db *bolt.DB, logger ILogger) (*AtlassianAuthService
 
 // UpdateAuth updates authentication state and configures HTTP client
 func (s *AtlassianAuthService) UpdateAuth(authData *interfaces.AuthData) error {
-	jar, _ := cookiejar.New(nil)
-	s.client = &http.Client{
-		Jar:     jar,
-		Timeout: 30 * time.Second,
+	return s.setAuth(authData, authSourceCapture)
+}
+
+// setAuth applies auth, stores it as the current auth and records it in the auth history
+func (s *AtlassianAuthService) setAuth(authData *interfaces.AuthData, source string) error {
+	authJSON, err := json.Marshal(authData)
+	if err != nil {
+		return err
 	}
 
-	baseURL, _ := url.Parse(authData.BaseURL)
-	s.client.Jar.SetCookies(baseURL, authData.GetHTTPCookies())
+	s.applyAuth(authData)
 
-	s.baseURL = authData.BaseURL
-	s.userAgent = authData.UserAgent
+	// Store auth in database
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := putAuthRecord(tx, s.cipher, "current", authJSON); err != nil {
+			return err
+		}
+		return s.putHistory(tx, authData, source)
+	})
+}
 
-	if cloudId, ok := authData.Tokens["cloudId"].(string); ok {
-		s.cloudId = cloudId
+// applyAuth configures the HTTP client with the captured cookies and tokens
+func (s *AtlassianAuthService) applyAuth(authData *interfaces.AuthData) {
+	jar, _ := cookiejar.New(nil)
+	baseURL, _ := url.Parse(authData.BaseURL)
+	jar.SetCookies(baseURL, authData.GetHTTPCookies())
+
+	cloudId, ok := authData.Tokens["cloudId"].(string)
+	if ok {
 		s.log.Debug().Str("cloudId", cloudId).Msg("CloudID extracted from auth tokens")
 	} else {
 		s.log.Warn().Msgf("CloudID not found in auth tokens or wrong type (tokens: %+v)", authData.Tokens)
 	}
 
-	if atlToken, ok := authData.Tokens["atlToken"].(string); ok {
-		s.atlToken = atlToken
+	atlToken, ok := authData.Tokens["atlToken"].(string)
+	if ok {
 		s.log.Debug().Msg("atlToken extracted from auth tokens")
 	} else {
 		s.log.Warn().Msgf("atlToken not found in auth tokens or wrong type (tokens: %+v)", authData.Tokens)
 	}
 
-	// Store auth in database
-	return s.db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte("auth"))
-		authJSON, err := json.Marshal(authData)
-		if err != nil {
-			return err
-		}
-		return bucket.Put([]byte("current"), authJSON)
-	})
+	// Cookies set by responses from here on are rotations of this session, merged into a copy
+	// so the caller's auth data is never changed underneath it
+	session := cloneAuthData(authData)
+	client := &http.Client{
+		Jar:     &rotationJar{CookieJar: jar, onSet: s.cookiesRotated(session)},
+		Timeout: 30 * time.Second,
+	}
+
+	// Scrapes read these concurrently, so the whole session is swapped under the lock
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.persistTimer != nil {
+		s.persistTimer.Stop()
+		s.persistTimer = nil
+	}
+	s.session = session
+	s.sessionRotated = false
+	s.client = client
+	s.baseURL = authData.BaseURL
+	s.userAgent = authData.UserAgent
+	s.cloudId = cloudId
+	s.atlToken = atlToken
 }
 
 // IsAuthenticated checks if valid authentication exists
 func (s *AtlassianAuthService) IsAuthenticated() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	// Only require HTTP client with cookies and baseURL
 	// cloudId and atlToken are optional and not used in API requests
 	return s.client != nil && s.baseURL != ""
@@ -104,11 +149,10 @@ func (s *AtlassianAuthService) IsAuthenticated() bool {
 func (s *AtlassianAuthService) LoadAuth() (*interfaces.AuthData, error) {
 	var authData interfaces.AuthData
 	err := s.db.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte("auth"))
-		if bucket == nil {
-			return fmt.Errorf("auth bucket not found")
+		authJSON, err := getAuthRecord(tx, s.cipher, "current")
+		if err != nil {
+			return err
 		}
-		authJSON := bucket.Get([]byte("current"))
 		if authJSON == nil {
 			return fmt.Errorf("no auth data found")
 		}
@@ -119,25 +163,35 @@ func (s *AtlassianAuthService) LoadAuth() (*interfaces.AuthData, error) {
 
 // GetHTTPClient returns configured HTTP client with cookies
 func (s *AtlassianAuthService) GetHTTPClient() *http.Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.client
 }
 
 // GetBaseURL returns the base URL for API requests
 func (s *AtlassianAuthService) GetBaseURL() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.baseURL
 }
 
 // GetUserAgent returns the user agent string
 func (s *AtlassianAuthService) GetUserAgent() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.userAgent
 }
 
 // GetCloudID returns the Atlassian cloud ID
 func (s *AtlassianAuthService) GetCloudID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.cloudId
 }
 
 // GetAtlToken returns the atl_token for CSRF protection
 func (s *AtlassianAuthService) GetAtlToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.atlToken
 }
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"aktis-parser/internal/interfaces"
+	. "github.com/ternarybob/arbor"
+)
+
+// authValidationInterval is how often stored credentials are re-validated in the background
+const authValidationInterval = 5 * time.Minute
+
+// authSessionMarker is implemented by auth services that keep a history of sessions to restore
+type authSessionMarker interface {
+	MarkSessionValid(at time.Time) error
+}
+
+// AuthValidator actively checks the current credentials against Jira and Confluence, since a client and
+// base URL existing says nothing about whether the session behind them is still alive
+type AuthValidator struct {
+	authService interfaces.AuthService
+	log         ILogger
+
+	mu     sync.RWMutex
+	status *interfaces.AuthStatus
+}
+
+// NewAuthValidator creates a validator for the shared AuthService
+func NewAuthValidator(authService interfaces.AuthService, logger ILogger) *AuthValidator {
+	return &AuthValidator{
+		authService: authService,
+		log:         logger,
+		status:      &interfaces.AuthStatus{State: interfaces.AuthStateWaiting},
+	}
+}
+
+// Start validates now and then every few minutes in the background
+func (v *AuthValidator) Start() {
+	go func() {
+		v.Validate()
+		ticker := time.NewTicker(authValidationInterval)
+		for range ticker.C {
+			v.Validate()
+		}
+	}()
+}
+
+// Status returns the result of the last validation
+func (v *AuthValidator) Status() *interfaces.AuthStatus {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	status := *v.status
+	return &status
+}
+
+// Validate calls /rest/api/3/myself and /wiki/rest/api/user/current with the current credentials and
+// records who they belong to and when the session cookies run out
+func (v *AuthValidator) Validate() *interfaces.AuthStatus {
+	status := &interfaces.AuthStatus{State: interfaces.AuthStateWaiting}
+	if !v.authService.IsAuthenticated() {
+		v.setStatus(status)
+		return status
+	}
+
+	now := time.Now()
+	status.BaseURL = v.authService.GetBaseURL()
+	status.CheckedAt = &now
+
+	if authData, err := v.authService.LoadAuth(); err == nil {
+		if expiresAt, name := authData.SessionCookieExpiry(); !expiresAt.IsZero() {
+			status.CookieExpiresAt = &expiresAt
+			status.ExpiringCookie = name
+		}
+		if authData.BaseURL != "" {
+			status.BaseURL = authData.BaseURL
+		}
+	}
+
+	jiraExpired, confluenceExpired := false, false
+
+	// Server and Data Center serve myself under v2 only
+	identity, err := v.fetchIdentity("/rest/api/3/myself")
+	if isNotFound(err) {
+		identity, err = v.fetchIdentity("/rest/api/2/myself")
+	}
+	if err != nil {
+		status.JiraError = err.Error()
+		jiraExpired = isAuthExpired(err)
+	} else {
+		status.Jira = identity
+	}
+
+	// Server and Data Center serve Confluence without /wiki
+	identity, err = v.fetchIdentity("/wiki/rest/api/user/current")
+	if isNotFound(err) {
+		identity, err = v.fetchIdentity("/rest/api/user/current")
+	}
+	if err != nil {
+		status.ConfluenceError = err.Error()
+		confluenceExpired = isAuthExpired(err)
+	} else {
+		status.Confluence = identity
+	}
+
+	switch {
+	case status.Jira != nil || status.Confluence != nil:
+		status.State = interfaces.AuthStateValid
+	case jiraExpired || confluenceExpired:
+		status.State = interfaces.AuthStateExpired
+	default:
+		status.State = interfaces.AuthStateError
+	}
+
+	// A session that passed is marked in the auth history as one worth restoring
+	if marker, ok := v.authService.(authSessionMarker); ok && status.State == interfaces.AuthStateValid {
+		if err := marker.MarkSessionValid(now); err != nil {
+			v.log.Warn().Err(err).Msg("Failed to mark session as valid in auth history")
+		}
+	}
+
+	v.setStatus(status)
+	return status
+}
+
+// setStatus records a validation result, logging when the state changes
+func (v *AuthValidator) setStatus(status *interfaces.AuthStatus) {
+	v.mu.Lock()
+	previous := v.status.State
+	v.status = status
+	v.mu.Unlock()
+
+	if previous == status.State {
+		return
+	}
+	event := v.log.Info()
+	if status.State == interfaces.AuthStateExpired || status.State == interfaces.AuthStateError {
+		event = v.log.Warn()
+	}
+	if identity := status.Identity(); identity != nil {
+		event = event.Str("account", identity.DisplayName)
+	}
+	event.Str("state", status.State).Str("jiraError", status.JiraError).Str("confluenceError", status.ConfluenceError).Msg("Authentication state changed")
+}
+
+// fetchIdentity fetches the current user from a Jira or Confluence endpoint
+// Cloud and Server field names differ, as do Jira and Confluence, so both are read
+func (v *AuthValidator) fetchIdentity(path string) (*interfaces.AuthIdentity, error) {
+	client := v.authService.GetHTTPClient()
+	if client == nil {
+		return nil, fmt.Errorf("not authenticated")
+	}
+
+	req, err := http.NewRequest("GET", v.authService.GetBaseURL()+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", v.authService.GetUserAgent())
+	req.Header.Set("Accept", "application/json")
+
+	// An expired Server session redirects to the login page rather than answering 401
+	noRedirect := *client
+	noRedirect.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	resp, err := noRedirect.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, fmt.Errorf("auth expired (status %d)", resp.StatusCode)
+	case resp.StatusCode >= 300 && resp.StatusCode < 400:
+		return nil, fmt.Errorf("auth expired (redirected to %s)", resp.Header.Get("Location"))
+	case resp.StatusCode != http.StatusOK:
+		return nil, &httpStatusError{Status: resp.StatusCode, Body: string(body)}
+	}
+
+	var user struct {
+		AccountID    string `json:"accountId"`
+		UserKey      string `json:"userKey"`
+		Key          string `json:"key"`
+		Type         string `json:"type"`
+		DisplayName  string `json:"displayName"`
+		EmailAddress string `json:"emailAddress"`
+		Email        string `json:"email"`
+	}
+	if err := json.Unmarshal(body, &user); err != nil {
+		return nil, fmt.Errorf("failed to parse current user: %w", err)
+	}
+
+	// Confluence answers anonymous requests with an anonymous user rather than an error
+	if user.Type == "anonymous" {
+		return nil, fmt.Errorf("auth expired (anonymous user)")
+	}
+
+	identity := &interfaces.AuthIdentity{
+		AccountID:   firstNonEmpty(user.AccountID, user.UserKey, user.Key),
+		DisplayName: user.DisplayName,
+		Email:       firstNonEmpty(user.EmailAddress, user.Email),
+	}
+	return identity, nil
+}
+
+// isAuthExpired reports whether a validation failed because the credentials were rejected
+func isAuthExpired(err error) bool {
+	// Client errors wrap the transport's message, so the marker may not lead
+	return err != nil && strings.Contains(err.Error(), "auth expired")
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"aktis-parser/internal/common"
+	"aktis-parser/internal/interfaces"
+)
+
+// identityRoutes maps paths on a fake site to the status and body they answer with
+type identityRoutes map[string]struct {
+	status int
+	body   string
+}
+
+func (routes identityRoutes) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route, ok := routes[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if route.status == http.StatusFound {
+		http.Redirect(w, r, "/login.jsp", http.StatusFound)
+		return
+	}
+	w.WriteHeader(route.status)
+	w.Write([]byte(route.body))
+}
+
+func TestAuthValidatorValidate(t *testing.T) {
+	tests := []struct {
+		name           string
+		routes         identityRoutes
+		wantState      string
+		wantJira       string
+		wantConfluence string
+	}{
+		{
+			name: "cloud",
+			routes: identityRoutes{
+				"/rest/api/3/myself":          {200, `{"accountId":"a-1","displayName":"Jane","emailAddress":"jane@example.com"}`},
+				"/wiki/rest/api/user/current": {200, `{"accountId":"a-1","displayName":"Jane","email":"jane@example.com","type":"known"}`},
+			},
+			wantState:      interfaces.AuthStateValid,
+			wantJira:       "a-1",
+			wantConfluence: "a-1",
+		},
+		{
+			name: "data center falls back to v2 and to Confluence without /wiki",
+			routes: identityRoutes{
+				"/rest/api/2/myself":     {200, `{"key":"jane","displayName":"Jane"}`},
+				"/rest/api/user/current": {200, `{"userKey":"ff80","displayName":"Jane"}`},
+			},
+			wantState:      interfaces.AuthStateValid,
+			wantJira:       "jane",
+			wantConfluence: "ff80",
+		},
+		{
+			name: "one product is enough",
+			routes: identityRoutes{
+				"/rest/api/3/myself":          {200, `{"accountId":"a-1"}`},
+				"/wiki/rest/api/user/current": {401, ``},
+			},
+			wantState: interfaces.AuthStateValid,
+			wantJira:  "a-1",
+		},
+		{
+			name: "rejected, redirected to login and anonymous are expired",
+			routes: identityRoutes{
+				"/rest/api/3/myself":          {401, ``},
+				"/wiki/rest/api/user/current": {200, `{"type":"anonymous"}`},
+			},
+			wantState: interfaces.AuthStateExpired,
+		},
+		{
+			name: "server session redirected to the login page",
+			routes: identityRoutes{
+				"/rest/api/3/myself":          {http.StatusFound, ``},
+				"/wiki/rest/api/user/current": {http.StatusFound, ``},
+			},
+			wantState: interfaces.AuthStateExpired,
+		},
+		{
+			name: "server errors are not expiry",
+			routes: identityRoutes{
+				"/rest/api/3/myself":          {500, `boom`},
+				"/wiki/rest/api/user/current": {503, `down`},
+			},
+			wantState: interfaces.AuthStateError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.routes)
+			t.Cleanup(server.Close)
+			validator := NewAuthValidator(&testAuth{baseURL: server.URL, client: server.Client()}, common.GetLogger())
+
+			status := validator.Validate()
+			if status.State != tt.wantState {
+				t.Fatalf("state = %s (jira %q, confluence %q), want %s", status.State, status.JiraError, status.ConfluenceError, tt.wantState)
+			}
+			if got := identityAccount(status.Jira); got != tt.wantJira {
+				t.Errorf("jira account = %q, want %q", got, tt.wantJira)
+			}
+			if got := identityAccount(status.Confluence); got != tt.wantConfluence {
+				t.Errorf("confluence account = %q, want %q", got, tt.wantConfluence)
+			}
+			if tt.wantJira == "" && status.JiraError == "" {
+				t.Error("jira failed without an error")
+			}
+			if validator.Status().State != tt.wantState {
+				t.Errorf("Status() = %s after Validate", validator.Status().State)
+			}
+		})
+	}
+}
+
+// identityAccount returns the account id of an identity, or "" for none
+func identityAccount(identity *interfaces.AuthIdentity) string {
+	if identity == nil {
+		return ""
+	}
+	return identity.AccountID
+}
+
+func TestAuthValidatorMarksStoredSession(t *testing.T) {
+	server := httptest.NewServer(identityRoutes{
+		"/rest/api/3/myself": {200, `{"accountId":"a-1","displayName":"Jane"}`},
+	})
+	t.Cleanup(server.Close)
+
+	authService := newTestAuthService(t)
+	validator := NewAuthValidator(authService, common.GetLogger())
+	if status := validator.Validate(); status.State != interfaces.AuthStateWaiting {
+		t.Fatalf("state without credentials = %s, want WAITING", status.State)
+	}
+
+	expires := time.Now().Add(2 * time.Hour).Truncate(time.Second)
+	auth := capturedAuth(server.URL, "s")
+	auth.Cookies[0].Domain = "127.0.0.1"
+	auth.Cookies[0].Expires = expires.Unix()
+	if err := authService.UpdateAuth(auth); err != nil {
+		t.Fatal(err)
+	}
+
+	status := validator.Validate()
+	if status.State != interfaces.AuthStateValid {
+		t.Fatalf("state = %s (%s), want VALID", status.State, status.JiraError)
+	}
+	if status.CookieExpiresAt == nil || !status.CookieExpiresAt.Equal(expires) || status.ExpiringCookie != "tenant.session.token" {
+		t.Errorf("cookie expiry = %v %q, want %v tenant.session.token", status.CookieExpiresAt, status.ExpiringCookie, expires)
+	}
+
+	entries, err := authService.AuthHistory()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("AuthHistory = %+v, %v", entries, err)
+	}
+	if entries[0].ValidatedAt == nil || !entries[0].ValidatedAt.Equal(*status.CheckedAt) {
+		t.Errorf("history entry validated at %v, want %v", entries[0].ValidatedAt, status.CheckedAt)
+	}
+}
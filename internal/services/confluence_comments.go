@@ -0,0 +1,198 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// maxCommentDepth caps how deep reply threads are followed
+const maxCommentDepth = 10
+
+// storePageComments replaces the stored footer and inline comments of each page, including all replies
+func (s *ConfluenceScraperService) storePageComments(pages []map[string]interface{}) {
+	for _, page := range pages {
+		pageID, ok := page["id"].(string)
+		if !ok {
+			continue
+		}
+
+		// v1 lists both kinds with their replies in one listing
+		if s.dataCenter() {
+			comments, err := s.fetchCommentsV1(pageID)
+			if err != nil {
+				s.log.Warn().Err(err).Str("pageId", pageID).Msg("Failed to fetch page comments")
+				continue
+			}
+			if err := s.putPageComments(pageID, comments); err != nil {
+				s.log.Warn().Err(err).Str("pageId", pageID).Msg("Failed to store page comments")
+			}
+			continue
+		}
+
+		comments := []map[string]interface{}{}
+		failed := false
+		for _, kind := range []string{"footer", "inline"} {
+			path := fmt.Sprintf("/wiki/api/v2/%s/%s/%s-comments?limit=100&body-format=storage", contentPath(page), pageID, kind)
+			threads, err := s.fetchComments(path, pageID, kind, "", 0)
+			if err != nil {
+				s.log.Warn().Err(err).Str("pageId", pageID).Str("kind", kind).Msg("Failed to fetch page comments")
+				failed = true
+				break
+			}
+			comments = append(comments, threads...)
+		}
+		// Keep the previously stored comments rather than replacing them with a partial set
+		if failed {
+			continue
+		}
+
+		if err := s.putPageComments(pageID, comments); err != nil {
+			s.log.Warn().Err(err).Str("pageId", pageID).Msg("Failed to store page comments")
+		}
+	}
+}
+
+// fetchComments follows a comment listing and the replies of every comment in it
+// Each record gains "pageId", "kind" and "parentCommentId"; inline comments also gain "selection",
+// the page text the comment thread is anchored to
+func (s *ConfluenceScraperService) fetchComments(path, pageID, kind, parentID string, depth int) ([]map[string]interface{}, error) {
+	comments := []map[string]interface{}{}
+
+	for path != "" {
+		data, err := s.makeRequest("GET", path)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Results []map[string]interface{} `json:"results"`
+			Links   struct {
+				Next string `json:"next"`
+			} `json:"_links"`
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse comments: %w", err)
+		}
+
+		for _, comment := range result.Results {
+			commentID, ok := comment["id"].(string)
+			if !ok {
+				continue
+			}
+			comment["pageId"] = pageID
+			comment["kind"] = kind
+			comment["parentCommentId"] = parentID
+			if properties, ok := comment["properties"].(map[string]interface{}); ok {
+				if selection, ok := properties["inlineOriginalSelection"].(string); ok {
+					comment["selection"] = selection
+				} else if selection, ok := properties["inline-original-selection"].(string); ok {
+					comment["selection"] = selection
+				}
+			}
+			comments = append(comments, comment)
+
+			if depth >= maxCommentDepth {
+				continue
+			}
+			repliesPath := fmt.Sprintf("/wiki/api/v2/%s-comments/%s/children?limit=100&body-format=storage", kind, commentID)
+			replies, err := s.fetchComments(repliesPath, pageID, kind, commentID, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			comments = append(comments, replies...)
+		}
+
+		path = wikiPath(result.Links.Next)
+	}
+
+	return comments, nil
+}
+
+// putPageComments stores the comments of a page under "<pageId>/<commentId>", dropping ones no longer on the page
+func (s *ConfluenceScraperService) putPageComments(pageID string, comments []map[string]interface{}) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("confluence_comments"))
+		if bucket == nil {
+			return nil
+		}
+
+		prefix := []byte(pageID + "/")
+		stale := [][]byte{}
+		c := bucket.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			stale = append(stale, append([]byte(nil), k...))
+		}
+		for _, k := range stale {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		for _, comment := range comments {
+			commentID, _ := comment["id"].(string)
+			value, err := json.Marshal(comment)
+			if err != nil {
+				continue
+			}
+			if err := bucket.Put([]byte(pageID+"/"+commentID), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// readPageComments returns the stored comment threads of a page
+// Top-level comments are ordered footer before inline and oldest first, with their replies nested under "replies"
+func readPageComments(bucket *bolt.Bucket, pageID string) []map[string]interface{} {
+	threads := []map[string]interface{}{}
+	if bucket == nil {
+		return threads
+	}
+
+	comments := []map[string]interface{}{}
+	prefix := []byte(pageID + "/")
+	c := bucket.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		var comment map[string]interface{}
+		if err := json.Unmarshal(v, &comment); err == nil {
+			comments = append(comments, comment)
+		}
+	}
+	sort.SliceStable(comments, func(i, j int) bool {
+		if comments[i]["kind"] != comments[j]["kind"] {
+			return comments[i]["kind"] == "footer"
+		}
+		return commentIDLess(comments[i]["id"], comments[j]["id"])
+	})
+
+	byID := make(map[string]map[string]interface{}, len(comments))
+	for _, comment := range comments {
+		commentID, _ := comment["id"].(string)
+		comment["replies"] = []map[string]interface{}{}
+		byID[commentID] = comment
+	}
+	for _, comment := range comments {
+		parentID, _ := comment["parentCommentId"].(string)
+		if parent, ok := byID[parentID]; ok {
+			parent["replies"] = append(parent["replies"].([]map[string]interface{}), comment)
+		} else {
+			threads = append(threads, comment)
+		}
+	}
+	return threads
+}
+
+// commentIDLess orders numeric comment ids, which grow over time, without parsing them
+func commentIDLess(a, b interface{}) bool {
+	left, _ := a.(string)
+	right, _ := b.(string)
+	if len(left) != len(right) {
+		return len(left) < len(right)
+	}
+	return left < right
+}
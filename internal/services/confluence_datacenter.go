@@ -0,0 +1,204 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Confluence Server and Data Center only have the v1 REST API, served from the site root rather than
+// /wiki. Request paths are written for Cloud and translated by requestPath; v1 records are reshaped
+// like their v2 counterparts so everything stored looks the same whichever flavor it came from.
+
+// v1ContentExpand is the expansion needed to reshape v1 content like v2 content with a storage body
+const v1ContentExpand = "body.storage,version,ancestors,space,extensions.position"
+
+// v1CommentExpand is the expansion needed to reshape v1 comments like v2 comments
+const v1CommentExpand = "body.storage,version,ancestors,extensions.inlineProperties,extensions.location"
+
+// requestPath returns the path to request on the site: Cloud paths as is, and on Server and
+// Data Center without the /wiki context Cloud serves Confluence from
+func (s *ConfluenceScraperService) requestPath(path string) string {
+	if !s.dataCenter() {
+		return path
+	}
+	return strings.TrimPrefix(path, "/wiki")
+}
+
+// v1ContentListPath lists the current pages or blog posts of a space through v1
+func v1ContentListPath(spaceKey, contentType, expand string) string {
+	params := url.Values{}
+	params.Set("spaceKey", spaceKey)
+	params.Set("type", contentType)
+	params.Set("status", "current")
+	params.Set("limit", "100")
+	if expand != "" {
+		params.Set("expand", expand)
+	}
+	return "/wiki/rest/api/content?" + params.Encode()
+}
+
+// normalizeV1Content reshapes v1 content like v2 content: the space, parent and position are taken
+// from the expanded space, ancestors and extensions, and the version gains authorId and createdAt
+func normalizeV1Content(content map[string]interface{}) map[string]interface{} {
+	if space, ok := content["space"].(map[string]interface{}); ok {
+		content["spaceId"] = v1ID(space["id"])
+	}
+	delete(content, "space")
+
+	// The tree rebuild stores ancestors as ids, v1 expands them as content records
+	if ancestors, ok := content["ancestors"].([]interface{}); ok && len(ancestors) > 0 {
+		if parent, ok := ancestors[len(ancestors)-1].(map[string]interface{}); ok {
+			content["parentId"] = v1ID(parent["id"])
+			content["parentType"] = parent["type"]
+		}
+	}
+	delete(content, "ancestors")
+
+	if extensions, ok := content["extensions"].(map[string]interface{}); ok {
+		// Unordered pages report a position of "none"
+		if position, ok := extensions["position"].(float64); ok {
+			content["position"] = position
+		}
+	}
+
+	if version, ok := content["version"].(map[string]interface{}); ok {
+		normalizeV1Version(version)
+	}
+	return content
+}
+
+// normalizeV1Version adds the v2 authorId and createdAt fields to a v1 version
+func normalizeV1Version(version map[string]interface{}) {
+	if by, ok := version["by"].(map[string]interface{}); ok {
+		if userKey, ok := by["userKey"].(string); ok {
+			version["authorId"] = userKey
+		}
+	}
+	if when, ok := version["when"].(string); ok {
+		version["createdAt"] = when
+	}
+}
+
+// normalizeV1Attachment reshapes a v1 attachment like an entry of the v2 attachments listing
+func normalizeV1Attachment(item map[string]interface{}) map[string]interface{} {
+	attachment := map[string]interface{}{
+		"id":    item["id"],
+		"title": item["title"],
+	}
+	if metadata, ok := item["metadata"].(map[string]interface{}); ok {
+		attachment["mediaType"] = metadata["mediaType"]
+	}
+	if extensions, ok := item["extensions"].(map[string]interface{}); ok {
+		attachment["fileSize"] = extensions["fileSize"]
+	}
+	if links, ok := item["_links"].(map[string]interface{}); ok {
+		attachment["downloadLink"] = links["download"]
+	}
+	return attachment
+}
+
+// fetchCommentsV1 lists every footer and inline comment of a page with its replies through v1
+// Records are reshaped like those built by fetchComments
+func (s *ConfluenceScraperService) fetchCommentsV1(pageID string) ([]map[string]interface{}, error) {
+	params := url.Values{}
+	params.Set("depth", "all")
+	params.Set("limit", "100")
+	params.Set("expand", v1CommentExpand)
+	path := fmt.Sprintf("/wiki/rest/api/content/%s/child/comment?%s", pageID, params.Encode())
+
+	comments := []map[string]interface{}{}
+	for path != "" {
+		data, err := s.makeRequest("GET", path)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Results []map[string]interface{} `json:"results"`
+			Links   struct {
+				Next string `json:"next"`
+			} `json:"_links"`
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse comments: %w", err)
+		}
+
+		for _, comment := range result.Results {
+			comment["pageId"] = pageID
+			comment["kind"] = "footer"
+			comment["parentCommentId"] = ""
+
+			// Replies list the comments they answer as ancestors, the closest one last
+			if ancestors, ok := comment["ancestors"].([]interface{}); ok {
+				for _, ancestor := range ancestors {
+					if parent, ok := ancestor.(map[string]interface{}); ok && parent["type"] == "comment" {
+						comment["parentCommentId"] = v1ID(parent["id"])
+					}
+				}
+			}
+			delete(comment, "ancestors")
+
+			if extensions, ok := comment["extensions"].(map[string]interface{}); ok {
+				if location, ok := extensions["location"].(string); ok && location != "" {
+					comment["kind"] = location
+				}
+				if inline, ok := extensions["inlineProperties"].(map[string]interface{}); ok {
+					if selection, ok := inline["originalSelection"].(string); ok {
+						comment["selection"] = selection
+					}
+				}
+			}
+			if version, ok := comment["version"].(map[string]interface{}); ok {
+				normalizeV1Version(version)
+			}
+			comments = append(comments, comment)
+		}
+
+		path = wikiPath(result.Links.Next)
+	}
+	return comments, nil
+}
+
+// findSpaceKeyV1 finds the key of a space by id by walking the v1 space listing
+// v1 has no lookup by space id
+func (s *ConfluenceScraperService) findSpaceKeyV1(spaceID string) (string, error) {
+	path := "/wiki/rest/api/space?limit=250"
+	for path != "" {
+		data, err := s.makeRequest("GET", path)
+		if err != nil {
+			return "", err
+		}
+
+		var result struct {
+			Results []map[string]interface{} `json:"results"`
+			Links   struct {
+				Next string `json:"next"`
+			} `json:"_links"`
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return "", fmt.Errorf("failed to parse spaces: %w", err)
+		}
+		for _, space := range result.Results {
+			if v1ID(space["id"]) == spaceID {
+				key, _ := space["key"].(string)
+				return key, nil
+			}
+		}
+		path = wikiPath(result.Links.Next)
+	}
+	return "", fmt.Errorf("space %s not found", spaceID)
+}
+
+// v1ID returns a v1 id as a string; content ids are strings but space ids are numbers
+func v1ID(value interface{}) string {
+	switch id := value.(type) {
+	case string:
+		return id
+	case float64:
+		return strconv.FormatInt(int64(id), 10)
+	}
+	return ""
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"fmt"
+)
+
+// RunCQLQuery stores every page or blog post matching a CQL query and tags them with the query name
+// Matches are grouped by space and stored like a sync of that space, then the affected trees are rebuilt;
+// other content types such as attachments and comments are ignored
+func (s *ConfluenceScraperService) RunCQLQuery(name, cql string) (int, error) {
+	s.log.Info().Str("query", name).Str("cql", cql).Msg("Running saved CQL query")
+	if s.uiLog != nil {
+		s.uiLog.BroadcastUILog("info", fmt.Sprintf("Running saved query: %s", name))
+	}
+
+	found, err := s.searchContent(cql)
+	if err != nil {
+		s.log.Error().Err(err).Str("query", name).Msg("Failed to search content")
+		if s.uiLog != nil {
+			s.uiLog.BroadcastUILog("error", fmt.Sprintf("Error running saved query %s: %v", name, err))
+		}
+		return 0, err
+	}
+
+	// Pages are fetched through v2 so stored records keep the v2 shape, then grouped by space
+	bySpace := make(map[string][]map[string]interface{})
+	for _, item := range found {
+		if item.contentType != "page" && item.contentType != "blogpost" {
+			continue
+		}
+		page, err := s.fetchContent(item.contentType, item.id)
+		if err != nil {
+			s.log.Warn().Err(err).Str("pageId", item.id).Msg("Failed to fetch matched page")
+			continue
+		}
+		page["type"] = item.contentType
+		spaceID, _ := page["spaceId"].(string)
+		bySpace[spaceID] = append(bySpace[spaceID], page)
+	}
+
+	matched := make(map[string]bool)
+	for spaceID, pages := range bySpace {
+		spaceKey, err := s.resolveSpaceKey(spaceID)
+		if err != nil {
+			s.log.Warn().Err(err).Str("spaceId", spaceID).Msg("Failed to resolve space of matched pages")
+			continue
+		}
+
+		for start := 0; start < len(pages); start += changedContentBatchSize {
+			end := min(start+changedContentBatchSize, len(pages))
+			if err := s.storeContent(spaceKey, spaceID, pages[start:end]); err != nil {
+				return 0, err
+			}
+		}
+		for _, page := range pages {
+			if id, ok := page["id"].(string); ok {
+				matched[id] = true
+			}
+		}
+
+		if err := s.rebuildSpaceTree(spaceKey); err != nil {
+			s.log.Warn().Err(err).Str("spaceKey", spaceKey).Msg("Failed to build page tree")
+		}
+	}
+
+	if err := tagQueryMatches(s.db, "confluence_pages", "confluence_query_tags", name, matched); err != nil {
+		return 0, err
+	}
+
+	if s.uiLog != nil {
+		s.uiLog.BroadcastUILog("success", fmt.Sprintf("Completed: %d pages matched saved query %s", len(matched), name))
+	}
+	return len(matched), nil
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"aktis-parser/internal/interfaces"
+	bolt "go.etcd.io/bbolt"
+)
+
+// snapshotSpaceContent returns the stored pages and blog posts of a space as tombstone candidates keyed by id
+func (s *ConfluenceScraperService) snapshotSpaceContent(spaceKey string) map[string]interfaces.Tombstone {
+	pages := make(map[string]interfaces.Tombstone)
+	s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("confluence_pages"))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var page map[string]interface{}
+			if err := json.Unmarshal(v, &page); err != nil {
+				return nil
+			}
+			if pageSpaceKey(page) != spaceKey {
+				return nil
+			}
+
+			record := interfaces.Tombstone{Source: "confluence", Key: string(k), ID: string(k), Container: spaceKey}
+			record.Title, _ = page["title"].(string)
+			pages[record.Key] = record
+			return nil
+		})
+	})
+	return pages
+}
+
+// fetchSpaceContentIDs lists the id of every current page or blog post of the configured content types
+// Bodies are not requested, so this is much cheaper than a full sync
+func (s *ConfluenceScraperService) fetchSpaceContentIDs(spaceKey, spaceID string) (map[string]bool, error) {
+	ids := make(map[string]bool)
+	dataCenter := s.dataCenter()
+	for _, contentType := range s.contentTypes() {
+		path := fmt.Sprintf("/wiki/api/v2/spaces/%s/%ss?limit=250", spaceID, contentType)
+		if dataCenter {
+			path = v1ContentListPath(spaceKey, contentType, "")
+		}
+		for path != "" {
+			data, err := s.makeRequest("GET", path)
+			if err != nil {
+				return nil, err
+			}
+
+			var result struct {
+				Results []struct {
+					ID string `json:"id"`
+				} `json:"results"`
+				Links struct {
+					Next string `json:"next"`
+				} `json:"_links"`
+			}
+			if err := json.Unmarshal(data, &result); err != nil {
+				return nil, fmt.Errorf("failed to parse %s ids: %w", contentType, err)
+			}
+
+			for _, item := range result.Results {
+				ids[item.ID] = true
+			}
+			path = wikiPath(result.Links.Next)
+		}
+	}
+	return ids, nil
+}
+
+// reconcileSpaceContent prunes stored pages that are missing from the space's remote id set
+// Each missing page is looked up by id: pages moved to another space are stored there, pages that were
+// deleted, trashed or archived are removed with their versions and comments, and all of them leave a tombstone
+func (s *ConfluenceScraperService) reconcileSpaceContent(spaceKey, spaceID string, before map[string]interfaces.Tombstone, remote map[string]bool, fullSync bool, syncedAt time.Time) {
+	report := &interfaces.PruneReport{
+		Source:      "confluence",
+		Container:   spaceKey,
+		SyncedAt:    syncedAt,
+		FullSync:    fullSync,
+		LocalCount:  len(before),
+		RemoteCount: len(remote),
+		Deleted:     []interfaces.Tombstone{},
+		Moved:       []interfaces.Tombstone{},
+	}
+
+	stored := s.storedContentTypes(before)
+	for _, record := range missingRecords(before, remote) {
+		record.Reason = "deleted"
+		record.DeletedAt = time.Now()
+
+		page, err := s.fetchContent(stored[record.Key], record.Key)
+		if err != nil && !isNotFound(err) {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", record.Key, err))
+			continue
+		}
+
+		if err == nil {
+			status, _ := page["status"].(string)
+			newSpaceID, _ := page["spaceId"].(string)
+
+			switch {
+			case status == "archived":
+				record.Reason = "archived"
+			case status != "current":
+				// trashed, deleted and drafts are no longer published content
+			case newSpaceID != "" && newSpaceID != spaceID:
+				if err := s.rehomeContent(page, stored[record.Key], newSpaceID, &record); err != nil {
+					report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", record.Key, err))
+					continue
+				}
+			default:
+				// Still current in this space, only the listing missed it
+				continue
+			}
+		}
+
+		err = s.db.Update(func(tx *bolt.Tx) error {
+			if record.Reason != "moved" {
+				if err := deletePageRecords(tx, record.Key); err != nil {
+					return err
+				}
+			}
+			return putTombstone(tx, "confluence_tombstones", record)
+		})
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", record.Key, err))
+			continue
+		}
+
+		if record.Reason == "moved" {
+			report.Moved = append(report.Moved, record)
+		} else {
+			report.Deleted = append(report.Deleted, record)
+		}
+	}
+
+	if err := savePruneReport(s.db, "confluence_prune_reports", report); err != nil {
+		s.log.Warn().Err(err).Str("spaceKey", spaceKey).Msg("Failed to store prune report")
+	}
+
+	s.log.Info().
+		Str("spaceKey", spaceKey).
+		Int("deleted", len(report.Deleted)).
+		Int("moved", len(report.Moved)).
+		Int("errors", len(report.Errors)).
+		Msg("Reconciled space pages")
+	if s.uiLog != nil && len(report.Deleted)+len(report.Moved) > 0 {
+		s.uiLog.BroadcastUILog("info", fmt.Sprintf("Pruned %d deleted and %d moved pages from %s", len(report.Deleted), len(report.Moved), spaceKey))
+	}
+}
+
+// rehomeContent stores a page that moved to another space under that space and rebuilds its tree
+func (s *ConfluenceScraperService) rehomeContent(page map[string]interface{}, contentType, newSpaceID string, record *interfaces.Tombstone) error {
+	newSpaceKey, err := s.resolveSpaceKey(newSpaceID)
+	if err != nil {
+		return err
+	}
+
+	page["type"] = contentType
+	if err := s.storeContent(newSpaceKey, newSpaceID, []map[string]interface{}{page}); err != nil {
+		return err
+	}
+	if err := s.rebuildSpaceTree(newSpaceKey); err != nil {
+		s.log.Warn().Err(err).Str("spaceKey", newSpaceKey).Msg("Failed to build page tree")
+	}
+
+	record.Reason = "moved"
+	record.MovedTo = newSpaceKey
+	return nil
+}
+
+// storedContentTypes returns the stored content type of each record, "page" when none was recorded
+func (s *ConfluenceScraperService) storedContentTypes(records map[string]interfaces.Tombstone) map[string]string {
+	types := make(map[string]string, len(records))
+	s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("confluence_pages"))
+		for id := range records {
+			types[id] = "page"
+			if bucket == nil {
+				continue
+			}
+			var page map[string]interface{}
+			if err := json.Unmarshal(bucket.Get([]byte(id)), &page); err == nil {
+				if contentType, ok := page["type"].(string); ok && contentType != "" {
+					types[id] = contentType
+				}
+			}
+		}
+		return nil
+	})
+	return types
+}
+
+// resolveSpaceKey returns the key of a space by id, from the stored space records when possible
+func (s *ConfluenceScraperService) resolveSpaceKey(spaceID string) (string, error) {
+	var spaceKey string
+	s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("confluence_spaces"))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var space map[string]interface{}
+			if err := json.Unmarshal(v, &space); err != nil {
+				return nil
+			}
+			// Spaces stored by the v1 scraper carry a numeric id
+			switch id := space["id"].(type) {
+			case string:
+				if id == spaceID {
+					spaceKey = string(k)
+				}
+			case float64:
+				if strconv.FormatInt(int64(id), 10) == spaceID {
+					spaceKey = string(k)
+				}
+			}
+			return nil
+		})
+	})
+	if spaceKey != "" {
+		return spaceKey, nil
+	}
+
+	if s.dataCenter() {
+		return s.findSpaceKeyV1(spaceID)
+	}
+
+	data, err := s.makeRequest("GET", fmt.Sprintf("/wiki/api/v2/spaces/%s", spaceID))
+	if err != nil {
+		return "", err
+	}
+	var space struct {
+		Key string `json:"key"`
+	}
+	if err := json.Unmarshal(data, &space); err != nil {
+		return "", fmt.Errorf("failed to parse space %s: %w", spaceID, err)
+	}
+	return space.Key, nil
+}
+
+// deletePageRecords removes a stored page along with its versions and comments
+func deletePageRecords(tx *bolt.Tx, pageID string) error {
+	if bucket := tx.Bucket([]byte("confluence_pages")); bucket != nil {
+		if err := bucket.Delete([]byte(pageID)); err != nil {
+			return err
+		}
+	}
+	for _, name := range []string{"confluence_versions", "confluence_comments"} {
+		if err := deleteIssueChildren(tx, name, pageID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPruneReports returns the reconciliation reports of a space, or of every space when empty, newest first
+func (s *ConfluenceScraperService) GetPruneReports(spaceKey string) ([]interfaces.PruneReport, error) {
+	return readPruneReports(s.db, "confluence_prune_reports", spaceKey)
+}
+
+// GetTombstones returns the tombstones of pages pruned from a space, or from every space when empty
+func (s *ConfluenceScraperService) GetTombstones(spaceKey string) ([]interfaces.Tombstone, error) {
+	return readTombstones(s.db, "confluence_tombstones", spaceKey)
+}
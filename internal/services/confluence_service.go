@@ -5,28 +5,49 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"aktis-parser/internal/common"
 	"aktis-parser/internal/interfaces"
 	. "github.com/ternarybob/arbor"
 	bolt "go.etcd.io/bbolt"
 )
 
+// confluenceBuckets lists every bucket owned by the Confluence scraper
+var confluenceBuckets = []string{
+	"confluence_spaces",
+	"confluence_pages",
+	"confluence_tree",
+	"confluence_versions",
+	"confluence_comments",
+	"confluence_sync_state",
+	"confluence_tombstones",
+	"confluence_prune_reports",
+	"confluence_query_tags",
+}
+
 // ConfluenceScraperService implements the ConfluenceScraper interface
 type ConfluenceScraperService struct {
 	authService interfaces.AuthService
+	config      *common.ConfluenceConfig
 	db          *bolt.DB
 	log         ILogger
 	uiLog       UILogger
+	attachments interfaces.AttachmentStore
+	flavor      deploymentFlavor
 }
 
 // NewConfluenceScraper creates a new Confluence scraper instance
-func NewConfluenceScraper(db *bolt.DB, authService interfaces.AuthService, logger ILogger) (*ConfluenceScraperService, error) {
+func NewConfluenceScraper(db *bolt.DB, authService interfaces.AuthService, config *common.ConfluenceConfig, logger ILogger) (*ConfluenceScraperService, error) {
 	// Create buckets
 	err := db.Update(func(tx *bolt.Tx) error {
-		tx.CreateBucketIfNotExists([]byte("confluence_spaces"))
-		tx.CreateBucketIfNotExists([]byte("confluence_pages"))
+		for _, name := range confluenceBuckets {
+			tx.CreateBucketIfNotExists([]byte(name))
+		}
 		return nil
 	})
 	if err != nil {
@@ -36,16 +57,18 @@ func NewConfluenceScraper(db *bolt.DB, authService interfaces.AuthService, logge
 	return &ConfluenceScraperService{
 		db:          db,
 		authService: authService,
+		config:      config,
 		log:         logger,
 	}, nil
 }
 
 // NewConfluenceScraperWithDB creates a new Confluence scraper instance with an existing database connection
-func NewConfluenceScraperWithDB(db *bolt.DB, authService interfaces.AuthService, logger ILogger) (*ConfluenceScraperService, error) {
+func NewConfluenceScraperWithDB(db *bolt.DB, authService interfaces.AuthService, config *common.ConfluenceConfig, logger ILogger) (*ConfluenceScraperService, error) {
 	// Create buckets
 	err := db.Update(func(tx *bolt.Tx) error {
-		tx.CreateBucketIfNotExists([]byte("confluence_spaces"))
-		tx.CreateBucketIfNotExists([]byte("confluence_pages"))
+		for _, name := range confluenceBuckets {
+			tx.CreateBucketIfNotExists([]byte(name))
+		}
 		return nil
 	})
 	if err != nil {
@@ -55,6 +78,7 @@ func NewConfluenceScraperWithDB(db *bolt.DB, authService interfaces.AuthService,
 	return &ConfluenceScraperService{
 		db:          db,
 		authService: authService,
+		config:      config,
 		log:         logger,
 	}, nil
 }
@@ -64,14 +88,33 @@ func (s *ConfluenceScraperService) SetUILogger(uiLog UILogger) {
 	s.uiLog = uiLog
 }
 
+// SetAttachmentStore sets the store used to download page attachments
+func (s *ConfluenceScraperService) SetAttachmentStore(store interfaces.AttachmentStore) {
+	s.attachments = store
+}
+
+// SetFlavor sets the deployment flavor: "cloud", "datacenter" or "auto" to detect it from serverInfo
+func (s *ConfluenceScraperService) SetFlavor(flavor string) {
+	s.flavor.set(flavor)
+}
+
+// dataCenter reports whether the authenticated site is Confluence Server or Data Center
+func (s *ConfluenceScraperService) dataCenter() bool {
+	return s.flavor.resolve(s.authService, s.log) == FlavorDataCenter
+}
+
 // Close closes the scraper and releases database resources
 func (s *ConfluenceScraperService) Close() error {
 	return s.db.Close()
 }
 
 // makeRequest makes an authenticated HTTP request
+// Paths are written for Cloud; on Server and Data Center they are served without /wiki
 func (s *ConfluenceScraperService) makeRequest(method, path string) ([]byte, error) {
-	url := s.authService.GetBaseURL() + path
+	if strings.HasPrefix(path, "/wiki/api/v2/") && s.dataCenter() {
+		return nil, fmt.Errorf("%s needs the Confluence Cloud v2 API", path)
+	}
+	url := s.authService.GetBaseURL() + s.requestPath(path)
 
 	req, err := http.NewRequest(method, url, nil)
 	if err != nil {
@@ -101,7 +144,7 @@ func (s *ConfluenceScraperService) makeRequest(method, path string) ([]byte, err
 		if resp.StatusCode == 401 || resp.StatusCode == 403 {
 			return nil, fmt.Errorf("auth expired (status %d)", resp.StatusCode)
 		}
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+		return nil, &httpStatusError{Status: resp.StatusCode, Body: string(body)}
 	}
 
 	if readErr != nil {
@@ -111,9 +154,13 @@ func (s *ConfluenceScraperService) makeRequest(method, path string) ([]byte, err
 	return body, nil
 }
 
-// GetSpacePageCount returns the total count of pages for a Confluence space
+// GetSpacePageCount returns the total count of current pages for a Confluence space
+// The v2 API has no count endpoint, so the CQL search totalSize is used
 func (s *ConfluenceScraperService) GetSpacePageCount(spaceKey string) (int, error) {
-	path := fmt.Sprintf("/wiki/rest/api/content?spaceKey=%s&limit=0", spaceKey)
+	params := url.Values{}
+	params.Set("cql", fmt.Sprintf("space=%s and type=page", cqlString(spaceKey)))
+	params.Set("limit", "1")
+	path := "/wiki/rest/api/search?" + params.Encode()
 
 	s.log.Debug().
 		Str("spaceKey", spaceKey).
@@ -130,8 +177,7 @@ func (s *ConfluenceScraperService) GetSpacePageCount(spaceKey string) (int, erro
 	}
 
 	var result struct {
-		Size  int `json:"size"`
-		Total int `json:"total"`
+		TotalSize int `json:"totalSize"`
 	}
 	if err := json.Unmarshal(data, &result); err != nil {
 		s.log.Error().
@@ -142,14 +188,88 @@ func (s *ConfluenceScraperService) GetSpacePageCount(spaceKey string) (int, erro
 		return -1, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	// Use 'total' field which contains the total number of pages in the space
-	// The 'size' field only contains the number of results in the current response
 	s.log.Debug().
 		Str("spaceKey", spaceKey).
-		Int("total", result.Total).
+		Int("total", result.TotalSize).
 		Msg("Retrieved page count from API")
 
-	return result.Total, nil
+	return result.TotalSize, nil
+}
+
+// wikiPath turns a _links.next value, which is relative to the /wiki context path, into a request path
+func wikiPath(link string) string {
+	if link == "" {
+		return ""
+	}
+	return "/wiki" + strings.TrimPrefix(link, "/wiki")
+}
+
+// resolveSpaceID returns the id of a space, from the stored space record when possible
+func (s *ConfluenceScraperService) resolveSpaceID(spaceKey string) (string, error) {
+	var spaceID string
+	s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("confluence_spaces"))
+		if bucket == nil {
+			return nil
+		}
+		data := bucket.Get([]byte(spaceKey))
+		if data == nil {
+			return nil
+		}
+		var space map[string]interface{}
+		if err := json.Unmarshal(data, &space); err != nil {
+			return nil
+		}
+		// Spaces stored by the v1 scraper carry a numeric id, which v2 accepts as a string
+		switch id := space["id"].(type) {
+		case string:
+			spaceID = id
+		case float64:
+			spaceID = strconv.FormatInt(int64(id), 10)
+		}
+		return nil
+	})
+	if spaceID != "" {
+		return spaceID, nil
+	}
+
+	if s.dataCenter() {
+		data, err := s.makeRequest("GET", "/wiki/rest/api/space/"+url.PathEscape(spaceKey))
+		if err != nil {
+			return "", err
+		}
+		var space map[string]interface{}
+		if err := json.Unmarshal(data, &space); err != nil {
+			return "", fmt.Errorf("failed to parse space lookup: %w", err)
+		}
+		if id := v1ID(space["id"]); id != "" {
+			return id, nil
+		}
+		return "", fmt.Errorf("space %s not found", spaceKey)
+	}
+
+	params := url.Values{}
+	params.Set("keys", spaceKey)
+	data, err := s.makeRequest("GET", "/wiki/api/v2/spaces?"+params.Encode())
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		Results []struct {
+			ID  string `json:"id"`
+			Key string `json:"key"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("failed to parse space lookup: %w", err)
+	}
+	for _, space := range result.Results {
+		if space.Key == spaceKey {
+			return space.ID, nil
+		}
+	}
+	return "", fmt.Errorf("space %s not found", spaceKey)
 }
 
 // ScrapeConfluence scrapes all Confluence spaces and page counts
@@ -157,12 +277,13 @@ func (s *ConfluenceScraperService) ScrapeConfluence() error {
 	s.log.Info().Msg("Scraping Confluence spaces...")
 
 	allSpaces := []map[string]interface{}{}
-	start := 0
-	limit := 25
 
-	// Paginate through all spaces
-	for {
-		path := fmt.Sprintf("/wiki/rest/api/space?start=%d&limit=%d", start, limit)
+	// Follow _links.next cursors through all spaces
+	path := "/wiki/api/v2/spaces?limit=250"
+	if s.dataCenter() {
+		path = "/wiki/rest/api/space?limit=250"
+	}
+	for path != "" {
 		data, err := s.makeRequest("GET", path)
 		if err != nil {
 			return err
@@ -170,25 +291,21 @@ func (s *ConfluenceScraperService) ScrapeConfluence() error {
 
 		var spaces struct {
 			Results []map[string]interface{} `json:"results"`
-			Size    int                      `json:"size"`
+			Links   struct {
+				Next string `json:"next"`
+			} `json:"_links"`
 		}
 		if err := json.Unmarshal(data, &spaces); err != nil {
 			return fmt.Errorf("failed to parse spaces: %w", err)
 		}
 
-		if len(spaces.Results) == 0 {
-			break
-		}
-
 		allSpaces = append(allSpaces, spaces.Results...)
 		s.log.Info().Int("count", len(spaces.Results)).Msgf("Fetched %d spaces (total so far: %d)", len(spaces.Results), len(allSpaces))
 
-		start += limit
-		if len(spaces.Results) < limit {
-			break
+		path = wikiPath(spaces.Links.Next)
+		if path != "" {
+			time.Sleep(500 * time.Millisecond)
 		}
-
-		time.Sleep(500 * time.Millisecond)
 	}
 
 	s.log.Info().Int("total", len(allSpaces)).Msg("Fetched all Confluence spaces, getting page counts...")
@@ -265,178 +382,98 @@ func (s *ConfluenceScraperService) ScrapeConfluence() error {
 }
 
 // GetSpacePages fetches pages for a specific Confluence space (public method for API)
+// An incremental sync is used when a watermark exists, otherwise a full sync
 func (s *ConfluenceScraperService) GetSpacePages(spaceKey string) error {
-	return s.scrapeSpacePages(spaceKey)
+	return s.SyncSpacePages(spaceKey, interfaces.PageSyncOptions{})
 }
 
-// scrapeSpacePages scrapes all pages in a Confluence space using concurrent batch fetching
-func (s *ConfluenceScraperService) scrapeSpacePages(spaceKey string) error {
+// SyncSpacePages syncs the configured content types of a Confluence space. Incremental syncs
+// only re-fetch content modified since the stored watermark; full syncs walk every page with v2 cursors.
+// Either way, stored pages that are no longer in the space are then pruned.
+func (s *ConfluenceScraperService) SyncSpacePages(spaceKey string, options interfaces.PageSyncOptions) error {
+	syncStarted := time.Now()
+	before := s.snapshotSpaceContent(spaceKey)
+
 	s.log.Info().Str("spaceKey", spaceKey).Msg("Starting to fetch Confluence pages from space")
 	if s.uiLog != nil {
 		s.uiLog.BroadcastUILog("info", fmt.Sprintf("Fetching pages from space: %s", spaceKey))
 	}
 
-	// Get total page count first (note: Confluence API page count is unreliable, so we fetch anyway)
-	pageCount, err := s.GetSpacePageCount(spaceKey)
+	spaceID, err := s.resolveSpaceID(spaceKey)
 	if err != nil {
-		s.log.Warn().Err(err).Str("spaceKey", spaceKey).Msg("Could not get page count, will fetch until empty")
-		pageCount = -1
-	} else {
-		s.log.Info().Str("spaceKey", spaceKey).Int("pageCount", pageCount).Msg("API reported page count (may be inaccurate)")
+		s.log.Error().Err(err).Str("spaceKey", spaceKey).Msg("Failed to resolve space id")
+		if s.uiLog != nil {
+			s.uiLog.BroadcastUILog("error", fmt.Sprintf("Failed to resolve space %s: %v", spaceKey, err))
+		}
+		return err
 	}
 
-	// Always attempt to fetch pages regardless of reported count
-	// The pagination loop will naturally stop when no pages are returned
+	state, err := s.GetSpaceSyncState(spaceKey)
+	if err != nil {
+		s.log.Warn().Err(err).Str("spaceKey", spaceKey).Msg("Failed to load sync state, falling back to full sync")
+	}
+	incremental := !options.FullSync && state != nil && !state.LastSynced.IsZero()
+	keepWatermark := false
 
-	limit := 25
-	batchSize := 5 // Number of concurrent requests
-	totalPages := 0
-	start := 0
-
-	for {
-		// Create batch of goroutines to fetch pages concurrently
-		var wg sync.WaitGroup
-		var mu sync.Mutex
-		batchResults := make([]struct {
-			start   int
-			pages   []map[string]interface{}
-			err     error
-			hasMore bool
-		}, batchSize)
-
-		actualBatchSize := batchSize
-		// Only use pageCount for optimization if we have a valid count (> 0)
-		// If pageCount is 0 or -1 (unknown), fetch until we get empty results
-		if pageCount > 0 {
-			remaining := pageCount - totalPages
-			if remaining <= 0 {
-				break
-			}
-			if remaining < batchSize*limit {
-				actualBatchSize = (remaining + limit - 1) / limit
-				if actualBatchSize == 0 {
-					actualBatchSize = 1
-				}
-			}
+	if incremental {
+		s.log.Info().
+			Str("spaceKey", spaceKey).
+			Str("watermark", state.LastSynced.Format(time.RFC3339)).
+			Msg("Starting incremental page sync")
+		if s.uiLog != nil {
+			s.uiLog.BroadcastUILog("info", fmt.Sprintf("Incremental sync for %s (changes since %s)", spaceKey, state.LastSynced.Format("2006-01-02 15:04")))
 		}
-		// If pageCount is 0 or negative, actualBatchSize remains at batchSize
-		// and we'll fetch until we get no results
 
-		for i := 0; i < actualBatchSize; i++ {
-			wg.Add(1)
-			batchStart := start + (i * limit)
-
-			go func(index int, batchStart int) {
-				defer wg.Done()
-
-				path := fmt.Sprintf("/wiki/rest/api/content?spaceKey=%s&start=%d&limit=%d&expand=body.storage,space",
-					spaceKey, batchStart, limit)
-
-				s.log.Debug().Str("path", path).Int("batch", index).Msg("Requesting pages batch")
-				data, err := s.makeRequest("GET", path)
-				if err != nil {
-					mu.Lock()
-					batchResults[index].err = err
-					mu.Unlock()
-					return
-				}
-
-				var result struct {
-					Results []map[string]interface{} `json:"results"`
-					Size    int                      `json:"size"`
-				}
-				if err := json.Unmarshal(data, &result); err != nil {
-					mu.Lock()
-					batchResults[index].err = fmt.Errorf("failed to parse pages: %w", err)
-					mu.Unlock()
-					return
-				}
-
-				mu.Lock()
-				batchResults[index].start = batchStart
-				batchResults[index].pages = result.Results
-				batchResults[index].hasMore = len(result.Results) >= limit
-				mu.Unlock()
-
-				time.Sleep(100 * time.Millisecond)
-			}(i, batchStart)
+		failed, err := s.syncChangedContent(spaceKey, spaceID, state.LastSynced)
+		if err != nil {
+			return err
 		}
+		// Content that failed to fetch is only found again while the watermark stays before its change
+		keepWatermark = failed > 0
+
+		// Deletions do not show up in a lastmodified search, so compare against the full id list
+		if remote, err := s.fetchSpaceContentIDs(spaceKey, spaceID); err != nil {
+			s.log.Warn().Err(err).Str("spaceKey", spaceKey).Msg("Failed to list space pages, skipping reconciliation")
+		} else {
+			s.reconcileSpaceContent(spaceKey, spaceID, before, remote, false, syncStarted)
+		}
+	} else {
+		s.log.Info().
+			Str("spaceKey", spaceKey).
+			Str("fullSyncRequested", fmt.Sprintf("%v", options.FullSync)).
+			Msg("Starting full page sync")
 
-		wg.Wait()
-
-		// Process results in order and store in database
-		foundEmpty := false
-		for i := 0; i < actualBatchSize; i++ {
-			if batchResults[i].err != nil {
-				s.log.Error().Err(batchResults[i].err).Int("batch", i).Msg("Batch fetch error")
-				if s.uiLog != nil {
-					s.uiLog.BroadcastUILog("error", fmt.Sprintf("Error fetching pages: %v", batchResults[i].err))
-				}
-				return batchResults[i].err
-			}
-
-			if len(batchResults[i].pages) == 0 {
-				foundEmpty = true
-				break
-			}
-
-			// Store pages
-			err = s.db.Update(func(tx *bolt.Tx) error {
-				bucket := tx.Bucket([]byte("confluence_pages"))
-				for _, page := range batchResults[i].pages {
-					id, ok := page["id"].(string)
-					if !ok {
-						continue
-					}
-					value, err := json.Marshal(page)
-					if err != nil {
-						continue
-					}
-					if err := bucket.Put([]byte(id), value); err != nil {
-						return err
-					}
-				}
-				return nil
-			})
-			if err != nil {
+		remote := make(map[string]bool)
+		for _, contentType := range s.contentTypes() {
+			if _, err := s.scrapeSpaceContent(spaceKey, spaceID, contentType, remote); err != nil {
 				return err
 			}
-
-			totalPages += len(batchResults[i].pages)
-
-			if s.uiLog != nil {
-				progress := ""
-				if pageCount > 0 {
-					progress = fmt.Sprintf(" (%d/%d)", totalPages, pageCount)
-				}
-				s.uiLog.BroadcastUILog("info", fmt.Sprintf("Fetched %d pages from %s%s", totalPages, spaceKey, progress))
-			}
-
-			// Check if we got fewer pages than requested (end of results)
-			if len(batchResults[i].pages) < limit {
-				foundEmpty = true
-				break
-			}
 		}
+		s.reconcileSpaceContent(spaceKey, spaceID, before, remote, true, syncStarted)
+	}
 
-		if foundEmpty {
-			break
-		}
-
-		start += actualBatchSize * limit
-
-		// If we know the page count and have fetched all pages
-		if pageCount > 0 && totalPages >= pageCount {
-			break
-		}
+	if err := s.rebuildSpaceTree(spaceKey); err != nil {
+		s.log.Warn().Err(err).Str("spaceKey", spaceKey).Msg("Failed to build page tree")
 	}
 
-	s.log.Info().Str("spaceKey", spaceKey).Int("totalPages", totalPages).Msg("Completed page scraping for space")
-	if s.uiLog != nil {
-		s.uiLog.BroadcastUILog("success", fmt.Sprintf("Completed: %d pages from %s", totalPages, spaceKey))
+	newState := &SpaceSyncState{
+		SpaceKey:   spaceKey,
+		LastSynced: syncStarted,
+	}
+	if incremental {
+		newState.LastFullSync = state.LastFullSync
+	} else {
+		newState.LastFullSync = syncStarted
+	}
+	if keepWatermark {
+		newState.LastSynced = state.LastSynced
+	}
+	if err := s.saveSpaceSyncState(newState); err != nil {
+		s.log.Warn().Err(err).Str("spaceKey", spaceKey).Msg("Failed to store sync watermark")
 	}
 
-	// Update the space's pageCount in database with actual count
+	// Update the space's pageCount (and blogpostCount) in database with the stored counts
+	counts := s.countSpaceContent(spaceKey)
 	err = s.db.Update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte("confluence_spaces"))
 		if bucket == nil {
@@ -453,7 +490,10 @@ func (s *ConfluenceScraperService) scrapeSpacePages(spaceKey string) error {
 			return err
 		}
 
-		space["pageCount"] = totalPages
+		for _, contentType := range s.contentTypes() {
+			space[contentType+"Count"] = counts[contentType]
+		}
+		space["lastSynced"] = syncStarted.Format(time.RFC3339)
 		updatedData, err := json.Marshal(space)
 		if err != nil {
 			return err
@@ -465,14 +505,278 @@ func (s *ConfluenceScraperService) scrapeSpacePages(spaceKey string) error {
 	if err != nil {
 		s.log.Warn().Err(err).Str("spaceKey", spaceKey).Msg("Failed to update space page count")
 	} else {
-		s.log.Info().Str("spaceKey", spaceKey).Int("pageCount", totalPages).Msg("Updated space with actual page count")
+		s.log.Info().Str("spaceKey", spaceKey).Int("pageCount", counts["page"]).Int("blogpostCount", counts["blogpost"]).Msg("Updated space with actual page count")
+	}
+
+	return nil
+}
+
+// contentTypes returns the configured content types, "page" when none are configured
+func (s *ConfluenceScraperService) contentTypes() []string {
+	if s.config != nil && len(s.config.ContentTypes) > 0 {
+		return s.config.ContentTypes
+	}
+	return []string{"page"}
+}
+
+// scrapeSpaceContent stores every page or blog post of a space, with labels and the enabled related records
+// The id of every record fetched is added to seen
+func (s *ConfluenceScraperService) scrapeSpaceContent(spaceKey, spaceID, contentType string, seen map[string]bool) (int, error) {
+	// The count is only used for progress reporting
+	pageCount := -1
+	if contentType == "page" {
+		var err error
+		if pageCount, err = s.GetSpacePageCount(spaceKey); err != nil {
+			s.log.Warn().Err(err).Str("spaceKey", spaceKey).Msg("Could not get page count, progress will not be reported")
+			pageCount = -1
+		}
+	}
+
+	totalPages := 0
+	dataCenter := s.dataCenter()
+	path := fmt.Sprintf("/wiki/api/v2/spaces/%s/%ss?limit=250&body-format=storage", spaceID, contentType)
+	if dataCenter {
+		path = v1ContentListPath(spaceKey, contentType, v1ContentExpand)
+	}
+
+	for path != "" {
+		s.log.Debug().Str("path", path).Str("type", contentType).Msg("Requesting pages batch")
+		data, err := s.makeRequest("GET", path)
+		if err != nil {
+			s.log.Error().Err(err).Str("spaceKey", spaceKey).Msg("Page fetch error")
+			if s.uiLog != nil {
+				s.uiLog.BroadcastUILog("error", fmt.Sprintf("Error fetching pages: %v", err))
+			}
+			return totalPages, err
+		}
+
+		var result struct {
+			Results []map[string]interface{} `json:"results"`
+			Links   struct {
+				Next string `json:"next"`
+			} `json:"_links"`
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return totalPages, fmt.Errorf("failed to parse %ss: %w", contentType, err)
+		}
+
+		for _, page := range result.Results {
+			if dataCenter {
+				normalizeV1Content(page)
+			}
+			page["type"] = contentType
+			if id, ok := page["id"].(string); ok {
+				seen[id] = true
+			}
+		}
+		if err := s.storeContent(spaceKey, spaceID, result.Results); err != nil {
+			return totalPages, err
+		}
+
+		totalPages += len(result.Results)
+
+		if s.uiLog != nil {
+			progress := ""
+			if pageCount > 0 {
+				progress = fmt.Sprintf(" (%d/%d)", totalPages, pageCount)
+			}
+			s.uiLog.BroadcastUILog("info", fmt.Sprintf("Fetched %d %ss from %s%s", totalPages, contentType, spaceKey, progress))
+		}
+
+		path = wikiPath(result.Links.Next)
+		if path != "" {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	s.log.Info().Str("spaceKey", spaceKey).Str("type", contentType).Int("total", totalPages).Msg("Completed content scraping for space")
+	if s.uiLog != nil {
+		s.uiLog.BroadcastUILog("success", fmt.Sprintf("Completed: %d %ss from %s", totalPages, contentType, spaceKey))
+	}
+
+	return totalPages, nil
+}
+
+// storeContent stores a batch of v2 pages or blog posts along with their labels and enabled related records
+// Each record must already carry its "type"; the space id and key are added since v2 records only carry spaceId
+func (s *ConfluenceScraperService) storeContent(spaceKey, spaceID string, pages []map[string]interface{}) error {
+	for _, page := range pages {
+		page["space"] = map[string]interface{}{
+			"id":  spaceID,
+			"key": spaceKey,
+		}
+		page["labels"] = s.fetchContentLabels(page)
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("confluence_pages"))
+		for _, page := range pages {
+			id, ok := page["id"].(string)
+			if !ok {
+				continue
+			}
+			// Saved query tags live in their own bucket so they survive full syncs
+			if tags := queryTags(tx, "confluence_query_tags", id); len(tags) > 0 {
+				page["queries"] = tags
+			}
+			value, err := json.Marshal(page)
+			if err != nil {
+				continue
+			}
+			if err := bucket.Put([]byte(id), value); err != nil {
+				return err
+			}
+			if err := clearTombstone(tx, "confluence_tombstones", id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.attachments != nil && s.attachments.Enabled() {
+		s.storePageAttachments(pages)
+	}
+
+	if s.config != nil && s.config.IncludeVersions {
+		s.storePageVersions(pages)
+	}
+
+	if s.config != nil && s.config.IncludeComments {
+		s.storePageComments(pages)
 	}
 
 	return nil
 }
 
+// contentPath returns the v2 path segment for a stored record: "blogposts" for blog posts, "pages" otherwise
+func contentPath(page map[string]interface{}) string {
+	if page["type"] == "blogpost" {
+		return "blogposts"
+	}
+	return "pages"
+}
+
+// fetchContentLabels returns the labels of a page or blog post as {id, name, prefix} records
+// Failures are logged and yield no labels so a single bad page does not stop the scrape
+func (s *ConfluenceScraperService) fetchContentLabels(page map[string]interface{}) []map[string]interface{} {
+	labels := []map[string]interface{}{}
+	pageID, ok := page["id"].(string)
+	if !ok {
+		return labels
+	}
+
+	path := fmt.Sprintf("/wiki/api/v2/%s/%s/labels?limit=250", contentPath(page), pageID)
+	if s.dataCenter() {
+		path = fmt.Sprintf("/wiki/rest/api/content/%s/label?limit=200", pageID)
+	}
+	for path != "" {
+		data, err := s.makeRequest("GET", path)
+		if err != nil {
+			s.log.Warn().Err(err).Str("pageId", pageID).Msg("Failed to fetch labels")
+			return labels
+		}
+
+		var result struct {
+			Results []map[string]interface{} `json:"results"`
+			Links   struct {
+				Next string `json:"next"`
+			} `json:"_links"`
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			s.log.Warn().Err(err).Str("pageId", pageID).Msg("Failed to parse labels")
+			return labels
+		}
+		for _, label := range result.Results {
+			labels = append(labels, map[string]interface{}{
+				"id":     label["id"],
+				"name":   label["name"],
+				"prefix": label["prefix"],
+			})
+		}
+
+		path = wikiPath(result.Links.Next)
+	}
+	return labels
+}
+
+// storePageAttachments downloads the attachments of each page through the attachment store
+func (s *ConfluenceScraperService) storePageAttachments(pages []map[string]interface{}) {
+	dataCenter := s.dataCenter()
+	for _, page := range pages {
+		pageID, ok := page["id"].(string)
+		if !ok {
+			continue
+		}
+
+		path := fmt.Sprintf("/wiki/api/v2/%s/%s/attachments?limit=250", contentPath(page), pageID)
+		if dataCenter {
+			path = fmt.Sprintf("/wiki/rest/api/content/%s/child/attachment?limit=200", pageID)
+		}
+		for path != "" {
+			data, err := s.makeRequest("GET", path)
+			if err != nil {
+				s.log.Warn().Err(err).Str("pageId", pageID).Msg("Failed to list page attachments")
+				break
+			}
+
+			var result struct {
+				Results []map[string]interface{} `json:"results"`
+				Links   struct {
+					Next string `json:"next"`
+				} `json:"_links"`
+			}
+			if err := json.Unmarshal(data, &result); err != nil {
+				s.log.Warn().Err(err).Str("pageId", pageID).Msg("Failed to parse page attachments")
+				break
+			}
+
+			for _, item := range result.Results {
+				if dataCenter {
+					item = normalizeV1Attachment(item)
+				}
+				s.storeAttachment(pageID, item)
+			}
+
+			path = wikiPath(result.Links.Next)
+		}
+	}
+}
+
+// storeAttachment stores a single attachment from the v2 page attachments listing
+func (s *ConfluenceScraperService) storeAttachment(pageID string, item map[string]interface{}) {
+	id, _ := item["id"].(string)
+	download, _ := item["downloadLink"].(string)
+	if id == "" || download == "" {
+		return
+	}
+
+	attachment := &interfaces.Attachment{
+		ID:          id,
+		Source:      "confluence",
+		OwnerKey:    pageID,
+		DownloadURL: s.authService.GetBaseURL() + s.requestPath(wikiPath(download)),
+	}
+	attachment.Filename, _ = item["title"].(string)
+	attachment.MimeType, _ = item["mediaType"].(string)
+	if size, ok := item["fileSize"].(float64); ok {
+		attachment.Size = int64(size)
+	}
+
+	if err := s.attachments.StoreAttachment(attachment); err != nil {
+		s.log.Warn().Err(err).Str("pageId", pageID).Str("attachment", id).Msg("Failed to store attachment")
+	}
+}
+
 // GetConfluenceData returns all Confluence data (spaces and pages)
 func (s *ConfluenceScraperService) GetConfluenceData() (map[string]interface{}, error) {
+	return s.GetConfluenceDataWithOptions(interfaces.ConfluenceDataOptions{})
+}
+
+// GetConfluenceDataWithOptions returns all Confluence data, optionally enriched with related records
+func (s *ConfluenceScraperService) GetConfluenceDataWithOptions(options interfaces.ConfluenceDataOptions) (map[string]interface{}, error) {
 	result := map[string]interface{}{
 		"spaces": make([]map[string]interface{}, 0),
 		"pages":  make([]map[string]interface{}, 0),
@@ -493,10 +797,14 @@ func (s *ConfluenceScraperService) GetConfluenceData() (map[string]interface{},
 
 		// Get all pages
 		pageBucket := tx.Bucket([]byte("confluence_pages"))
+		commentBucket := tx.Bucket([]byte("confluence_comments"))
 		if pageBucket != nil {
 			pageBucket.ForEach(func(k, v []byte) error {
 				var page map[string]interface{}
 				if err := json.Unmarshal(v, &page); err == nil {
+					if options.IncludeComments {
+						page["comments"] = readPageComments(commentBucket, string(k))
+					}
 					result["pages"] = append(result["pages"].([]map[string]interface{}), page)
 				}
 				return nil
@@ -538,25 +846,18 @@ func (s *ConfluenceScraperService) ClearSpacesCache() error {
 	return nil
 }
 
-// ClearAllData deletes all Confluence data from all buckets
+// ClearAllData deletes all Confluence data from all buckets (spaces, pages and related records)
 func (s *ConfluenceScraperService) ClearAllData() error {
 	s.log.Info().Msg("Clearing all Confluence data from database")
 
 	return s.db.Update(func(tx *bolt.Tx) error {
-		// Delete and recreate confluence_spaces bucket
-		if err := tx.DeleteBucket([]byte("confluence_spaces")); err != nil && err != bolt.ErrBucketNotFound {
-			return fmt.Errorf("failed to delete confluence_spaces bucket: %w", err)
-		}
-		if _, err := tx.CreateBucketIfNotExists([]byte("confluence_spaces")); err != nil {
-			return fmt.Errorf("failed to recreate confluence_spaces bucket: %w", err)
-		}
-
-		// Delete and recreate confluence_pages bucket
-		if err := tx.DeleteBucket([]byte("confluence_pages")); err != nil && err != bolt.ErrBucketNotFound {
-			return fmt.Errorf("failed to delete confluence_pages bucket: %w", err)
-		}
-		if _, err := tx.CreateBucketIfNotExists([]byte("confluence_pages")); err != nil {
-			return fmt.Errorf("failed to recreate confluence_pages bucket: %w", err)
+		for _, name := range confluenceBuckets {
+			if err := tx.DeleteBucket([]byte(name)); err != nil && err != bolt.ErrBucketNotFound {
+				return fmt.Errorf("failed to delete %s bucket: %w", name, err)
+			}
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return fmt.Errorf("failed to recreate %s bucket: %w", name, err)
+			}
 		}
 
 		s.log.Info().Msg("All Confluence data cleared successfully")
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// changedContentBatchSize is the number of changed pages fetched before they are stored
+const changedContentBatchSize = 25
+
+// SpaceSyncState records the incremental sync watermark for a Confluence space
+type SpaceSyncState struct {
+	SpaceKey     string    `json:"spaceKey"`
+	LastSynced   time.Time `json:"lastSynced"`
+	LastFullSync time.Time `json:"lastFullSync"`
+}
+
+// GetSpaceSyncState returns the stored sync watermark for a space, or nil if none exists
+func (s *ConfluenceScraperService) GetSpaceSyncState(spaceKey string) (*SpaceSyncState, error) {
+	var state *SpaceSyncState
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("confluence_sync_state"))
+		if bucket == nil {
+			return nil
+		}
+		data := bucket.Get([]byte(spaceKey))
+		if data == nil {
+			return nil
+		}
+		state = &SpaceSyncState{}
+		return json.Unmarshal(data, state)
+	})
+	return state, err
+}
+
+// saveSpaceSyncState stores the sync watermark for a space
+func (s *ConfluenceScraperService) saveSpaceSyncState(state *SpaceSyncState) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte("confluence_sync_state"))
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(state.SpaceKey), value)
+	})
+}
+
+// syncChangedContent re-fetches the content of a space modified since the watermark, returning how
+// many changed pages could not be fetched; the caller keeps the watermark when any failed
+// CQL only lists the changed ids; each one is then fetched through v2 so stored records keep the v2 shape
+func (s *ConfluenceScraperService) syncChangedContent(spaceKey, spaceID string, since time.Time) (int, error) {
+	changed, err := s.findChangedContent(spaceKey, since)
+	if err != nil {
+		s.log.Error().Err(err).Str("spaceKey", spaceKey).Msg("Failed to search changed pages")
+		if s.uiLog != nil {
+			s.uiLog.BroadcastUILog("error", fmt.Sprintf("Error searching changed pages: %v", err))
+		}
+		return 0, err
+	}
+
+	s.log.Info().Str("spaceKey", spaceKey).Int("changed", len(changed)).Msg("Found changed pages")
+
+	batch := []map[string]interface{}{}
+	fetched, failed := 0, 0
+	for _, item := range changed {
+		page, err := s.fetchContent(item.contentType, item.id)
+		if err != nil {
+			// Content deleted since the search is pruned by reconciliation, anything else has to be retried
+			if !isNotFound(err) {
+				failed++
+			}
+			s.log.Warn().Err(err).Str("pageId", item.id).Msg("Failed to fetch changed page")
+			continue
+		}
+		page["type"] = item.contentType
+		batch = append(batch, page)
+
+		if len(batch) >= changedContentBatchSize {
+			if err := s.storeContent(spaceKey, spaceID, batch); err != nil {
+				return 0, err
+			}
+			fetched += len(batch)
+			batch = []map[string]interface{}{}
+			if s.uiLog != nil {
+				s.uiLog.BroadcastUILog("info", fmt.Sprintf("Fetched %d/%d changed pages from %s", fetched, len(changed), spaceKey))
+			}
+		}
+	}
+	if len(batch) > 0 {
+		if err := s.storeContent(spaceKey, spaceID, batch); err != nil {
+			return 0, err
+		}
+		fetched += len(batch)
+	}
+
+	if failed > 0 {
+		s.log.Warn().Str("spaceKey", spaceKey).Int("failed", failed).Int("changed", len(changed)).Msg("Some changed pages could not be fetched")
+		if s.uiLog != nil {
+			s.uiLog.BroadcastUILog("warn", fmt.Sprintf("Fetched %d changed pages from %s, %d failed and will be retried on the next sync", fetched, spaceKey, failed))
+		}
+		return failed, nil
+	}
+
+	if s.uiLog != nil {
+		s.uiLog.BroadcastUILog("success", fmt.Sprintf("Completed: %d changed pages from %s", fetched, spaceKey))
+	}
+	return 0, nil
+}
+
+// changedContent identifies a page or blog post found by a CQL search
+type changedContent struct {
+	id          string
+	contentType string
+}
+
+// findChangedContent lists the content of a space modified since the watermark with a CQL lastmodified search
+func (s *ConfluenceScraperService) findChangedContent(spaceKey string, since time.Time) ([]changedContent, error) {
+	// Relative CQL dates avoid any dependency on the Confluence user's timezone
+	minutes := int(time.Since(since.Add(-incrementalSyncOverlap)).Minutes()) + 1
+	cql := fmt.Sprintf("space=%s and type in (%s) and lastmodified >= now(\"-%dm\")",
+		cqlString(spaceKey), strings.Join(s.contentTypes(), ","), minutes)
+	return s.searchContent(cql)
+}
+
+// cqlString quotes a value as a CQL string literal
+func cqlString(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// searchContent lists the id and type of everything matching a CQL query through the v1 search API
+func (s *ConfluenceScraperService) searchContent(cql string) ([]changedContent, error) {
+	params := url.Values{}
+	params.Set("cql", cql)
+	params.Set("limit", "250")
+	path := "/wiki/rest/api/content/search?" + params.Encode()
+
+	changed := []changedContent{}
+	for path != "" {
+		data, err := s.makeRequest("GET", path)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Results []struct {
+				ID   string `json:"id"`
+				Type string `json:"type"`
+			} `json:"results"`
+			Links struct {
+				Next string `json:"next"`
+			} `json:"_links"`
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse content search: %w", err)
+		}
+
+		for _, item := range result.Results {
+			changed = append(changed, changedContent{id: item.ID, contentType: item.Type})
+		}
+
+		path = wikiPath(result.Links.Next)
+	}
+	return changed, nil
+}
+
+// fetchContent fetches a single page or blog post with its storage body through v2
+// Server and Data Center fetch it through v1, including trashed content as v2 does
+func (s *ConfluenceScraperService) fetchContent(contentType, id string) (map[string]interface{}, error) {
+	section := "pages"
+	if contentType == "blogpost" {
+		section = "blogposts"
+	}
+
+	dataCenter := s.dataCenter()
+	path := fmt.Sprintf("/wiki/api/v2/%s/%s?body-format=storage", section, id)
+	if dataCenter {
+		path = fmt.Sprintf("/wiki/rest/api/content/%s?status=any&expand=%s", id, v1ContentExpand)
+	}
+
+	data, err := s.makeRequest("GET", path)
+	if err != nil {
+		return nil, err
+	}
+
+	var page map[string]interface{}
+	if err := json.Unmarshal(data, &page); err != nil {
+		return nil, fmt.Errorf("failed to parse %s %s: %w", contentType, id, err)
+	}
+	if dataCenter {
+		normalizeV1Content(page)
+	}
+	return page, nil
+}
+
+// countSpaceContent counts the stored records of a space per content type
+func (s *ConfluenceScraperService) countSpaceContent(spaceKey string) map[string]int {
+	counts := make(map[string]int)
+	s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("confluence_pages"))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var page map[string]interface{}
+			if err := json.Unmarshal(v, &page); err != nil {
+				return nil
+			}
+			if pageSpaceKey(page) != spaceKey {
+				return nil
+			}
+			contentType, _ := page["type"].(string)
+			if contentType == "" {
+				contentType = "page"
+			}
+			counts[contentType]++
+			return nil
+		})
+	})
+	return counts
+}
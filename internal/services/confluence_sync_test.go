@@ -0,0 +1,119 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"aktis-parser/internal/common"
+	"aktis-parser/internal/interfaces"
+)
+
+// fakeConfluence serves the endpoints a sync of space S (id 100) uses on a Cloud site
+type fakeConfluence struct {
+	mu      sync.Mutex
+	pages   []string
+	failing map[string]bool
+	cql     []string
+}
+
+func (f *fakeConfluence) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	page := func(id string) map[string]interface{} {
+		return map[string]interface{}{"id": id, "title": "Page " + id, "spaceId": "100"}
+	}
+
+	switch path := r.URL.Path; {
+	case path == "/wiki/api/v2/spaces":
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": []interface{}{map[string]string{"id": "100", "key": "S"}}})
+	case path == "/wiki/rest/api/search":
+		json.NewEncoder(w).Encode(map[string]interface{}{"totalSize": len(f.pages)})
+	case path == "/wiki/rest/api/content/search":
+		f.cql = append(f.cql, r.URL.Query().Get("cql"))
+		results := []interface{}{}
+		for _, id := range f.pages {
+			results = append(results, map[string]string{"id": id, "type": "page"})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	case path == "/wiki/api/v2/spaces/100/pages":
+		results := []interface{}{}
+		for _, id := range f.pages {
+			results = append(results, page(id))
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	case strings.HasSuffix(path, "/labels"):
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": []interface{}{}})
+	case strings.HasPrefix(path, "/wiki/api/v2/pages/"):
+		id := strings.TrimPrefix(path, "/wiki/api/v2/pages/")
+		if f.failing[id] {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(page(id))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestSyncSpacePagesKeepsWatermarkOnFetchFailure(t *testing.T) {
+	confluence := &fakeConfluence{pages: []string{"1", "2"}, failing: map[string]bool{}}
+	server := httptest.NewServer(confluence)
+	t.Cleanup(server.Close)
+
+	scraper, err := NewConfluenceScraperWithDB(openTestDB(t), &testAuth{baseURL: server.URL, client: server.Client()}, &common.ConfluenceConfig{}, common.GetLogger())
+	if err != nil {
+		t.Fatalf("NewConfluenceScraperWithDB: %v", err)
+	}
+	scraper.SetFlavor(FlavorCloud)
+
+	if err := scraper.SyncSpacePages("S", interfaces.PageSyncOptions{}); err != nil {
+		t.Fatalf("full sync: %v", err)
+	}
+	full, _ := scraper.GetSpaceSyncState("S")
+	if full == nil {
+		t.Fatal("no watermark after the full sync")
+	}
+
+	confluence.failing["2"] = true
+	if err := scraper.SyncSpacePages("S", interfaces.PageSyncOptions{}); err != nil {
+		t.Fatalf("incremental sync: %v", err)
+	}
+	if len(confluence.cql) == 0 || !strings.HasPrefix(confluence.cql[0], `space="S" and `) {
+		t.Errorf("changed content CQL = %q", confluence.cql)
+	}
+	kept, _ := scraper.GetSpaceSyncState("S")
+	if kept == nil || !kept.LastSynced.Equal(full.LastSynced) {
+		t.Errorf("watermark moved past a failed fetch: %v then %v", full, kept)
+	}
+
+	confluence.failing["2"] = false
+	if err := scraper.SyncSpacePages("S", interfaces.PageSyncOptions{}); err != nil {
+		t.Fatalf("retried sync: %v", err)
+	}
+	advanced, _ := scraper.GetSpaceSyncState("S")
+	if advanced == nil || !advanced.LastSynced.After(full.LastSynced) {
+		t.Errorf("watermark did not advance once every fetch succeeded: %v then %v", full, advanced)
+	}
+}
+
+func TestCQLString(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"DEV", `"DEV"`},
+		{`DEV" or space="OPS`, `"DEV\" or space=\"OPS"`},
+		{`back\slash`, `"back\\slash"`},
+		{"", `""`},
+	}
+	for _, tt := range tests {
+		if got := cqlString(tt.value); got != tt.want {
+			t.Errorf("cqlString(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"aktis-parser/internal/interfaces"
+	bolt "go.etcd.io/bbolt"
+)
+
+// SpaceTree is the navigation tree of a space as stored in the confluence_tree bucket
+type SpaceTree struct {
+	SpaceKey string                   `json:"spaceKey"`
+	Roots    []string                 `json:"roots"`
+	Children map[string][]string      `json:"children"`
+	Pages    map[string]SpaceTreePage `json:"pages"`
+}
+
+// SpaceTreePage holds the fields of a page needed to render the tree
+type SpaceTreePage struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	ParentID string `json:"parentId,omitempty"`
+	Position int    `json:"position"`
+}
+
+// rebuildSpaceTree builds the tree index of a space from its stored pages
+// Each page record also gains "ancestors", the ids from the space root down to its parent
+func (s *ConfluenceScraperService) rebuildSpaceTree(spaceKey string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		pageBucket := tx.Bucket([]byte("confluence_pages"))
+		if pageBucket == nil {
+			return nil
+		}
+
+		tree := SpaceTree{
+			SpaceKey: spaceKey,
+			Roots:    []string{},
+			Children: make(map[string][]string),
+			Pages:    make(map[string]SpaceTreePage),
+		}
+		records := make(map[string]map[string]interface{})
+
+		err := pageBucket.ForEach(func(k, v []byte) error {
+			var page map[string]interface{}
+			if err := json.Unmarshal(v, &page); err != nil {
+				return nil
+			}
+			// Blog posts are dated rather than nested, so they stay out of the tree
+			if pageSpaceKey(page) != spaceKey || page["type"] == "blogpost" {
+				return nil
+			}
+
+			entry := SpaceTreePage{ID: string(k)}
+			entry.Title, _ = page["title"].(string)
+			entry.ParentID, _ = page["parentId"].(string)
+			if position, ok := page["position"].(float64); ok {
+				entry.Position = int(position)
+			}
+
+			tree.Pages[entry.ID] = entry
+			records[entry.ID] = page
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// Pages whose parent is not a stored page (the space homepage, or pages under folders) are roots
+		for id, entry := range tree.Pages {
+			if _, ok := tree.Pages[entry.ParentID]; ok {
+				tree.Children[entry.ParentID] = append(tree.Children[entry.ParentID], id)
+			} else {
+				tree.Roots = append(tree.Roots, id)
+			}
+		}
+		sortTreeIDs(tree.Roots, tree.Pages)
+		for _, children := range tree.Children {
+			sortTreeIDs(children, tree.Pages)
+		}
+
+		for id, page := range records {
+			ancestors := []string{}
+			seen := map[string]bool{id: true}
+			for parent := tree.Pages[id].ParentID; parent != ""; parent = tree.Pages[parent].ParentID {
+				if _, ok := tree.Pages[parent]; !ok || seen[parent] {
+					break
+				}
+				seen[parent] = true
+				ancestors = append([]string{parent}, ancestors...)
+			}
+			page["ancestors"] = ancestors
+
+			value, err := json.Marshal(page)
+			if err != nil {
+				continue
+			}
+			if err := pageBucket.Put([]byte(id), value); err != nil {
+				return err
+			}
+		}
+
+		treeBucket, err := tx.CreateBucketIfNotExists([]byte("confluence_tree"))
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(tree)
+		if err != nil {
+			return fmt.Errorf("failed to marshal tree for %s: %w", spaceKey, err)
+		}
+		return treeBucket.Put([]byte(spaceKey), value)
+	})
+}
+
+// GetSpaceTree returns the page tree of a space below parentID down to depth levels
+// An empty parentID starts at the space roots; nil is returned when no tree is stored
+func (s *ConfluenceScraperService) GetSpaceTree(spaceKey, parentID string, depth int) ([]interfaces.PageTreeNode, error) {
+	if depth < 1 {
+		depth = 1
+	}
+
+	var tree *SpaceTree
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("confluence_tree"))
+		if bucket == nil {
+			return nil
+		}
+		data := bucket.Get([]byte(spaceKey))
+		if data == nil {
+			return nil
+		}
+		tree = &SpaceTree{}
+		return json.Unmarshal(data, tree)
+	})
+	if err != nil || tree == nil {
+		return nil, err
+	}
+
+	ids := tree.Roots
+	if parentID != "" {
+		if _, ok := tree.Pages[parentID]; !ok {
+			return nil, nil
+		}
+		ids = tree.Children[parentID]
+	}
+
+	return tree.nodes(ids, depth), nil
+}
+
+// nodes builds tree nodes for the given page ids, expanding children down to depth levels
+func (t *SpaceTree) nodes(ids []string, depth int) []interfaces.PageTreeNode {
+	nodes := make([]interfaces.PageTreeNode, 0, len(ids))
+	for _, id := range ids {
+		page := t.Pages[id]
+		children := t.Children[id]
+		node := interfaces.PageTreeNode{
+			ID:          page.ID,
+			Title:       page.Title,
+			ParentID:    page.ParentID,
+			Position:    page.Position,
+			HasChildren: len(children) > 0,
+			ChildCount:  len(children),
+		}
+		if depth > 1 && len(children) > 0 {
+			node.Children = t.nodes(children, depth-1)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// sortTreeIDs orders sibling pages by their position, then title
+func sortTreeIDs(ids []string, pages map[string]SpaceTreePage) {
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := pages[ids[i]], pages[ids[j]]
+		if a.Position != b.Position {
+			return a.Position < b.Position
+		}
+		return a.Title < b.Title
+	})
+}
+
+// pageSpaceKey returns the space key stored on a page, or empty if absent
+func pageSpaceKey(page map[string]interface{}) string {
+	if space, ok := page["space"].(map[string]interface{}); ok {
+		if key, ok := space["key"].(string); ok {
+			return key
+		}
+	}
+	return ""
+}
@@ -0,0 +1,309 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"aktis-parser/internal/common"
+	"aktis-parser/internal/converters"
+	"aktis-parser/internal/interfaces"
+	bolt "go.etcd.io/bbolt"
+)
+
+// diffContextLines is the number of unchanged lines shown around each change in a version diff
+const diffContextLines = 3
+
+// versionKey builds the confluence_versions key of a page version
+// Numbers are zero padded so a page's versions sort in order under its id prefix
+func versionKey(pageID string, number int) []byte {
+	return []byte(fmt.Sprintf("%s/%010d", pageID, number))
+}
+
+// storePageVersions fetches the versions of each page that are newer than the ones already stored
+// Pages whose current version is already stored cost no requests
+func (s *ConfluenceScraperService) storePageVersions(pages []map[string]interface{}) {
+	for _, page := range pages {
+		pageID, ok := page["id"].(string)
+		if !ok {
+			continue
+		}
+		current := 0
+		if version, ok := page["version"].(map[string]interface{}); ok {
+			if number, ok := version["number"].(float64); ok {
+				current = int(number)
+			}
+		}
+
+		latest := s.latestStoredVersion(pageID)
+		if current > 0 && current <= latest {
+			continue
+		}
+
+		title, _ := page["title"].(string)
+		versions, err := s.fetchPageVersions(contentPath(page), pageID, title, latest)
+		if err != nil {
+			s.log.Warn().Err(err).Str("pageId", pageID).Msg("Failed to fetch page versions")
+			continue
+		}
+		if err := s.putPageVersions(pageID, versions); err != nil {
+			s.log.Warn().Err(err).Str("pageId", pageID).Msg("Failed to store page versions")
+		}
+	}
+}
+
+// fetchPageVersions lists versions newest first, stopping at the stored ones or the configured limit
+// section is the v2 path segment of the content type, "pages" or "blogposts"
+func (s *ConfluenceScraperService) fetchPageVersions(section, pageID, title string, latest int) ([]interfaces.PageVersion, error) {
+	limit := s.config.MaxVersions
+	versions := []interfaces.PageVersion{}
+
+	params := url.Values{}
+	params.Set("limit", "50")
+	params.Set("body-format", "storage")
+	params.Set("sort", "-modified-date")
+	path := fmt.Sprintf("/wiki/api/v2/%s/%s/versions?%s", section, pageID, params.Encode())
+	if s.dataCenter() {
+		// v1 lists versions newest first without bodies
+		path = fmt.Sprintf("/wiki/rest/api/content/%s/version?limit=50", pageID)
+	}
+
+	for path != "" {
+		data, err := s.makeRequest("GET", path)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Results []struct {
+				Number    int    `json:"number"`
+				Message   string `json:"message"`
+				MinorEdit bool   `json:"minorEdit"`
+				AuthorID  string `json:"authorId"`
+				CreatedAt string `json:"createdAt"`
+				Body      struct {
+					Storage struct {
+						Value string `json:"value"`
+					} `json:"storage"`
+				} `json:"body"`
+				// v1 names the author and date differently
+				By struct {
+					UserKey string `json:"userKey"`
+				} `json:"by"`
+				When string `json:"when"`
+			} `json:"results"`
+			Links struct {
+				Next string `json:"next"`
+			} `json:"_links"`
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse versions: %w", err)
+		}
+
+		for _, item := range result.Results {
+			if item.Number <= latest || (limit > 0 && len(versions) >= limit) {
+				return versions, nil
+			}
+
+			version := interfaces.PageVersion{
+				PageID:    pageID,
+				Number:    item.Number,
+				Title:     title,
+				Message:   item.Message,
+				MinorEdit: item.MinorEdit,
+				AuthorID:  item.AuthorID,
+				CreatedAt: item.CreatedAt,
+				Body:      item.Body.Storage.Value,
+			}
+			if version.AuthorID == "" {
+				version.AuthorID = item.By.UserKey
+			}
+			if version.CreatedAt == "" {
+				version.CreatedAt = item.When
+			}
+
+			// Some sites omit bodies from the versions listing, so fetch the page at that version
+			if version.Body == "" {
+				body, versionTitle, err := s.fetchPageAtVersion(section, pageID, item.Number)
+				if err != nil {
+					s.log.Warn().Err(err).Str("pageId", pageID).Int("version", item.Number).Msg("Failed to fetch version body")
+				}
+				version.Body = body
+				if versionTitle != "" {
+					version.Title = versionTitle
+				}
+			}
+
+			versions = append(versions, version)
+		}
+
+		path = wikiPath(result.Links.Next)
+	}
+
+	return versions, nil
+}
+
+// fetchPageAtVersion returns the storage body and title of a page or blog post at a given version
+func (s *ConfluenceScraperService) fetchPageAtVersion(section, pageID string, number int) (string, string, error) {
+	var data []byte
+	var err error
+	if s.dataCenter() {
+		// v1 only serves earlier versions as historical content, the latest one is current
+		data, err = s.makeRequest("GET", fmt.Sprintf("/wiki/rest/api/content/%s?status=historical&version=%d&expand=body.storage", pageID, number))
+		if isNotFound(err) {
+			data, err = s.makeRequest("GET", fmt.Sprintf("/wiki/rest/api/content/%s?expand=body.storage", pageID))
+		}
+	} else {
+		data, err = s.makeRequest("GET", fmt.Sprintf("/wiki/api/v2/%s/%s?version=%d&body-format=storage", section, pageID, number))
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	var page struct {
+		Title string `json:"title"`
+		Body  struct {
+			Storage struct {
+				Value string `json:"value"`
+			} `json:"storage"`
+		} `json:"body"`
+	}
+	if err := json.Unmarshal(data, &page); err != nil {
+		return "", "", fmt.Errorf("failed to parse page version: %w", err)
+	}
+	return page.Body.Storage.Value, page.Title, nil
+}
+
+// putPageVersions stores fetched versions and drops the oldest ones beyond the configured limit
+func (s *ConfluenceScraperService) putPageVersions(pageID string, versions []interfaces.PageVersion) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("confluence_versions"))
+		if bucket == nil {
+			return nil
+		}
+
+		for _, version := range versions {
+			value, err := json.Marshal(version)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(versionKey(pageID, version.Number), value); err != nil {
+				return err
+			}
+		}
+
+		if s.config.MaxVersions <= 0 {
+			return nil
+		}
+
+		keys := [][]byte{}
+		prefix := []byte(pageID + "/")
+		c := bucket.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			keys = append(keys, append([]byte(nil), k...))
+		}
+		for i := 0; i < len(keys)-s.config.MaxVersions; i++ {
+			if err := bucket.Delete(keys[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// latestStoredVersion returns the highest stored version number of a page, or 0 if none is stored
+func (s *ConfluenceScraperService) latestStoredVersion(pageID string) int {
+	latest := 0
+	s.db.View(func(tx *bolt.Tx) error {
+		versions := readPageVersions(tx, pageID)
+		if len(versions) > 0 {
+			latest = versions[0].Number
+		}
+		return nil
+	})
+	return latest
+}
+
+// readPageVersions returns the stored versions of a page, newest first
+func readPageVersions(tx *bolt.Tx, pageID string) []interfaces.PageVersion {
+	versions := []interfaces.PageVersion{}
+	bucket := tx.Bucket([]byte("confluence_versions"))
+	if bucket == nil {
+		return versions
+	}
+
+	prefix := []byte(pageID + "/")
+	c := bucket.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		var version interfaces.PageVersion
+		if err := json.Unmarshal(v, &version); err != nil {
+			continue
+		}
+		versions = append([]interfaces.PageVersion{version}, versions...)
+	}
+	return versions
+}
+
+// GetPageVersions returns the stored versions of a page, newest first, without their bodies
+func (s *ConfluenceScraperService) GetPageVersions(pageID string) ([]interfaces.PageVersion, error) {
+	var versions []interfaces.PageVersion
+	err := s.db.View(func(tx *bolt.Tx) error {
+		versions = readPageVersions(tx, pageID)
+		for i := range versions {
+			versions[i].Body = ""
+		}
+		return nil
+	})
+	return versions, err
+}
+
+// GetPageVersionDiff diffs two stored versions of a page after converting both from storage format to Markdown
+func (s *ConfluenceScraperService) GetPageVersionDiff(pageID string, from, to int) (*interfaces.PageVersionDiff, error) {
+	var versions []interfaces.PageVersion
+	err := s.db.View(func(tx *bolt.Tx) error {
+		versions = readPageVersions(tx, pageID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	toIndex, fromIndex := -1, -1
+	for i, version := range versions {
+		if to == 0 || version.Number == to {
+			toIndex = i
+			break
+		}
+	}
+	if from == 0 {
+		if toIndex >= 0 && toIndex+1 < len(versions) {
+			fromIndex = toIndex + 1
+		}
+	} else {
+		for i, version := range versions {
+			if version.Number == from {
+				fromIndex = i
+				break
+			}
+		}
+	}
+	if fromIndex < 0 || toIndex < 0 {
+		return nil, nil
+	}
+	fromVersion, toVersion := versions[fromIndex], versions[toIndex]
+
+	fromText := converters.StorageToMarkdown(fromVersion.Body)
+	toText := converters.StorageToMarkdown(toVersion.Body)
+
+	return &interfaces.PageVersionDiff{
+		PageID: pageID,
+		From:   fromVersion.Number,
+		To:     toVersion.Number,
+		Diff: common.UnifiedDiff(
+			fmt.Sprintf("%s (v%d)", fromVersion.Title, fromVersion.Number),
+			fmt.Sprintf("%s (v%d)", toVersion.Title, toVersion.Number),
+			fromText, toText, diffContextLines,
+		),
+	}, nil
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"aktis-parser/internal/interfaces"
+	. "github.com/ternarybob/arbor"
+)
+
+// Deployment flavors of an Atlassian site
+const (
+	FlavorAuto       = "auto"
+	FlavorCloud      = "cloud"
+	FlavorDataCenter = "datacenter"
+)
+
+// flavorRetryDelay is how long a flavor guessed after a failed detection is used before detecting again
+const flavorRetryDelay = 5 * time.Minute
+
+// deploymentFlavor resolves whether the authenticated site is Atlassian Cloud or Server/Data Center
+// A configured flavor is used as is; "auto" is detected once per base URL, and a failed detection
+// falls back to a guess from the host that is retried after flavorRetryDelay
+type deploymentFlavor struct {
+	mu         sync.Mutex
+	configured string
+	baseURL    string
+	detected   string
+	// retryAt is when a guessed flavor expires, zero once the flavor was detected
+	retryAt   time.Time
+	detecting bool
+}
+
+// set changes the configured flavor, clearing any detected one
+func (f *deploymentFlavor) set(flavor string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.configured = flavor
+	f.baseURL = ""
+	f.detected = ""
+	f.retryAt = time.Time{}
+}
+
+// resolve returns FlavorCloud or FlavorDataCenter for the current base URL
+// Detection runs without the lock, and calls made meanwhile use the cached flavor or the host guess
+func (f *deploymentFlavor) resolve(authService interfaces.AuthService, log ILogger) string {
+	f.mu.Lock()
+	if f.configured == FlavorCloud || f.configured == FlavorDataCenter {
+		f.mu.Unlock()
+		return f.configured
+	}
+
+	baseURL := authService.GetBaseURL()
+	cached := ""
+	if f.detected != "" && f.baseURL == baseURL {
+		cached = f.detected
+		if f.retryAt.IsZero() || time.Now().Before(f.retryAt) {
+			f.mu.Unlock()
+			return cached
+		}
+	}
+	if f.detecting {
+		f.mu.Unlock()
+		if cached != "" {
+			return cached
+		}
+		return flavorFromHost(baseURL)
+	}
+	f.detecting = true
+	f.mu.Unlock()
+
+	flavor, err := detectFlavor(authService)
+	retryAt := time.Time{}
+	if err != nil {
+		flavor = flavorFromHost(baseURL)
+		retryAt = time.Now().Add(flavorRetryDelay)
+		log.Warn().Err(err).Str("baseURL", baseURL).Str("flavor", flavor).Msg("Failed to detect deployment flavor, guessing from host")
+	} else {
+		log.Info().Str("baseURL", baseURL).Str("flavor", flavor).Msg("Detected deployment flavor")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.detecting = false
+	// Without a client the guess is not cached, so detection runs again once auth arrives
+	if authService.GetHTTPClient() != nil && f.configured != FlavorCloud && f.configured != FlavorDataCenter {
+		f.baseURL = baseURL
+		f.detected = flavor
+		f.retryAt = retryAt
+	}
+	return flavor
+}
+
+// detectFlavor asks /rest/api/2/serverInfo for the deployment type, which Cloud reports as
+// "Cloud" and Server and Data Center as "Server" or "DataCenter"
+func detectFlavor(authService interfaces.AuthService) (string, error) {
+	client := authService.GetHTTPClient()
+	if client == nil {
+		return "", fmt.Errorf("not authenticated")
+	}
+
+	req, err := http.NewRequest("GET", authService.GetBaseURL()+"/rest/api/2/serverInfo", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", authService.GetUserAgent())
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", &httpStatusError{Status: resp.StatusCode, Body: string(body)}
+	}
+
+	var info struct {
+		DeploymentType string `json:"deploymentType"`
+	}
+	if err := json.Unmarshal(body, &info); err != nil {
+		return "", fmt.Errorf("failed to parse server info: %w", err)
+	}
+
+	if strings.EqualFold(info.DeploymentType, "Cloud") {
+		return FlavorCloud, nil
+	}
+	return FlavorDataCenter, nil
+}
+
+// flavorFromHost guesses the flavor from the site host, for sites without Jira's serverInfo
+// such as a standalone Confluence
+func flavorFromHost(baseURL string) string {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return FlavorCloud
+	}
+	host := parsed.Hostname()
+	if strings.HasSuffix(host, ".atlassian.net") || strings.HasSuffix(host, ".jira.com") || host == oauthGatewayHost {
+		return FlavorCloud
+	}
+	return FlavorDataCenter
+}
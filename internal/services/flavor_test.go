@@ -0,0 +1,115 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"aktis-parser/internal/common"
+)
+
+// serverInfo answers /rest/api/2/serverInfo, failing until it is told the deployment type
+type serverInfo struct {
+	mu             sync.Mutex
+	deploymentType string
+	calls          int
+	release        chan struct{}
+}
+
+func (s *serverInfo) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.release != nil {
+		<-s.release
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.deploymentType == "" {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte(`{"deploymentType":"` + s.deploymentType + `"}`))
+}
+
+func (s *serverInfo) callCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+func TestDeploymentFlavorRetriesFailedDetection(t *testing.T) {
+	info := &serverInfo{}
+	server := httptest.NewServer(info)
+	t.Cleanup(server.Close)
+	auth := &testAuth{baseURL: server.URL, client: server.Client()}
+
+	var flavor deploymentFlavor
+	flavor.set(FlavorAuto)
+
+	// The host is not an Atlassian Cloud one, so the failed detection guesses Data Center
+	if got := flavor.resolve(auth, common.GetLogger()); got != FlavorDataCenter {
+		t.Fatalf("guessed flavor = %s, want datacenter", got)
+	}
+	info.mu.Lock()
+	info.deploymentType = "Cloud"
+	info.mu.Unlock()
+
+	if got := flavor.resolve(auth, common.GetLogger()); got != FlavorDataCenter || info.callCount() != 1 {
+		t.Errorf("within the retry window got %s after %d detections, want the cached guess", got, info.callCount())
+	}
+
+	flavor.mu.Lock()
+	flavor.retryAt = time.Now().Add(-time.Second)
+	flavor.mu.Unlock()
+	if got := flavor.resolve(auth, common.GetLogger()); got != FlavorCloud || info.callCount() != 2 {
+		t.Errorf("after the retry window got %s after %d detections, want cloud detected again", got, info.callCount())
+	}
+
+	// A detected flavor is kept for good
+	if got := flavor.resolve(auth, common.GetLogger()); got != FlavorCloud || info.callCount() != 2 {
+		t.Errorf("detected flavor not cached: %s after %d detections", got, info.callCount())
+	}
+}
+
+func TestDeploymentFlavorDetectsWithoutHoldingTheLock(t *testing.T) {
+	info := &serverInfo{deploymentType: "Cloud", release: make(chan struct{})}
+	server := httptest.NewServer(info)
+	t.Cleanup(server.Close)
+	auth := &testAuth{baseURL: server.URL, client: server.Client()}
+
+	var flavor deploymentFlavor
+	flavor.set(FlavorAuto)
+
+	detected := make(chan string)
+	go func() { detected <- flavor.resolve(auth, common.GetLogger()) }()
+
+	// Wait for the detection to start, then resolve again while serverInfo hangs
+	for {
+		flavor.mu.Lock()
+		detecting := flavor.detecting
+		flavor.mu.Unlock()
+		if detecting {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	resolved := make(chan string)
+	go func() { resolved <- flavor.resolve(auth, common.GetLogger()) }()
+	select {
+	case got := <-resolved:
+		if got != FlavorDataCenter {
+			t.Errorf("flavor during detection = %s, want the host guess", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("resolve waited for the pending detection")
+	}
+
+	close(info.release)
+	if got := <-detected; got != FlavorCloud {
+		t.Errorf("detected flavor = %s, want cloud", got)
+	}
+	if got := flavor.resolve(auth, common.GetLogger()); got != FlavorCloud || info.callCount() != 1 {
+		t.Errorf("flavor after detection = %s with %d detections", got, info.callCount())
+	}
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"aktis-parser/internal/interfaces"
+	bolt "go.etcd.io/bbolt"
+)
+
+// syncIssueActivity fetches comments and worklogs for the given issues and replaces the stored records
+func (s *JiraScraper) syncIssueActivity(issueKeys []string, includeComments, includeWorklogs bool) {
+	for _, issueKey := range issueKeys {
+		if includeComments {
+			comments, err := s.fetchIssueComments(issueKey)
+			if err != nil {
+				s.log.Warn().Err(err).Str("issue", issueKey).Msg("Failed to fetch issue comments")
+			} else if err := s.storeIssueChildren("issue_comments", issueKey, comments); err != nil {
+				s.log.Error().Err(err).Str("issue", issueKey).Msg("Failed to store issue comments")
+			}
+		}
+
+		if includeWorklogs {
+			worklogs, err := s.fetchIssueWorklogs(issueKey)
+			if err != nil {
+				s.log.Warn().Err(err).Str("issue", issueKey).Msg("Failed to fetch issue worklogs")
+			} else if err := s.storeIssueChildren("issue_worklogs", issueKey, worklogs); err != nil {
+				s.log.Error().Err(err).Str("issue", issueKey).Msg("Failed to store issue worklogs")
+			}
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// fetchIssueComments pages through /rest/api/3/issue/{key}/comment
+func (s *JiraScraper) fetchIssueComments(issueKey string) ([]map[string]interface{}, error) {
+	return s.fetchIssuePages(issueKey, "comment", "comments", 100)
+}
+
+// fetchIssueWorklogs pages through /rest/api/3/issue/{key}/worklog
+func (s *JiraScraper) fetchIssueWorklogs(issueKey string) ([]map[string]interface{}, error) {
+	return s.fetchIssuePages(issueKey, "worklog", "worklogs", 1000)
+}
+
+// fetchIssuePages pages through a startAt/total issue sub-resource and returns every entry
+func (s *JiraScraper) fetchIssuePages(issueKey, resource, field string, maxResults int) ([]map[string]interface{}, error) {
+	entries := []map[string]interface{}{}
+	startAt := 0
+
+	for {
+		path := fmt.Sprintf("/rest/api/3/issue/%s/%s?startAt=%d&maxResults=%d",
+			url.PathEscape(issueKey), resource, startAt, maxResults)
+
+		data, err := s.makeRequest("GET", path)
+		if err != nil {
+			return nil, err
+		}
+
+		var result map[string]interface{}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse %s response: %w", resource, err)
+		}
+
+		page := toMapSlice(result[field])
+		entries = append(entries, page...)
+		startAt += len(page)
+
+		total := 0
+		if t, ok := result["total"].(float64); ok {
+			total = int(t)
+		}
+
+		if len(page) == 0 || startAt >= total {
+			return entries, nil
+		}
+	}
+}
+
+// storeIssueChildren replaces every record of an issue in a bucket keyed by "<issueKey>/<id>"
+// Each record gains an issueKey field linking it back to its issue
+func (s *JiraScraper) storeIssueChildren(bucketName, issueKey string, records []map[string]interface{}) error {
+	var wikiFields []string
+	if s.dataCenter() {
+		wikiFields = activityWikiFields
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists([]byte(bucketName)); err != nil {
+			return err
+		}
+
+		// Records removed in Jira must not survive a resync
+		if err := deleteIssueChildren(tx, bucketName, issueKey); err != nil {
+			return err
+		}
+
+		bucket := tx.Bucket([]byte(bucketName))
+		for _, record := range records {
+			id, ok := record["id"].(string)
+			if !ok {
+				continue
+			}
+			record["issueKey"] = issueKey
+			if rendered := renderRichTextFields(record, wikiFields); len(rendered) > 0 {
+				record["rendered"] = rendered
+			}
+			value, err := json.Marshal(record)
+			if err != nil {
+				s.log.Warn().Str("issue", issueKey).Str("id", id).Err(err).Msg("Failed to marshal issue record")
+				continue
+			}
+			if err := bucket.Put([]byte(issueKey+"/"+id), value); err != nil {
+				return fmt.Errorf("failed to store %s record %s/%s: %w", bucketName, issueKey, id, err)
+			}
+		}
+		return nil
+	})
+}
+
+// readIssueChildren returns every record of an issue from a bucket keyed by "<issueKey>/<id>"
+func readIssueChildren(bucket *bolt.Bucket, issueKey string) []map[string]interface{} {
+	records := []map[string]interface{}{}
+	if bucket == nil {
+		return records
+	}
+
+	prefix := []byte(issueKey + "/")
+	c := bucket.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		var record map[string]interface{}
+		if err := json.Unmarshal(v, &record); err == nil {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// storeIssueAttachments downloads the attachments listed in each issue's attachment field
+func (s *JiraScraper) storeIssueAttachments(issues []map[string]interface{}) {
+	for _, issue := range issues {
+		issueKey, _ := issue["key"].(string)
+		fields, ok := issue["fields"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		for _, item := range toMapSlice(fields["attachment"]) {
+			id, _ := item["id"].(string)
+			content, _ := item["content"].(string)
+			if id == "" || content == "" {
+				continue
+			}
+
+			attachment := &interfaces.Attachment{
+				ID:          id,
+				Source:      "jira",
+				OwnerKey:    issueKey,
+				DownloadURL: content,
+			}
+			attachment.Filename, _ = item["filename"].(string)
+			attachment.MimeType, _ = item["mimeType"].(string)
+			if size, ok := item["size"].(float64); ok {
+				attachment.Size = int64(size)
+			}
+
+			if err := s.attachments.StoreAttachment(attachment); err != nil {
+				s.log.Warn().Err(err).Str("issue", issueKey).Str("attachment", id).Msg("Failed to store attachment")
+			}
+		}
+	}
+}
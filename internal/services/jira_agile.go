@@ -0,0 +1,352 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// agileBuckets lists the buckets rebuilt on every board scrape
+var agileBuckets = []string{
+	"boards",
+	"sprints",
+	"issue_sprints",
+	"epics",
+}
+
+// ScrapeBoards scrapes Agile boards with their sprints, sprint issues and epics
+// Every scrape replaces the stored boards, sprints, sprint membership and epics, so any failed fetch
+// fails the scrape and leaves the stored records as they were
+func (s *JiraScraper) ScrapeBoards() error {
+	s.log.Info().Msg("Scraping Agile boards...")
+	if s.uiLog != nil {
+		s.uiLog.BroadcastUILog("info", "Fetching Agile boards from Jira...")
+	}
+
+	boards, err := s.fetchAgileValues("/rest/agile/1.0/board", nil, 50)
+	if err != nil {
+		return fmt.Errorf("failed to fetch boards: %w", err)
+	}
+
+	s.log.Info().Msgf("Found %d boards", len(boards))
+
+	// A sprint holding issues from several boards is listed by each of them
+	sprints := make(map[string]map[string]interface{})
+	sprintIDs := []string{}
+	epics := make(map[string]map[string]interface{})
+
+	for _, board := range boards {
+		boardID := agileID(board["id"])
+		if boardID == "" {
+			continue
+		}
+		boardType, _ := board["type"].(string)
+
+		// Only scrum boards have sprints, kanban boards answer with 400
+		if boardType == "scrum" {
+			boardSprints, err := s.fetchAgileValues(fmt.Sprintf("/rest/agile/1.0/board/%s/sprint", boardID), nil, 50)
+			if err != nil {
+				return fmt.Errorf("failed to fetch sprints of board %s: %w", boardID, err)
+			}
+
+			for _, sprint := range boardSprints {
+				sprintID := agileID(sprint["id"])
+				if sprintID == "" {
+					continue
+				}
+				if existing, ok := sprints[sprintID]; ok {
+					existing["boardIds"] = append(existing["boardIds"].([]string), boardID)
+					continue
+				}
+				sprint["boardIds"] = []string{boardID}
+				sprints[sprintID] = sprint
+				sprintIDs = append(sprintIDs, sprintID)
+			}
+		}
+
+		// Team-managed boards without an epic panel answer with 400
+		boardEpics, err := s.fetchAgileValues(fmt.Sprintf("/rest/agile/1.0/board/%s/epic", boardID), nil, 50)
+		if isBadRequest(err) {
+			s.log.Debug().Err(err).Str("board", boardID).Msg("Board does not support epics")
+		} else if err != nil {
+			return fmt.Errorf("failed to fetch epics of board %s: %w", boardID, err)
+		}
+		for _, epic := range boardEpics {
+			epicKey, ok := epic["key"].(string)
+			if !ok {
+				continue
+			}
+			if existing, ok := epics[epicKey]; ok {
+				existing["boardIds"] = append(existing["boardIds"].([]string), boardID)
+				continue
+			}
+			epic["boardIds"] = []string{boardID}
+			epics[epicKey] = epic
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// Sprint membership is fetched once per sprint, after every board listing it is known
+	issueSprints := []map[string]interface{}{}
+	for _, sprintID := range sprintIDs {
+		members, err := s.fetchSprintIssues(sprints[sprintID])
+		if err != nil {
+			return fmt.Errorf("failed to fetch issues of sprint %s: %w", sprintID, err)
+		}
+		issueSprints = append(issueSprints, members...)
+	}
+
+	// Record the children of each epic to build the epic → issue hierarchy
+	for epicKey, epic := range epics {
+		children, err := s.fetchAgileValues(fmt.Sprintf("/rest/agile/1.0/epic/%s/issue", url.PathEscape(epicKey)),
+			url.Values{"fields": {"issuetype,parent"}}, 100)
+		if err != nil {
+			return fmt.Errorf("failed to fetch issues of epic %s: %w", epicKey, err)
+		}
+
+		issueKeys := make([]string, 0, len(children))
+		for _, child := range children {
+			if key, ok := child["key"].(string); ok {
+				issueKeys = append(issueKeys, key)
+			}
+		}
+		epic["issueKeys"] = issueKeys
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range agileBuckets {
+			if err := tx.DeleteBucket([]byte(name)); err != nil && err != bolt.ErrBucketNotFound {
+				return fmt.Errorf("failed to clear %s bucket: %w", name, err)
+			}
+			if _, err := tx.CreateBucket([]byte(name)); err != nil {
+				return fmt.Errorf("failed to recreate %s bucket: %w", name, err)
+			}
+		}
+
+		if err := putRecords(tx.Bucket([]byte("boards")), boards, func(r map[string]interface{}) string {
+			return agileID(r["id"])
+		}); err != nil {
+			return err
+		}
+		sprintBucket := tx.Bucket([]byte("sprints"))
+		for sprintID, sprint := range sprints {
+			value, err := json.Marshal(sprint)
+			if err != nil {
+				continue
+			}
+			if err := sprintBucket.Put([]byte(sprintID), value); err != nil {
+				return err
+			}
+		}
+		if err := putRecords(tx.Bucket([]byte("issue_sprints")), issueSprints, func(r map[string]interface{}) string {
+			issueKey, _ := r["issueKey"].(string)
+			if issueKey == "" {
+				return ""
+			}
+			return issueKey + "/" + agileID(r["sprintId"])
+		}); err != nil {
+			return err
+		}
+
+		epicBucket := tx.Bucket([]byte("epics"))
+		for epicKey, epic := range epics {
+			value, err := json.Marshal(epic)
+			if err != nil {
+				continue
+			}
+			if err := epicBucket.Put([]byte(epicKey), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store boards: %w", err)
+	}
+
+	s.log.Info().
+		Int("boards", len(boards)).
+		Int("sprints", len(sprints)).
+		Int("epics", len(epics)).
+		Msg("Stored Agile boards")
+	if s.uiLog != nil {
+		s.uiLog.BroadcastUILog("info", fmt.Sprintf("Stored %d boards, %d sprints and %d epics", len(boards), len(sprints), len(epics)))
+	}
+
+	return nil
+}
+
+// fetchSprintIssues returns one issue_sprints record per issue in a sprint
+func (s *JiraScraper) fetchSprintIssues(sprint map[string]interface{}) ([]map[string]interface{}, error) {
+	sprintID := agileID(sprint["id"])
+
+	issues, err := s.fetchAgileValues(fmt.Sprintf("/rest/agile/1.0/sprint/%s/issue", sprintID),
+		url.Values{"fields": {"issuetype,parent,epic"}}, 100)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]map[string]interface{}, 0, len(issues))
+	for _, issue := range issues {
+		issueKey, ok := issue["key"].(string)
+		if !ok {
+			continue
+		}
+
+		record := map[string]interface{}{
+			"issueKey":    issueKey,
+			"sprintId":    sprintID,
+			"sprintName":  sprint["name"],
+			"sprintState": sprint["state"],
+			"boardIds":    sprint["boardIds"],
+		}
+		if fields, ok := issue["fields"].(map[string]interface{}); ok {
+			if parent, ok := fields["parent"].(map[string]interface{}); ok {
+				record["parentKey"] = parent["key"]
+			}
+			if epic, ok := fields["epic"].(map[string]interface{}); ok {
+				record["epicKey"] = epic["key"]
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// fetchAgileValues pages through an Agile API list resource and returns every value
+// The Agile API lists issues under "issues" and everything else under "values"
+func (s *JiraScraper) fetchAgileValues(path string, query url.Values, maxResults int) ([]map[string]interface{}, error) {
+	values := []map[string]interface{}{}
+	startAt := 0
+
+	if query == nil {
+		query = url.Values{}
+	}
+
+	for {
+		query.Set("startAt", strconv.Itoa(startAt))
+		query.Set("maxResults", strconv.Itoa(maxResults))
+
+		data, err := s.makeRequest("GET", path+"?"+query.Encode())
+		if err != nil {
+			return values, err
+		}
+
+		var result struct {
+			Values []map[string]interface{} `json:"values"`
+			Issues []map[string]interface{} `json:"issues"`
+			Total  int                      `json:"total"`
+			IsLast *bool                    `json:"isLast"`
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return values, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+
+		page := result.Values
+		if page == nil {
+			page = result.Issues
+		}
+		values = append(values, page...)
+		startAt += len(page)
+
+		if len(page) == 0 {
+			return values, nil
+		}
+		if result.IsLast != nil {
+			if *result.IsLast {
+				return values, nil
+			}
+		} else if startAt >= result.Total {
+			return values, nil
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// GetBoards returns all stored Agile boards
+func (s *JiraScraper) GetBoards() ([]map[string]interface{}, error) {
+	boards := []map[string]interface{}{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("boards"))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var board map[string]interface{}
+			if err := json.Unmarshal(v, &board); err == nil {
+				boards = append(boards, board)
+			}
+			return nil
+		})
+	})
+	return boards, err
+}
+
+// GetBoardSprints returns the stored sprints of a board
+func (s *JiraScraper) GetBoardSprints(boardID string) ([]map[string]interface{}, error) {
+	sprints := []map[string]interface{}{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("sprints"))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var sprint map[string]interface{}
+			if err := json.Unmarshal(v, &sprint); err != nil {
+				return nil
+			}
+			boardIDs, _ := sprint["boardIds"].([]interface{})
+			for _, id := range boardIDs {
+				if id == boardID {
+					sprints = append(sprints, sprint)
+					break
+				}
+			}
+			return nil
+		})
+	})
+	return sprints, err
+}
+
+// putRecords stores records in a bucket under the key returned by keyFn, skipping records without a key
+func putRecords(bucket *bolt.Bucket, records []map[string]interface{}, keyFn func(map[string]interface{}) string) error {
+	for _, record := range records {
+		key := keyFn(record)
+		if key == "" {
+			continue
+		}
+		value, err := json.Marshal(record)
+		if err != nil {
+			continue
+		}
+		if err := bucket.Put([]byte(key), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isBadRequest reports whether a request failed with 400, which the Agile API returns for unsupported resources
+func isBadRequest(err error) bool {
+	var statusErr *httpStatusError
+	return errors.As(err, &statusErr) && statusErr.Status == http.StatusBadRequest
+}
+
+// agileID converts the numeric ids of the Agile API into strings
+func agileID(value interface{}) string {
+	switch id := value.(type) {
+	case float64:
+		return strconv.FormatInt(int64(id), 10)
+	case string:
+		return id
+	}
+	return ""
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// fakeAgile serves two scrum boards sharing a sprint and a kanban board without epics
+type fakeAgile struct {
+	mu          sync.Mutex
+	failSprints bool
+}
+
+func (f *fakeAgile) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	values := func(values ...map[string]interface{}) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"values": values, "isLast": true})
+	}
+	switch r.URL.Path {
+	case "/rest/agile/1.0/board":
+		values(
+			map[string]interface{}{"id": 1, "type": "scrum"},
+			map[string]interface{}{"id": 2, "type": "scrum"},
+			map[string]interface{}{"id": 3, "type": "kanban"},
+		)
+	case "/rest/agile/1.0/board/1/sprint":
+		values(map[string]interface{}{"id": 10, "name": "Shared"}, map[string]interface{}{"id": 11, "name": "Own"})
+	case "/rest/agile/1.0/board/2/sprint":
+		if f.failSprints {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		values(map[string]interface{}{"id": 10, "name": "Shared"})
+	case "/rest/agile/1.0/board/1/epic", "/rest/agile/1.0/board/2/epic":
+		values(map[string]interface{}{"key": "P-100"})
+	case "/rest/agile/1.0/board/3/epic":
+		http.Error(w, "epics not supported", http.StatusBadRequest)
+	case "/rest/agile/1.0/sprint/10/issue", "/rest/agile/1.0/sprint/11/issue":
+		json.NewEncoder(w).Encode(map[string]interface{}{"issues": []map[string]interface{}{{"key": "P-1"}}, "isLast": true})
+	case "/rest/agile/1.0/epic/P-100/issue":
+		json.NewEncoder(w).Encode(map[string]interface{}{"issues": []map[string]interface{}{{"key": "P-1"}}, "isLast": true})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// sprintIDs returns the ids of the stored sprints of a board
+func sprintIDs(t *testing.T, scraper *JiraScraper, boardID string) map[string]bool {
+	t.Helper()
+	sprints, err := scraper.GetBoardSprints(boardID)
+	if err != nil {
+		t.Fatalf("GetBoardSprints(%s): %v", boardID, err)
+	}
+	ids := make(map[string]bool)
+	for _, sprint := range sprints {
+		ids[agileID(sprint["id"])] = true
+	}
+	return ids
+}
+
+func TestScrapeBoardsSharedSprints(t *testing.T) {
+	agile := &fakeAgile{}
+	scraper := newTestJiraScraper(t, agile)
+
+	if err := scraper.ScrapeBoards(); err != nil {
+		t.Fatalf("ScrapeBoards: %v", err)
+	}
+	if ids := sprintIDs(t, scraper, "1"); !ids["10"] || !ids["11"] || len(ids) != 2 {
+		t.Errorf("board 1 sprints = %v, want 10 and 11", ids)
+	}
+	if ids := sprintIDs(t, scraper, "2"); !ids["10"] || len(ids) != 1 {
+		t.Errorf("board 2 sprints = %v, want the shared sprint 10", ids)
+	}
+
+	// The shared sprint's issues were fetched once and carry both boards
+	var membership struct {
+		BoardIDs []string `json:"boardIds"`
+	}
+	scraper.GetDB().View(func(tx *bolt.Tx) error {
+		return json.Unmarshal(tx.Bucket([]byte("issue_sprints")).Get([]byte("P-1/10")), &membership)
+	})
+	if len(membership.BoardIDs) != 2 {
+		t.Errorf("P-1 in sprint 10 lists boards %v, want 1 and 2", membership.BoardIDs)
+	}
+
+	// A failing board leaves every stored record in place
+	agile.failSprints = true
+	if err := scraper.ScrapeBoards(); err == nil {
+		t.Fatal("ScrapeBoards succeeded with a failing board")
+	}
+	if ids := sprintIDs(t, scraper, "2"); !ids["10"] {
+		t.Errorf("board 2 sprints after a failed scrape = %v, want 10 kept", ids)
+	}
+	if boards, _ := scraper.GetBoards(); len(boards) != 3 {
+		t.Errorf("boards after a failed scrape = %d, want 3", len(boards))
+	}
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"aktis-parser/internal/converters"
+)
+
+// jiraFieldPresets maps preset names to the fields they request
+// Entries that are not field ids are resolved by name through /rest/api/3/field,
+// and preset entries that do not exist on the instance are skipped silently
+var jiraFieldPresets = map[string][]string{
+	"minimal": {
+		"summary", "status", "issuetype", "project",
+	},
+	"standard": {
+		"summary", "status", "issuetype", "project",
+		"description", "assignee", "reporter", "creator", "labels", "priority",
+		"created", "updated", "resolution", "resolutiondate", "duedate",
+		"components", "fixVersions", "parent", "issuelinks", "subtasks", "attachment",
+		"Sprint", "Story Points", "Story point estimate",
+	},
+	"all": {
+		"*all",
+	},
+}
+
+// JiraField describes a field from the /rest/api/3/field metadata
+type JiraField struct {
+	ID     string `json:"id"`
+	Key    string `json:"key"`
+	Name   string `json:"name"`
+	Custom bool   `json:"custom"`
+	Schema struct {
+		Type   string `json:"type"`
+		Custom string `json:"custom"`
+	} `json:"schema"`
+}
+
+// wikiTextFields are the system fields Server and Data Center return as wiki markup
+var wikiTextFields = []string{"description", "environment"}
+
+// activityWikiFields are the wiki markup fields of comments ("body") and worklogs ("comment")
+var activityWikiFields = []string{"body", "comment"}
+
+// textareaFieldType is the schema of multi-line custom fields, which hold wiki markup on Server and Data Center
+const textareaFieldType = "com.atlassian.jira.plugin.system.customfieldtypes:textarea"
+
+// getFieldMetadata returns the Jira field metadata, fetching it once per base URL
+func (s *JiraScraper) getFieldMetadata() ([]JiraField, error) {
+	baseURL := s.authService.GetBaseURL()
+
+	s.fieldsMu.Lock()
+	defer s.fieldsMu.Unlock()
+
+	if s.fieldMetadata != nil && s.fieldMetadataURL == baseURL {
+		return s.fieldMetadata, nil
+	}
+
+	data, err := s.makeRequest("GET", "/rest/api/3/field")
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []JiraField
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to parse field metadata: %w", err)
+	}
+
+	s.fieldMetadata = fields
+	s.fieldMetadataURL = baseURL
+
+	s.log.Debug().Int("fields", len(fields)).Msg("Loaded Jira field metadata")
+	return fields, nil
+}
+
+// resolveFields turns a field selection (presets, ids or names) into the field ids
+// passed to the search API. The configured selection is used when none is given.
+func (s *JiraScraper) resolveFields(selection []string) string {
+	if len(selection) == 0 && s.config != nil {
+		selection = s.config.Fields
+	}
+	if len(selection) == 0 {
+		selection = []string{"standard"}
+	}
+
+	var metadata []JiraField
+	needsMetadata := false
+
+	resolved := []string{}
+	seen := make(map[string]bool)
+	add := func(id string) {
+		if !seen[id] {
+			seen[id] = true
+			resolved = append(resolved, id)
+		}
+	}
+
+	for _, entry := range selection {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		names := []string{entry}
+		fromPreset := false
+		if preset, ok := jiraFieldPresets[strings.ToLower(entry)]; ok {
+			names = preset
+			fromPreset = true
+		}
+
+		for _, name := range names {
+			// Wildcards and exclusions are passed straight through to the API
+			if strings.HasPrefix(name, "*") || strings.HasPrefix(name, "-") {
+				add(name)
+				continue
+			}
+
+			if !needsMetadata {
+				needsMetadata = true
+				fields, err := s.getFieldMetadata()
+				if err != nil {
+					s.log.Warn().Err(err).Msg("Failed to load field metadata, using field selection as given")
+				}
+				metadata = fields
+			}
+
+			if id, ok := lookupFieldID(metadata, name); ok {
+				add(id)
+			} else if metadata == nil && !strings.Contains(name, " ") {
+				// Without metadata only entries that look like ids can be used
+				add(name)
+			} else if !fromPreset {
+				s.log.Warn().Str("field", name).Msg("Unknown Jira field in selection, skipping")
+			}
+		}
+	}
+
+	// The project field is required to group stored issues by project
+	if !seen["*all"] && !seen["project"] {
+		add("project")
+	}
+
+	return strings.Join(resolved, ",")
+}
+
+// lookupFieldID finds a field by id or key first, then by case-insensitive name
+func lookupFieldID(metadata []JiraField, name string) (string, bool) {
+	for _, field := range metadata {
+		if field.ID == name || field.Key == name {
+			return field.ID, true
+		}
+	}
+	for _, field := range metadata {
+		if strings.EqualFold(field.Name, name) {
+			return field.ID, true
+		}
+	}
+	return "", false
+}
+
+// wikiFieldIDs returns the issue fields holding wiki markup on Server and Data Center, or nil on Cloud
+// where rich text is ADF and is found by its shape
+func (s *JiraScraper) wikiFieldIDs() []string {
+	if !s.dataCenter() {
+		return nil
+	}
+
+	fields := append([]string(nil), wikiTextFields...)
+	metadata, err := s.getFieldMetadata()
+	if err != nil {
+		s.log.Warn().Err(err).Msg("Failed to load field metadata, rendering only system text fields")
+		return fields
+	}
+	for _, field := range metadata {
+		if field.Schema.Custom == textareaFieldType {
+			fields = append(fields, field.ID)
+		}
+	}
+	return fields
+}
+
+// renderRichTextFields renders the rich text of a record as Markdown and plain text
+// With no wiki fields every ADF valued entry is rendered (Cloud); otherwise the named wiki markup
+// strings are (Server and Data Center)
+func renderRichTextFields(record map[string]interface{}, wikiFields []string) map[string]interface{} {
+	if wikiFields == nil {
+		return renderADFFields(record)
+	}
+
+	rendered := make(map[string]interface{})
+	for _, key := range wikiFields {
+		markup, ok := record[key].(string)
+		if !ok || markup == "" {
+			continue
+		}
+		rendered[key] = map[string]string{
+			"markdown": converters.WikiToMarkdown(markup),
+			"text":     converters.WikiToText(markup),
+		}
+	}
+	return rendered
+}
+
+// renderADFFields renders every ADF valued entry of a record as Markdown and plain text
+// The result is keyed like the record and stored as "rendered" next to the raw ADF
+func renderADFFields(record map[string]interface{}) map[string]interface{} {
+	rendered := make(map[string]interface{})
+	for key, value := range record {
+		if !converters.IsADF(value) {
+			continue
+		}
+		rendered[key] = map[string]string{
+			"markdown": converters.ADFToMarkdown(value),
+			"text":     converters.ADFToText(value),
+		}
+	}
+	return rendered
+}
@@ -0,0 +1,392 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"aktis-parser/internal/interfaces"
+	bolt "go.etcd.io/bbolt"
+)
+
+// parentLinkType is the link type used for parent/subtask relations in the issue_links bucket
+const parentLinkType = "Parent"
+
+// blocksLinkType is the name of Jira's built-in blocking link type
+const blocksLinkType = "Blocks"
+
+// maxGraphDepth caps graph traversal so a single request cannot walk the whole instance
+const maxGraphDepth = 5
+
+// storeIssueLinks records the issuelinks, subtasks and parent of an issue in the issue_links bucket
+// Issues fetched without any of those fields keep their previously stored links
+func storeIssueLinks(tx *bolt.Tx, issue map[string]interface{}) error {
+	issueKey, _ := issue["key"].(string)
+	fields, ok := issue["fields"].(map[string]interface{})
+	if issueKey == "" || !ok {
+		return nil
+	}
+
+	_, hasLinks := fields["issuelinks"]
+	_, hasSubtasks := fields["subtasks"]
+	_, hasParent := fields["parent"]
+	if !hasLinks && !hasSubtasks && !hasParent {
+		return nil
+	}
+
+	bucket, err := tx.CreateBucketIfNotExists([]byte("issue_links"))
+	if err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(extractIssueLinks(issueKey, fields))
+	if err != nil {
+		return fmt.Errorf("failed to marshal links for %s: %w", issueKey, err)
+	}
+	if err := bucket.Put([]byte(issueKey), value); err != nil {
+		return fmt.Errorf("failed to store links for %s: %w", issueKey, err)
+	}
+	return nil
+}
+
+// extractIssueLinks converts an issue's link fields into edges pointing from the outward to the inward issue
+func extractIssueLinks(issueKey string, fields map[string]interface{}) []interfaces.IssueLink {
+	links := []interfaces.IssueLink{}
+
+	for _, link := range toMapSlice(fields["issuelinks"]) {
+		linkType, _ := link["type"].(map[string]interface{})
+		edge := interfaces.IssueLink{}
+		edge.Type, _ = linkType["name"].(string)
+		edge.Outward, _ = linkType["outward"].(string)
+		edge.Inward, _ = linkType["inward"].(string)
+
+		if outward, ok := link["outwardIssue"].(map[string]interface{}); ok {
+			edge.From = issueKey
+			edge.To, _ = outward["key"].(string)
+		} else if inward, ok := link["inwardIssue"].(map[string]interface{}); ok {
+			edge.From, _ = inward["key"].(string)
+			edge.To = issueKey
+		}
+
+		if edge.From != "" && edge.To != "" {
+			links = append(links, edge)
+		}
+	}
+
+	if parent, ok := fields["parent"].(map[string]interface{}); ok {
+		if parentKey, ok := parent["key"].(string); ok {
+			links = append(links, parentLink(parentKey, issueKey))
+		}
+	}
+
+	for _, subtask := range toMapSlice(fields["subtasks"]) {
+		if subtaskKey, ok := subtask["key"].(string); ok {
+			links = append(links, parentLink(issueKey, subtaskKey))
+		}
+	}
+
+	return links
+}
+
+// parentLink returns the edge between a parent and one of its children
+func parentLink(parentKey, childKey string) interfaces.IssueLink {
+	return interfaces.IssueLink{
+		Type:    parentLinkType,
+		From:    parentKey,
+		To:      childKey,
+		Outward: "is parent of",
+		Inward:  "is child of",
+	}
+}
+
+// issueLinkGraph is the in-memory adjacency index built from the issue_links bucket
+// Every edge is listed under both of its issues and appears only once even though Jira reports it on both sides
+type issueLinkGraph map[string][]interfaces.IssueLink
+
+// loadIssueLinkGraph reads every stored edge into an adjacency index
+func loadIssueLinkGraph(tx *bolt.Tx) issueLinkGraph {
+	graph := make(issueLinkGraph)
+	bucket := tx.Bucket([]byte("issue_links"))
+	if bucket == nil {
+		return graph
+	}
+
+	seen := make(map[string]bool)
+	bucket.ForEach(func(k, v []byte) error {
+		var links []interfaces.IssueLink
+		if err := json.Unmarshal(v, &links); err != nil {
+			return nil
+		}
+		for _, link := range links {
+			id := link.Type + "|" + link.From + "|" + link.To
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			graph[link.From] = append(graph[link.From], link)
+			graph[link.To] = append(graph[link.To], link)
+		}
+		return nil
+	})
+	return graph
+}
+
+// GetIssueGraph returns the link neighborhood of an issue up to the given depth, or nil if the issue is unknown
+// linkTypes filters edges by link type name or description ("Blocks", "is cloned by"); empty means every type
+func (s *JiraScraper) GetIssueGraph(issueKey string, depth int, linkTypes []string) (*interfaces.IssueGraph, error) {
+	if depth < 1 {
+		depth = 1
+	}
+	if depth > maxGraphDepth {
+		depth = maxGraphDepth
+	}
+
+	var graph *interfaces.IssueGraph
+	err := s.db.View(func(tx *bolt.Tx) error {
+		issues := tx.Bucket([]byte("issues"))
+		adjacency := loadIssueLinkGraph(tx)
+		if !issueKnown(issues, adjacency, issueKey) {
+			return nil
+		}
+
+		depths := map[string]int{issueKey: 0}
+		queue := []string{issueKey}
+		links := []interfaces.IssueLink{}
+		seenLinks := make(map[string]bool)
+
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			if depths[current] >= depth {
+				continue
+			}
+
+			for _, link := range adjacency[current] {
+				if !matchesLinkType(link, linkTypes) {
+					continue
+				}
+
+				id := link.Type + "|" + link.From + "|" + link.To
+				if !seenLinks[id] {
+					seenLinks[id] = true
+					links = append(links, link)
+				}
+
+				neighbor := link.To
+				if neighbor == current {
+					neighbor = link.From
+				}
+				if _, visited := depths[neighbor]; !visited {
+					depths[neighbor] = depths[current] + 1
+					queue = append(queue, neighbor)
+				}
+			}
+		}
+
+		graph = &interfaces.IssueGraph{
+			Root:  issueKey,
+			Depth: depth,
+			Nodes: graphNodes(issues, depths),
+			Links: links,
+		}
+		return nil
+	})
+	return graph, err
+}
+
+// GetBlockedByChain returns everything blocking an issue or its children, in dependency order
+// Children are found through parent links, so asking about an epic also covers its stories and subtasks
+func (s *JiraScraper) GetBlockedByChain(issueKey string) (*interfaces.BlockedByChain, error) {
+	var chain *interfaces.BlockedByChain
+	err := s.db.View(func(tx *bolt.Tx) error {
+		issues := tx.Bucket([]byte("issues"))
+		adjacency := loadIssueLinkGraph(tx)
+		if !issueKnown(issues, adjacency, issueKey) {
+			return nil
+		}
+
+		depths := map[string]int{issueKey: 0}
+		links := []interfaces.IssueLink{}
+		blocks := []interfaces.IssueLink{}
+
+		// Collect the issue and all of its descendants
+		queue := []string{issueKey}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			for _, link := range adjacency[current] {
+				if link.Type != parentLinkType || link.From != current {
+					continue
+				}
+				if _, visited := depths[link.To]; !visited {
+					depths[link.To] = depths[current] + 1
+					queue = append(queue, link.To)
+					links = append(links, link)
+				}
+			}
+		}
+
+		// Follow "is blocked by" edges transitively from every collected issue
+		for key := range depths {
+			queue = append(queue, key)
+		}
+		sort.Strings(queue)
+		seenBlocks := make(map[string]bool)
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			for _, link := range adjacency[current] {
+				if !strings.EqualFold(link.Type, blocksLinkType) || link.To != current {
+					continue
+				}
+				id := link.From + "|" + link.To
+				if seenBlocks[id] {
+					continue
+				}
+				seenBlocks[id] = true
+				blocks = append(blocks, link)
+
+				if _, visited := depths[link.From]; !visited {
+					depths[link.From] = depths[current] + 1
+					queue = append(queue, link.From)
+				}
+			}
+		}
+
+		order, cycle := topologicalOrder(depths, blocks)
+
+		nodes := make([]interfaces.IssueGraphNode, 0, len(order))
+		for _, key := range order {
+			nodes = append(nodes, graphNode(issues, key, depths[key]))
+		}
+
+		chain = &interfaces.BlockedByChain{
+			Root:  issueKey,
+			Order: nodes,
+			Links: append(links, blocks...),
+			Cycle: cycle,
+		}
+		return nil
+	})
+	return chain, err
+}
+
+// topologicalOrder orders issues so every blocker comes before the issues it blocks
+// Issues caught in a blocking cycle cannot be ordered and are returned separately
+func topologicalOrder(nodes map[string]int, blocks []interfaces.IssueLink) ([]string, []string) {
+	inDegree := make(map[string]int, len(nodes))
+	blocked := make(map[string][]string)
+	for key := range nodes {
+		inDegree[key] = 0
+	}
+	for _, link := range blocks {
+		inDegree[link.To]++
+		blocked[link.From] = append(blocked[link.From], link.To)
+	}
+
+	ready := []string{}
+	for key, degree := range inDegree {
+		if degree == 0 {
+			ready = append(ready, key)
+		}
+	}
+	sort.Strings(ready)
+
+	order := make([]string, 0, len(nodes))
+	for len(ready) > 0 {
+		current := ready[0]
+		ready = ready[1:]
+		order = append(order, current)
+
+		next := []string{}
+		for _, key := range blocked[current] {
+			inDegree[key]--
+			if inDegree[key] == 0 {
+				next = append(next, key)
+			}
+		}
+		sort.Strings(next)
+		ready = append(ready, next...)
+	}
+
+	cycle := []string{}
+	for key, degree := range inDegree {
+		if degree > 0 {
+			cycle = append(cycle, key)
+		}
+	}
+	sort.Strings(cycle)
+
+	return order, cycle
+}
+
+// issueKnown reports whether an issue is stored or appears in any stored link
+func issueKnown(issues *bolt.Bucket, adjacency issueLinkGraph, issueKey string) bool {
+	if _, ok := adjacency[issueKey]; ok {
+		return true
+	}
+	return issues != nil && issues.Get([]byte(issueKey)) != nil
+}
+
+// matchesLinkType checks a link against a filter of type names or descriptions
+func matchesLinkType(link interfaces.IssueLink, linkTypes []string) bool {
+	if len(linkTypes) == 0 {
+		return true
+	}
+	for _, linkType := range linkTypes {
+		if strings.EqualFold(linkType, link.Type) ||
+			strings.EqualFold(linkType, link.Outward) ||
+			strings.EqualFold(linkType, link.Inward) {
+			return true
+		}
+	}
+	return false
+}
+
+// graphNodes builds the nodes of a graph response, ordered by depth then key
+func graphNodes(issues *bolt.Bucket, depths map[string]int) []interfaces.IssueGraphNode {
+	nodes := make([]interfaces.IssueGraphNode, 0, len(depths))
+	for key, depth := range depths {
+		nodes = append(nodes, graphNode(issues, key, depth))
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Depth != nodes[j].Depth {
+			return nodes[i].Depth < nodes[j].Depth
+		}
+		return nodes[i].Key < nodes[j].Key
+	})
+	return nodes
+}
+
+// graphNode describes an issue from its stored record; issues outside the synced projects only carry their key
+func graphNode(issues *bolt.Bucket, issueKey string, depth int) interfaces.IssueGraphNode {
+	node := interfaces.IssueGraphNode{Key: issueKey, Depth: depth}
+	if issues == nil {
+		return node
+	}
+
+	data := issues.Get([]byte(issueKey))
+	if data == nil {
+		return node
+	}
+
+	var issue map[string]interface{}
+	if err := json.Unmarshal(data, &issue); err != nil {
+		return node
+	}
+	node.Stored = true
+
+	fields, _ := issue["fields"].(map[string]interface{})
+	node.Summary, _ = fields["summary"].(string)
+	if status, ok := fields["status"].(map[string]interface{}); ok {
+		node.Status, _ = status["name"].(string)
+		if category, ok := status["statusCategory"].(map[string]interface{}); ok {
+			node.StatusCategory, _ = category["key"].(string)
+		}
+	}
+	if issueType, ok := fields["issuetype"].(map[string]interface{}); ok {
+		node.IssueType, _ = issueType["name"].(string)
+	}
+	return node
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// IssueHistory is the changelog of a single issue as stored in the issue_history bucket
+type IssueHistory struct {
+	IssueKey  string                   `json:"issueKey"`
+	Histories []map[string]interface{} `json:"histories"`
+	FetchedAt time.Time                `json:"fetchedAt"`
+}
+
+// storeIssueChangelogs stores the changelogs returned with expand=changelog
+// The search API truncates long changelogs, so the remainder is paged from /issue/{key}/changelog
+func (s *JiraScraper) storeIssueChangelogs(changelogs map[string]map[string]interface{}) {
+	histories := make(map[string][]map[string]interface{}, len(changelogs))
+
+	for issueKey, changelog := range changelogs {
+		entries := toMapSlice(changelog["histories"])
+
+		total := len(entries)
+		if t, ok := changelog["total"].(float64); ok {
+			total = int(t)
+		}
+
+		if len(entries) < total {
+			all, err := s.fetchIssueChangelog(issueKey)
+			if err != nil {
+				s.log.Warn().Err(err).Str("issue", issueKey).Msg("Failed to page issue changelog, storing partial history")
+			} else {
+				entries = all
+			}
+		}
+
+		histories[issueKey] = entries
+	}
+
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte("issue_history"))
+		if err != nil {
+			return err
+		}
+		for issueKey, entries := range histories {
+			value, err := json.Marshal(IssueHistory{
+				IssueKey:  issueKey,
+				Histories: entries,
+				FetchedAt: time.Now(),
+			})
+			if err != nil {
+				s.log.Warn().Str("issue", issueKey).Err(err).Msg("Failed to marshal issue history")
+				continue
+			}
+			if err := bucket.Put([]byte(issueKey), value); err != nil {
+				return fmt.Errorf("failed to store history for %s: %w", issueKey, err)
+			}
+		}
+		return nil
+	}); err != nil {
+		s.log.Error().Err(err).Msg("Failed to store issue histories")
+		return
+	}
+
+	s.log.Debug().Int("issues", len(histories)).Msg("Stored issue histories")
+}
+
+// fetchIssueChangelog pages through /rest/api/3/issue/{key}/changelog and returns every history entry
+// Server and Data Center have no changelog resource but return the whole changelog when expanded
+func (s *JiraScraper) fetchIssueChangelog(issueKey string) ([]map[string]interface{}, error) {
+	if s.dataCenter() {
+		data, err := s.makeRequest("GET", fmt.Sprintf("/rest/api/2/issue/%s?expand=changelog&fields=summary", url.PathEscape(issueKey)))
+		if err != nil {
+			return nil, err
+		}
+		var issue struct {
+			Changelog struct {
+				Histories []map[string]interface{} `json:"histories"`
+			} `json:"changelog"`
+		}
+		if err := json.Unmarshal(data, &issue); err != nil {
+			return nil, fmt.Errorf("failed to parse changelog: %w", err)
+		}
+		return issue.Changelog.Histories, nil
+	}
+
+	entries := []map[string]interface{}{}
+	startAt := 0
+	maxResults := 100
+
+	for {
+		path := fmt.Sprintf("/rest/api/3/issue/%s/changelog?startAt=%d&maxResults=%d",
+			url.PathEscape(issueKey), startAt, maxResults)
+
+		data, err := s.makeRequest("GET", path)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Values []map[string]interface{} `json:"values"`
+			Total  int                      `json:"total"`
+			IsLast bool                     `json:"isLast"`
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse changelog: %w", err)
+		}
+
+		entries = append(entries, result.Values...)
+		startAt += len(result.Values)
+
+		if result.IsLast || len(result.Values) == 0 || startAt >= result.Total {
+			return entries, nil
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// GetIssueHistory returns the stored changelog for an issue, or nil if none is stored
+func (s *JiraScraper) GetIssueHistory(issueKey string) (map[string]interface{}, error) {
+	var history map[string]interface{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("issue_history"))
+		if bucket == nil {
+			return nil
+		}
+		data := bucket.Get([]byte(issueKey))
+		if data == nil {
+			return nil
+		}
+		return json.Unmarshal(data, &history)
+	})
+	return history, err
+}
+
+// toMapSlice converts a decoded JSON array into a slice of objects, skipping other values
+func toMapSlice(value interface{}) []map[string]interface{} {
+	items, _ := value.([]interface{})
+	result := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			result = append(result, m)
+		}
+	}
+	return result
+}
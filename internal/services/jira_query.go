@@ -0,0 +1,21 @@
+package services
+
+import (
+	"aktis-parser/internal/interfaces"
+)
+
+// RunJQLQuery stores every issue matching a JQL query and tags them with the query name
+// Issues may come from any project; issues tagged by an earlier run that no longer match lose the tag
+func (s *JiraScraper) RunJQLQuery(name, jql string) (int, error) {
+	s.log.Info().Str("query", name).Str("jql", jql).Msg("Running saved JQL query")
+
+	matched := make(map[string]bool)
+	if err := s.scrapeProjectIssues("", jql, interfaces.IssueSyncOptions{}, matched); err != nil {
+		return 0, err
+	}
+
+	if err := tagQueryMatches(s.db, "issues", "jira_query_tags", name, matched); err != nil {
+		return 0, err
+	}
+	return len(matched), nil
+}
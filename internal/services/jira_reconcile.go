@@ -0,0 +1,167 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"aktis-parser/internal/interfaces"
+	bolt "go.etcd.io/bbolt"
+)
+
+// snapshotProjectIssues returns the stored issues of a project as tombstone candidates keyed by issue key
+func (s *JiraScraper) snapshotProjectIssues(projectKey string) map[string]interfaces.Tombstone {
+	issues := make(map[string]interfaces.Tombstone)
+	s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("issues"))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var issue map[string]interface{}
+			if err := json.Unmarshal(v, &issue); err != nil {
+				return nil
+			}
+			if issueProjectKey(issue) != projectKey {
+				return nil
+			}
+
+			record := interfaces.Tombstone{Source: "jira", Key: string(k), Container: projectKey}
+			record.ID, _ = issue["id"].(string)
+			if fields, ok := issue["fields"].(map[string]interface{}); ok {
+				record.Title, _ = fields["summary"].(string)
+			}
+			issues[record.Key] = record
+			return nil
+		})
+	})
+	return issues
+}
+
+// fetchProjectIssueKeys lists the key of every issue currently in a project
+func (s *JiraScraper) fetchProjectIssueKeys(projectKey string) (map[string]bool, error) {
+	keys := make(map[string]bool)
+	cursor := ""
+
+	for {
+		// Requesting only ids lets search/jql return up to 5000 issues per page
+		page, err := s.searchIssues(fmt.Sprintf("project=\"%s\"", projectKey), "id", "", 5000, cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, issue := range page.Issues {
+			if key, ok := issue["key"].(string); ok {
+				keys[key] = true
+			}
+		}
+
+		if page.Next == "" {
+			return keys, nil
+		}
+		cursor = page.Next
+	}
+}
+
+// reconcileProjectIssues prunes stored issues that are missing from the project's remote key set
+// Each missing issue is looked up by id: issues that were moved are re-fetched under their new key,
+// issues that no longer exist are deleted, and both leave a tombstone under their old key
+func (s *JiraScraper) reconcileProjectIssues(projectKey string, before map[string]interfaces.Tombstone, remote map[string]bool, options interfaces.IssueSyncOptions, fullSync bool, syncedAt time.Time) {
+	report := &interfaces.PruneReport{
+		Source:      "jira",
+		Container:   projectKey,
+		SyncedAt:    syncedAt,
+		FullSync:    fullSync,
+		LocalCount:  len(before),
+		RemoteCount: len(remote),
+		Deleted:     []interfaces.Tombstone{},
+		Moved:       []interfaces.Tombstone{},
+	}
+
+	for _, record := range missingRecords(before, remote) {
+		record.Reason = "deleted"
+		record.DeletedAt = time.Now()
+
+		if record.ID != "" {
+			newKey, newProject, err := s.lookupIssue(record.ID)
+			if err != nil && !isNotFound(err) {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", record.Key, err))
+				continue
+			}
+			if err == nil {
+				// The issue still exists, so store its current version wherever it lives now
+				if err := s.scrapeProjectIssues(newProject, fmt.Sprintf("id = %s", record.ID), options, nil); err != nil {
+					report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", record.Key, err))
+					continue
+				}
+				if newKey == record.Key {
+					continue
+				}
+				record.Reason = "moved"
+				record.MovedTo = newKey
+			}
+		}
+
+		err := s.db.Update(func(tx *bolt.Tx) error {
+			if bucket := tx.Bucket([]byte("issues")); bucket != nil {
+				if err := bucket.Delete([]byte(record.Key)); err != nil {
+					return err
+				}
+			}
+			if err := deleteIssueRecords(tx, record.Key); err != nil {
+				return err
+			}
+			return putTombstone(tx, "jira_tombstones", record)
+		})
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", record.Key, err))
+			continue
+		}
+
+		if record.Reason == "moved" {
+			report.Moved = append(report.Moved, record)
+		} else {
+			report.Deleted = append(report.Deleted, record)
+		}
+	}
+
+	if err := savePruneReport(s.db, "jira_prune_reports", report); err != nil {
+		s.log.Warn().Err(err).Str("project", projectKey).Msg("Failed to store prune report")
+	}
+
+	s.log.Info().
+		Str("project", projectKey).
+		Int("deleted", len(report.Deleted)).
+		Int("moved", len(report.Moved)).
+		Int("errors", len(report.Errors)).
+		Msg("Reconciled project issues")
+	if s.uiLog != nil && len(report.Deleted)+len(report.Moved) > 0 {
+		s.uiLog.BroadcastUILog("info", fmt.Sprintf("Pruned %d deleted and %d moved issues from %s", len(report.Deleted), len(report.Moved), projectKey))
+	}
+}
+
+// lookupIssue returns the current key and project of an issue by id
+// Jira resolves the id even after a move, which gives the issue a new key
+func (s *JiraScraper) lookupIssue(issueID string) (string, string, error) {
+	data, err := s.makeRequest("GET", fmt.Sprintf("/rest/api/3/issue/%s?fields=project", issueID))
+	if err != nil {
+		return "", "", err
+	}
+
+	var issue map[string]interface{}
+	if err := json.Unmarshal(data, &issue); err != nil {
+		return "", "", fmt.Errorf("failed to parse issue %s: %w", issueID, err)
+	}
+	issueKey, _ := issue["key"].(string)
+	return issueKey, issueProjectKey(issue), nil
+}
+
+// GetPruneReports returns the reconciliation reports of a project, or of every project when empty, newest first
+func (s *JiraScraper) GetPruneReports(projectKey string) ([]interfaces.PruneReport, error) {
+	return readPruneReports(s.db, "jira_prune_reports", projectKey)
+}
+
+// GetTombstones returns the tombstones of issues pruned from a project, or from every project when empty
+func (s *JiraScraper) GetTombstones(projectKey string) ([]interfaces.Tombstone, error) {
+	return readTombstones(s.db, "jira_tombstones", projectKey)
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"aktis-parser/internal/interfaces"
+)
+
+// fakeJira serves the search and issue endpoints of a Cloud site holding one project
+type fakeJira struct {
+	mu       sync.Mutex
+	issues   []map[string]interface{}
+	queries  []string
+	failures bool
+}
+
+func (f *fakeJira) setIssues(keys ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.issues = nil
+	for _, key := range keys {
+		f.issues = append(f.issues, map[string]interface{}{
+			"id":  strings.TrimPrefix(key, "P-"),
+			"key": key,
+			"fields": map[string]interface{}{
+				"summary": "Issue " + key,
+				"project": map[string]interface{}{"key": "P"},
+			},
+		})
+	}
+}
+
+func (f *fakeJira) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failures {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/rest/api/3/search/jql":
+		f.queries = append(f.queries, r.URL.Query().Get("jql"))
+		json.NewEncoder(w).Encode(map[string]interface{}{"issues": f.issues, "isLast": true})
+	case strings.HasPrefix(r.URL.Path, "/rest/api/3/issue/"):
+		id := strings.TrimPrefix(r.URL.Path, "/rest/api/3/issue/")
+		for _, issue := range f.issues {
+			if issue["id"] == id {
+				json.NewEncoder(w).Encode(issue)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeJira) lastQuery() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.queries) == 0 {
+		return ""
+	}
+	return f.queries[len(f.queries)-1]
+}
+
+func TestSyncProjectIssuesIncrementalPrunesDeletedIssues(t *testing.T) {
+	jira := &fakeJira{}
+	jira.setIssues("P-1", "P-2")
+	scraper := newTestJiraScraper(t, jira)
+
+	if err := scraper.SyncProjectIssues("P", interfaces.IssueSyncOptions{}); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	if got := scraper.GetIssueCount(); got != 2 {
+		t.Fatalf("issues after first sync = %d, want 2", got)
+	}
+	first, err := scraper.GetProjectSyncState("P")
+	if err != nil || first == nil {
+		t.Fatalf("watermark after first sync = %v, %v", first, err)
+	}
+
+	jira.setIssues("P-1")
+	if err := scraper.SyncProjectIssues("P", interfaces.IssueSyncOptions{}); err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+
+	// The second sync only asked for changes, then listed the project to find P-2 gone
+	found := false
+	for _, query := range jira.queries {
+		if strings.Contains(query, "updated >=") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("no incremental query among %q", jira.queries)
+	}
+
+	if got := scraper.GetIssueCount(); got != 1 {
+		t.Errorf("issues after second sync = %d, want 1", got)
+	}
+	tombstones, err := scraper.GetTombstones("P")
+	if err != nil {
+		t.Fatalf("GetTombstones: %v", err)
+	}
+	if len(tombstones) != 1 || tombstones[0].Key != "P-2" || tombstones[0].Reason != "deleted" {
+		t.Errorf("tombstones = %+v, want P-2 deleted", tombstones)
+	}
+
+	second, err := scraper.GetProjectSyncState("P")
+	if err != nil || second == nil || !second.LastSynced.After(first.LastSynced) {
+		t.Errorf("watermark did not advance: %v then %v", first, second)
+	}
+	if !second.LastFullSync.Equal(first.LastFullSync) {
+		t.Errorf("incremental sync changed LastFullSync from %v to %v", first.LastFullSync, second.LastFullSync)
+	}
+}
+
+func TestSyncProjectIssuesFullSyncFailureKeepsIssues(t *testing.T) {
+	jira := &fakeJira{}
+	jira.setIssues("P-1", "P-2")
+	scraper := newTestJiraScraper(t, jira)
+
+	if err := scraper.SyncProjectIssues("P", interfaces.IssueSyncOptions{}); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	before, _ := scraper.GetProjectSyncState("P")
+
+	jira.failures = true
+	if err := scraper.SyncProjectIssues("P", interfaces.IssueSyncOptions{FullSync: true}); err == nil {
+		t.Fatal("full sync succeeded against a failing site")
+	}
+
+	if got := scraper.GetIssueCount(); got != 2 {
+		t.Errorf("issues after failed full sync = %d, want 2", got)
+	}
+	after, _ := scraper.GetProjectSyncState("P")
+	if after == nil || !after.LastSynced.Equal(before.LastSynced) {
+		t.Errorf("watermark changed by a failed sync: %v then %v", before, after)
+	}
+}
+
+func TestSyncProjectIssuesFullSyncPrunesDeletedIssues(t *testing.T) {
+	jira := &fakeJira{}
+	jira.setIssues("P-1", "P-2", "P-3")
+	scraper := newTestJiraScraper(t, jira)
+
+	if err := scraper.SyncProjectIssues("P", interfaces.IssueSyncOptions{}); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+
+	jira.setIssues("P-1", "P-3")
+	if err := scraper.SyncProjectIssues("P", interfaces.IssueSyncOptions{FullSync: true}); err != nil {
+		t.Fatalf("full sync: %v", err)
+	}
+	if query := jira.lastQuery(); query != `project="P"` {
+		t.Errorf("full sync query = %q, want the whole project", query)
+	}
+
+	if got := scraper.GetIssueCount(); got != 2 {
+		t.Errorf("issues after full sync = %d, want 2", got)
+	}
+	reports, err := scraper.GetPruneReports("P")
+	if err != nil || len(reports) == 0 {
+		t.Fatalf("GetPruneReports = %v, %v", reports, err)
+	}
+	latest := reports[0]
+	if !latest.FullSync || latest.RemoteCount != 2 || len(latest.Deleted) != 1 || latest.Deleted[0].Key != "P-2" {
+		t.Errorf("prune report = %+v, want P-2 deleted by a full sync", latest)
+	}
+}
@@ -1,33 +1,127 @@
 package services
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"aktis-parser/internal/common"
 	"aktis-parser/internal/interfaces"
 	. "github.com/ternarybob/arbor"
 	bolt "go.etcd.io/bbolt"
 )
 
+// incrementalSyncOverlap is subtracted from the watermark so clock skew between
+// this service and Jira cannot cause edits to be missed
+const incrementalSyncOverlap = 5 * time.Minute
+
+// ProjectSyncState records the incremental sync watermark for a Jira project
+type ProjectSyncState struct {
+	ProjectKey   string    `json:"projectKey"`
+	LastSynced   time.Time `json:"lastSynced"`
+	LastFullSync time.Time `json:"lastFullSync"`
+}
+
+// jiraBuckets lists every bucket owned by the Jira scraper
+var jiraBuckets = []string{
+	"projects",
+	"issues",
+	"jira_sync_state",
+	"issue_history",
+	"issue_comments",
+	"issue_worklogs",
+	"issue_links",
+	"boards",
+	"sprints",
+	"issue_sprints",
+	"epics",
+	"jira_tombstones",
+	"jira_prune_reports",
+	"jira_query_tags",
+}
+
+// issueRecordBuckets lists buckets keyed by issue key that hold one record per issue
+var issueRecordBuckets = []string{
+	"issue_history",
+	"issue_links",
+}
+
+// issueChildBuckets lists buckets keyed by "<issueKey>/<id>" that hold many records per issue
+var issueChildBuckets = []string{
+	"issue_comments",
+	"issue_worklogs",
+}
+
+// deleteIssueRecords removes every related record stored for an issue
+func deleteIssueRecords(tx *bolt.Tx, issueKey string) error {
+	for _, name := range issueRecordBuckets {
+		bucket := tx.Bucket([]byte(name))
+		if bucket == nil {
+			continue
+		}
+		if err := bucket.Delete([]byte(issueKey)); err != nil {
+			return fmt.Errorf("failed to delete %s record for %s: %w", name, issueKey, err)
+		}
+	}
+	for _, name := range issueChildBuckets {
+		if err := deleteIssueChildren(tx, name, issueKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteIssueChildren removes all records for an issue from a bucket keyed by "<issueKey>/<id>"
+func deleteIssueChildren(tx *bolt.Tx, bucketName, issueKey string) error {
+	bucket := tx.Bucket([]byte(bucketName))
+	if bucket == nil {
+		return nil
+	}
+
+	prefix := []byte(issueKey + "/")
+	var keysToDelete [][]byte
+	c := bucket.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		keysToDelete = append(keysToDelete, append([]byte(nil), k...))
+	}
+
+	for _, k := range keysToDelete {
+		if err := bucket.Delete(k); err != nil {
+			return fmt.Errorf("failed to delete %s record %s: %w", bucketName, k, err)
+		}
+	}
+	return nil
+}
+
 // JiraScraper implements the Scraper interface for Atlassian Jira
 type JiraScraper struct {
 	authService interfaces.AuthService
+	config      *common.JiraConfig
 	db          *bolt.DB
 	log         ILogger
 	uiLog       UILogger
+	attachments interfaces.AttachmentStore
+	flavor      deploymentFlavor
+
+	fieldsMu         sync.Mutex
+	fieldMetadata    []JiraField
+	fieldMetadataURL string
 }
 
 // NewJiraScraper creates a new Jira scraper instance
-func NewJiraScraper(db *bolt.DB, authService interfaces.AuthService, logger ILogger) (*JiraScraper, error) {
+func NewJiraScraper(db *bolt.DB, authService interfaces.AuthService, config *common.JiraConfig, logger ILogger) (*JiraScraper, error) {
 	// Create buckets
 	err := db.Update(func(tx *bolt.Tx) error {
-		tx.CreateBucketIfNotExists([]byte("projects"))
-		tx.CreateBucketIfNotExists([]byte("issues"))
+		for _, name := range jiraBuckets {
+			tx.CreateBucketIfNotExists([]byte(name))
+		}
 		return nil
 	})
 	if err != nil {
@@ -37,6 +131,7 @@ func NewJiraScraper(db *bolt.DB, authService interfaces.AuthService, logger ILog
 	return &JiraScraper{
 		db:          db,
 		authService: authService,
+		config:      config,
 		log:         logger,
 	}, nil
 }
@@ -46,6 +141,21 @@ func (s *JiraScraper) SetUILogger(uiLog UILogger) {
 	s.uiLog = uiLog
 }
 
+// SetAttachmentStore sets the store used to download issue attachments
+func (s *JiraScraper) SetAttachmentStore(store interfaces.AttachmentStore) {
+	s.attachments = store
+}
+
+// SetFlavor sets the deployment flavor: "cloud", "datacenter" or "auto" to detect it from serverInfo
+func (s *JiraScraper) SetFlavor(flavor string) {
+	s.flavor.set(flavor)
+}
+
+// dataCenter reports whether the authenticated site is Jira Server or Data Center
+func (s *JiraScraper) dataCenter() bool {
+	return s.flavor.resolve(s.authService, s.log) == FlavorDataCenter
+}
+
 // GetDB returns the database connection for sharing with other services
 func (s *JiraScraper) GetDB() *bolt.DB {
 	return s.db
@@ -58,9 +168,32 @@ func (s *JiraScraper) Close() error {
 
 // makeRequest makes an authenticated HTTP request
 func (s *JiraScraper) makeRequest(method, path string) ([]byte, error) {
+	return s.doRequest(method, path, nil)
+}
+
+// makeJSONRequest makes an authenticated HTTP request with a JSON encoded body
+func (s *JiraScraper) makeJSONRequest(method, path string, payload interface{}) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request body: %w", err)
+	}
+	return s.doRequest(method, path, body)
+}
+
+// doRequest performs the authenticated HTTP request with an optional JSON body
+// Paths are written against the Cloud v3 API; Server and Data Center serve the same resources under v2
+func (s *JiraScraper) doRequest(method, path string, payload []byte) ([]byte, error) {
+	if strings.HasPrefix(path, "/rest/api/3/") && s.dataCenter() {
+		path = "/rest/api/2/" + strings.TrimPrefix(path, "/rest/api/3/")
+	}
 	url := s.authService.GetBaseURL() + path
 
-	req, err := http.NewRequest(method, url, nil)
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, url, reqBody)
 	if err != nil {
 		return nil, err
 	}
@@ -68,6 +201,9 @@ func (s *JiraScraper) makeRequest(method, path string) ([]byte, error) {
 	req.Header.Set("User-Agent", s.authService.GetUserAgent())
 	req.Header.Set("Accept", "application/json, text/html")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
 	resp, err := s.authService.GetHTTPClient().Do(req)
 	if err != nil {
@@ -88,7 +224,7 @@ func (s *JiraScraper) makeRequest(method, path string) ([]byte, error) {
 		if resp.StatusCode == 401 || resp.StatusCode == 403 {
 			return nil, fmt.Errorf("auth expired (status %d)", resp.StatusCode)
 		}
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+		return nil, &httpStatusError{Status: resp.StatusCode, Body: string(body)}
 	}
 
 	if readErr != nil {
@@ -101,19 +237,31 @@ func (s *JiraScraper) makeRequest(method, path string) ([]byte, error) {
 // GetProjectIssueCount returns the total count of issues for a project
 func (s *JiraScraper) GetProjectIssueCount(projectKey string) (int, error) {
 	// Atlassian Cloud /rest/api/3/search/jql endpoint no longer returns a `total` field
-	// We need to fetch issues with maxResults=5000 (API max) and count them
-	// Using fields=-all to minimize response size since we only need the count
+	// The approximate-count endpoint returns the count in a single request, and walking
+	// the result set with nextPageToken is used as the fallback
 	jql := fmt.Sprintf("project=\"%s\"", projectKey)
-	encodedJQL := url.QueryEscape(jql)
-	path := fmt.Sprintf("/rest/api/3/search/jql?jql=%s&maxResults=5000&fields=-all", encodedJQL)
 
 	s.log.Debug().
 		Str("project", projectKey).
 		Str("jql", jql).
-		Str("path", path).
 		Msg("Fetching issue count")
 
-	data, err := s.makeRequest("GET", path)
+	var count int
+	var err error
+	if s.dataCenter() {
+		// The classic search still reports the total, an empty page is enough
+		var page *issueSearchPage
+		if page, err = s.searchIssues(jql, "id", "", 0, ""); err == nil {
+			count = page.Total
+		}
+	} else if count, err = s.approximateIssueCount(jql); err != nil {
+		s.log.Warn().
+			Str("project", projectKey).
+			Err(err).
+			Msg("Approximate count unavailable, counting issues page by page")
+
+		count, err = s.countIssuesByPaging(jql)
+	}
 	if err != nil {
 		s.log.Error().
 			Str("project", projectKey).
@@ -122,30 +270,134 @@ func (s *JiraScraper) GetProjectIssueCount(projectKey string) (int, error) {
 		return 0, err
 	}
 
+	s.log.Info().
+		Str("project", projectKey).
+		Int("count", count).
+		Msg("Retrieved issue count")
+
+	return count, nil
+}
+
+// approximateIssueCount returns the issue count reported by /rest/api/3/search/approximate-count
+func (s *JiraScraper) approximateIssueCount(jql string) (int, error) {
+	data, err := s.makeJSONRequest("POST", "/rest/api/3/search/approximate-count", map[string]string{
+		"jql": jql,
+	})
+	if err != nil {
+		return 0, err
+	}
+
 	var result struct {
-		Issues []interface{} `json:"issues"`
-		IsLast bool          `json:"isLast"`
+		Count *int `json:"count"`
 	}
 	if err := json.Unmarshal(data, &result); err != nil {
-		s.log.Error().
-			Str("project", projectKey).
-			Err(err).
-			Str("data", string(data)).
-			Msg("Failed to parse issue count response")
 		return 0, fmt.Errorf("failed to parse response: %w", err)
 	}
+	if result.Count == nil {
+		return 0, fmt.Errorf("approximate-count response missing count")
+	}
 
-	count := len(result.Issues)
+	return *result.Count, nil
+}
 
-	s.log.Info().
-		Str("project", projectKey).
-		Int("count", count).
-		Str("isLast", fmt.Sprintf("%v", result.IsLast)).
-		Msg("Retrieved issue count")
+// countIssuesByPaging counts issues matching the JQL by walking every page of keys
+func (s *JiraScraper) countIssuesByPaging(jql string) (int, error) {
+	count := 0
+	cursor := ""
 
-	// If isLast is false, there are more than 5000 issues
-	// For simplicity, we return the count we got (5000+)
-	return count, nil
+	for {
+		// Only ids are requested, which allows the API maximum of 5000 per page
+		page, err := s.searchIssues(jql, "id", "", 5000, cursor)
+		if err != nil {
+			return 0, err
+		}
+
+		count += len(page.Issues)
+
+		if page.Next == "" {
+			return count, nil
+		}
+		cursor = page.Next
+	}
+}
+
+// issueSearchPage is one page of a JQL search
+type issueSearchPage struct {
+	Issues []map[string]interface{}
+	// Next is the cursor of the following page, empty on the last page
+	Next string
+	// Total is only reported by Server and Data Center, -1 on Cloud
+	Total int
+}
+
+// searchIssues fetches one page of a JQL search starting at cursor (empty for the first page)
+// Cloud pages /rest/api/3/search/jql with an opaque nextPageToken; Server and Data Center page
+// the classic /rest/api/2/search with startAt and total, so the cursor is the next startAt
+func (s *JiraScraper) searchIssues(jql, fields, expand string, maxResults int, cursor string) (*issueSearchPage, error) {
+	params := url.Values{}
+	params.Set("jql", jql)
+	params.Set("maxResults", strconv.Itoa(maxResults))
+	params.Set("fields", fields)
+	if expand != "" {
+		params.Set("expand", expand)
+	}
+
+	if s.dataCenter() {
+		startAt := 0
+		if cursor != "" {
+			startAt, _ = strconv.Atoi(cursor)
+		}
+		params.Set("startAt", strconv.Itoa(startAt))
+
+		data, err := s.makeRequest("GET", "/rest/api/2/search?"+params.Encode())
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Issues []map[string]interface{} `json:"issues"`
+			Total  int                      `json:"total"`
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse issues: %w", err)
+		}
+
+		page := &issueSearchPage{Issues: result.Issues, Total: result.Total}
+		// The server may cap maxResults, so the next page starts after what was returned
+		if next := startAt + len(result.Issues); len(result.Issues) > 0 && next < result.Total {
+			page.Next = strconv.Itoa(next)
+		}
+		return page, nil
+	}
+
+	if cursor != "" {
+		params.Set("nextPageToken", cursor)
+	}
+
+	data, err := s.makeRequest("GET", "/rest/api/3/search/jql?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Issues        []map[string]interface{} `json:"issues"`
+		NextPageToken string                   `json:"nextPageToken"`
+		IsLast        bool                     `json:"isLast"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse issues: %w", err)
+	}
+
+	page := &issueSearchPage{Issues: result.Issues, Total: -1}
+	// The last page either sets isLast or omits the token. A token that does not advance would loop
+	// forever, and stopping there would pass a truncated result off as complete, so it is an error
+	if !result.IsLast && result.NextPageToken != "" {
+		if result.NextPageToken == cursor {
+			return nil, fmt.Errorf("nextPageToken did not advance for %q, results would be incomplete", jql)
+		}
+		page.Next = result.NextPageToken
+	}
+	return page, nil
 }
 
 // ScrapeProjects scrapes all Jira projects and their issues
@@ -274,11 +526,14 @@ func (s *JiraScraper) DeleteProjectIssues(projectKey string) error {
 			}
 		}
 
-		// Delete all matching keys
+		// Delete all matching keys along with their related records
 		for _, k := range keysToDelete {
 			if err := bucket.Delete(k); err != nil {
 				return err
 			}
+			if err := deleteIssueRecords(tx, string(k)); err != nil {
+				return err
+			}
 		}
 
 		s.log.Info().
@@ -286,112 +541,193 @@ func (s *JiraScraper) DeleteProjectIssues(projectKey string) error {
 			Int("deleted", len(keysToDelete)).
 			Msg("Deleted project issues")
 
+		// Without its issues the watermark is meaningless, force the next sync to be full
+		if stateBucket := tx.Bucket([]byte("jira_sync_state")); stateBucket != nil {
+			if err := stateBucket.Delete([]byte(projectKey)); err != nil {
+				return err
+			}
+		}
+
 		return nil
 	})
 }
 
-// GetProjectIssues retrieves all issues for a given project and syncs them
+// GetProjectIssues retrieves issues for a given project and syncs them
+// An incremental sync is used when a watermark exists, otherwise a full sync
 func (s *JiraScraper) GetProjectIssues(projectKey string) error {
-	// First delete existing issues for this project
-	if err := s.DeleteProjectIssues(projectKey); err != nil {
-		s.log.Error().Err(err).Str("project", projectKey).Msg("Failed to delete old issues")
+	return s.SyncProjectIssues(projectKey, interfaces.IssueSyncOptions{})
+}
+
+// SyncProjectIssues syncs issues for a project. Incremental syncs only fetch issues
+// updated since the stored watermark, full syncs fetch every issue; both upsert them.
+// Either way, stored issues that are no longer in the project are then pruned, so a
+// sync that fails part way leaves what was stored before in place.
+func (s *JiraScraper) SyncProjectIssues(projectKey string, options interfaces.IssueSyncOptions) error {
+	syncStarted := time.Now()
+	before := s.snapshotProjectIssues(projectKey)
+
+	state, err := s.GetProjectSyncState(projectKey)
+	if err != nil {
+		s.log.Warn().Err(err).Str("project", projectKey).Msg("Failed to load sync state, falling back to full sync")
+	}
+
+	jql := fmt.Sprintf("project=\"%s\"", projectKey)
+	incremental := !options.FullSync && state != nil && !state.LastSynced.IsZero()
+
+	if incremental {
+		// Relative JQL dates avoid any dependency on the Jira user's timezone
+		minutes := int(syncStarted.Sub(state.LastSynced.Add(-incrementalSyncOverlap)).Minutes()) + 1
+		jql = fmt.Sprintf("%s AND updated >= \"-%dm\"", jql, minutes)
+
+		s.log.Info().
+			Str("project", projectKey).
+			Str("watermark", state.LastSynced.Format(time.RFC3339)).
+			Msg("Starting incremental issue sync")
+		if s.uiLog != nil {
+			s.uiLog.BroadcastUILog("info", fmt.Sprintf("Incremental sync for %s (changes since %s)", projectKey, state.LastSynced.Format("2006-01-02 15:04")))
+		}
+	} else {
+		s.log.Info().
+			Str("project", projectKey).
+			Str("fullSyncRequested", fmt.Sprintf("%v", options.FullSync)).
+			Msg("Starting full issue sync")
+	}
+
+	// A full sync stores every remote issue, so the keys it stores are the remote key set
+	seen := make(map[string]bool)
+	if err := s.scrapeProjectIssues(projectKey, jql, options, seen); err != nil {
 		return err
 	}
 
-	// Now fetch fresh issues
-	return s.scrapeProjectIssues(projectKey)
+	// An incremental sync only stored changed issues and has to list the rest
+	remote := seen
+	if incremental {
+		if remote, err = s.fetchProjectIssueKeys(projectKey); err != nil {
+			s.log.Warn().Err(err).Str("project", projectKey).Msg("Failed to list project issues, skipping reconciliation")
+			remote = nil
+		}
+	}
+	if remote != nil {
+		s.reconcileProjectIssues(projectKey, before, remote, options, !incremental, syncStarted)
+	}
+
+	newState := &ProjectSyncState{
+		ProjectKey: projectKey,
+		LastSynced: syncStarted,
+	}
+	if incremental {
+		newState.LastFullSync = state.LastFullSync
+	} else {
+		newState.LastFullSync = syncStarted
+	}
+
+	if err := s.saveProjectSyncState(newState); err != nil {
+		s.log.Warn().Err(err).Str("project", projectKey).Msg("Failed to store sync watermark")
+	}
+
+	return nil
+}
+
+// GetProjectSyncState returns the stored sync watermark for a project, or nil if none exists
+func (s *JiraScraper) GetProjectSyncState(projectKey string) (*ProjectSyncState, error) {
+	var state *ProjectSyncState
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("jira_sync_state"))
+		if bucket == nil {
+			return nil
+		}
+		data := bucket.Get([]byte(projectKey))
+		if data == nil {
+			return nil
+		}
+		state = &ProjectSyncState{}
+		return json.Unmarshal(data, state)
+	})
+	return state, err
+}
+
+// saveProjectSyncState stores the sync watermark for a project
+func (s *JiraScraper) saveProjectSyncState(state *ProjectSyncState) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte("jira_sync_state"))
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(state.ProjectKey), value)
+	})
 }
 
-// scrapeProjectIssues scrapes all issues for a given project using count-based pagination
-func (s *JiraScraper) scrapeProjectIssues(projectKey string) error {
+// scrapeProjectIssues scrapes all issues matching the JQL for a given project using nextPageToken pagination
+// Issues are upserted, so existing records for unchanged issues are left untouched
+// Saved query runs pass an empty project key since their JQL may span projects; seen, when not nil,
+// collects the key of every stored issue
+func (s *JiraScraper) scrapeProjectIssues(projectKey, jql string, options interfaces.IssueSyncOptions, seen map[string]bool) error {
+	scope := projectKey
+	if scope == "" {
+		scope = "saved query"
+	}
+
 	s.log.Info().Str("project", projectKey).Msg("Scraping issues for project")
 	if s.uiLog != nil {
-		s.uiLog.BroadcastUILog("info", fmt.Sprintf("Fetching issues for project: %s", projectKey))
+		s.uiLog.BroadcastUILog("info", fmt.Sprintf("Fetching issues for: %s", scope))
 	}
 
-	startAt := 0
+	fields := s.resolveFields(options.Fields)
+	includeChangelog := options.IncludeChangelog || (s.config != nil && s.config.IncludeChangelog)
+	includeComments := options.IncludeComments || (s.config != nil && s.config.IncludeComments)
+	includeWorklogs := options.IncludeWorklogs || (s.config != nil && s.config.IncludeWorklogs)
+
+	expand := ""
+	if includeChangelog {
+		expand = "changelog"
+	}
+
+	// Resolved up front since it may need the field metadata, which must not be fetched inside a transaction
+	wikiFields := s.wikiFieldIDs()
+
 	maxResults := 100
 	totalFetched := 0
-	maxIterations := 200 // Safety limit: max 20,000 issues (200 * 100)
-	seenIssueKeys := make(map[string]bool)
-
-	for iteration := 0; iteration < maxIterations; iteration++ {
-		// Use /rest/api/3/search/jql endpoint with properly escaped JQL
-		// JQL syntax: project = "PROJECT_KEY"
-		jql := fmt.Sprintf("project=\"%s\"", projectKey)
-		encodedJQL := url.QueryEscape(jql)
-		path := fmt.Sprintf("/rest/api/3/search/jql?jql=%s&startAt=%d&maxResults=%d&fields=key,summary,status,issuetype,project",
-			encodedJQL, startAt, maxResults)
+	cursor := ""
 
+	for page := 1; ; page++ {
 		s.log.Info().
 			Str("project", projectKey).
 			Str("jql", jql).
-			Int("startAt", startAt).
+			Str("fields", fields).
 			Int("maxResults", maxResults).
-			Int("iteration", iteration+1).
+			Int("page", page).
 			Msg("Fetching issues batch")
 
-		data, err := s.makeRequest("GET", path)
+		result, err := s.searchIssues(jql, fields, expand, maxResults, cursor)
 		if err != nil {
-			s.log.Error().Err(err).Str("project", projectKey).Str("path", path).Msg("Failed to fetch issues")
+			s.log.Error().Err(err).Str("project", projectKey).Str("jql", jql).Msg("Failed to fetch issues")
 			if s.uiLog != nil {
-				s.uiLog.BroadcastUILog("error", fmt.Sprintf("Failed to fetch issues for %s: %v", projectKey, err))
+				s.uiLog.BroadcastUILog("error", fmt.Sprintf("Failed to fetch issues for %s: %v", scope, err))
 			}
 			return err
 		}
 
-		var result struct {
-			Issues []map[string]interface{} `json:"issues"`
-			IsLast bool                     `json:"isLast"`
-		}
-		if err := json.Unmarshal(data, &result); err != nil {
-			s.log.Error().Err(err).Str("project", projectKey).Msg("Failed to parse issues response")
-			if s.uiLog != nil {
-				s.uiLog.BroadcastUILog("error", fmt.Sprintf("Failed to parse issues for %s: %v", projectKey, err))
-			}
-			return fmt.Errorf("failed to parse issues: %w", err)
-		}
-
 		issuesInBatch := len(result.Issues)
 
 		s.log.Info().
 			Str("project", projectKey).
 			Int("issuesInBatch", issuesInBatch).
-			Int("startAt", startAt).
-			Str("isLast", fmt.Sprintf("%v", result.IsLast)).
+			Int("page", page).
+			Str("isLast", fmt.Sprintf("%v", result.Next == "")).
 			Msg("Received issues batch")
 
-		// If no issues returned, we're done
-		if issuesInBatch == 0 {
-			s.log.Info().
-				Str("project", projectKey).
-				Int("totalFetched", totalFetched).
-				Msg("No more issues, stopping pagination")
-			break
-		}
-
-		// Verify issues belong to the requested project and check for duplicates
-		duplicateCount := 0
-		newIssuesCount := 0
+		// Verify issues belong to the requested project
 		wrongProjectCount := 0
 		for _, issue := range result.Issues {
-			issueKey := ""
-			if key, ok := issue["key"].(string); ok {
-				issueKey = key
-			}
-
-			// Check which project this issue belongs to
-			actualProjectKey := ""
-			if fields, ok := issue["fields"].(map[string]interface{}); ok {
-				if project, ok := fields["project"].(map[string]interface{}); ok {
-					if key, ok := project["key"].(string); ok {
-						actualProjectKey = key
-					}
-				}
-			}
+			issueKey, _ := issue["key"].(string)
+			actualProjectKey := issueProjectKey(issue)
 
 			// Warn if issue belongs to different project
-			if actualProjectKey != "" && actualProjectKey != projectKey {
+			if projectKey != "" && actualProjectKey != "" && actualProjectKey != projectKey {
 				wrongProjectCount++
 				s.log.Warn().
 					Str("requestedProject", projectKey).
@@ -399,16 +735,6 @@ func (s *JiraScraper) scrapeProjectIssues(projectKey string) error {
 					Str("issueKey", issueKey).
 					Msg("API returned issue from wrong project")
 			}
-
-			// Check for duplicates
-			if issueKey != "" {
-				if seenIssueKeys[issueKey] {
-					duplicateCount++
-				} else {
-					seenIssueKeys[issueKey] = true
-					newIssuesCount++
-				}
-			}
 		}
 
 		// Log warning if wrong project issues detected (but don't stop scraping)
@@ -422,25 +748,18 @@ func (s *JiraScraper) scrapeProjectIssues(projectKey string) error {
 				Msg("Jira API returned some issues from wrong project - investigate JQL query")
 		}
 
-		// If all issues are duplicates, we're looping on same data
-		if duplicateCount > 0 && newIssuesCount == 0 {
-			s.log.Info().
-				Str("project", projectKey).
-				Int("duplicates", duplicateCount).
-				Int("totalFetched", totalFetched).
-				Msg("All issues are duplicates, stopping pagination")
-			break
-		}
-
-		if duplicateCount > 0 {
-			s.log.Warn().
-				Str("project", projectKey).
-				Int("duplicates", duplicateCount).
-				Int("newIssues", newIssuesCount).
-				Msg("Detected duplicate issues in batch")
+		// The changelog is stored in its own bucket rather than on the issue record
+		changelogs := make(map[string]map[string]interface{})
+		for _, issue := range result.Issues {
+			if changelog, ok := issue["changelog"].(map[string]interface{}); ok {
+				if key, ok := issue["key"].(string); ok {
+					changelogs[key] = changelog
+				}
+				delete(issue, "changelog")
+			}
 		}
 
-		// Store issues in database (only new ones)
+		// Store issues in database
 		storedCount := 0
 		if err := s.db.Update(func(tx *bolt.Tx) error {
 			bucket := tx.Bucket([]byte("issues"))
@@ -453,6 +772,15 @@ func (s *JiraScraper) scrapeProjectIssues(projectKey string) error {
 					s.log.Warn().Msg("Issue missing key field, skipping")
 					continue
 				}
+				if fields, ok := issue["fields"].(map[string]interface{}); ok {
+					if rendered := renderRichTextFields(fields, wikiFields); len(rendered) > 0 {
+						issue["rendered"] = rendered
+					}
+				}
+				// Saved query tags live in their own bucket so they survive full syncs
+				if tags := queryTags(tx, "jira_query_tags", key); len(tags) > 0 {
+					issue["queries"] = tags
+				}
 				value, err := json.Marshal(issue)
 				if err != nil {
 					s.log.Warn().Str("key", key).Err(err).Msg("Failed to marshal issue")
@@ -461,6 +789,15 @@ func (s *JiraScraper) scrapeProjectIssues(projectKey string) error {
 				if err := bucket.Put([]byte(key), value); err != nil {
 					return fmt.Errorf("failed to store issue %s: %w", key, err)
 				}
+				if err := storeIssueLinks(tx, issue); err != nil {
+					return err
+				}
+				if err := clearTombstone(tx, "jira_tombstones", key); err != nil {
+					return err
+				}
+				if seen != nil {
+					seen[key] = true
+				}
 				storedCount++
 			}
 			return nil
@@ -472,42 +809,46 @@ func (s *JiraScraper) scrapeProjectIssues(projectKey string) error {
 			return err
 		}
 
-		totalFetched += newIssuesCount
+		totalFetched += storedCount
+
+		if len(changelogs) > 0 {
+			s.storeIssueChangelogs(changelogs)
+		}
+
+		if includeComments || includeWorklogs {
+			issueKeys := make([]string, 0, issuesInBatch)
+			for _, issue := range result.Issues {
+				if key, ok := issue["key"].(string); ok {
+					issueKeys = append(issueKeys, key)
+				}
+			}
+			s.syncIssueActivity(issueKeys, includeComments, includeWorklogs)
+		}
+
+		if s.attachments != nil && s.attachments.Enabled() {
+			s.storeIssueAttachments(result.Issues)
+		}
 
 		s.log.Info().
 			Str("project", projectKey).
 			Int("batchSize", issuesInBatch).
-			Int("newIssues", newIssuesCount).
-			Int("duplicates", duplicateCount).
+			Int("stored", storedCount).
 			Int("totalFetched", totalFetched).
 			Msg("Stored issues batch")
 
-		if s.uiLog != nil {
-			s.uiLog.BroadcastUILog("info", fmt.Sprintf("Stored %d new issues for %s (total: %d)", newIssuesCount, projectKey, totalFetched))
-		}
-
-		// Stop if isLast flag is true
-		if result.IsLast {
-			s.log.Info().
-				Str("project", projectKey).
-				Int("totalFetched", totalFetched).
-				Msg("Reached last page (isLast=true)")
-			break
+		if s.uiLog != nil && storedCount > 0 {
+			s.uiLog.BroadcastUILog("info", fmt.Sprintf("Stored %d issues for %s (total: %d)", storedCount, scope, totalFetched))
 		}
 
-		// Stop if we got fewer issues than requested (indicates last page)
-		if issuesInBatch < maxResults {
+		if result.Next == "" {
 			s.log.Info().
 				Str("project", projectKey).
-				Int("received", issuesInBatch).
-				Int("requested", maxResults).
 				Int("totalFetched", totalFetched).
-				Msg("Received partial batch, stopping pagination")
+				Msg("Reached last page")
 			break
 		}
 
-		// Increment startAt based on actual issues fetched
-		startAt += issuesInBatch
+		cursor = result.Next
 		time.Sleep(300 * time.Millisecond)
 	}
 
@@ -517,14 +858,31 @@ func (s *JiraScraper) scrapeProjectIssues(projectKey string) error {
 		Msg("Completed fetching issues")
 
 	if s.uiLog != nil {
-		s.uiLog.BroadcastUILog("success", fmt.Sprintf("Completed: %d issues for %s", totalFetched, projectKey))
+		s.uiLog.BroadcastUILog("success", fmt.Sprintf("Completed: %d issues for %s", totalFetched, scope))
 	}
 
 	return nil
 }
 
+// issueProjectKey returns the project key stored in an issue's fields, or empty if absent
+func issueProjectKey(issue map[string]interface{}) string {
+	if fields, ok := issue["fields"].(map[string]interface{}); ok {
+		if project, ok := fields["project"].(map[string]interface{}); ok {
+			if key, ok := project["key"].(string); ok {
+				return key
+			}
+		}
+	}
+	return ""
+}
+
 // GetJiraData returns all Jira data (projects and issues)
 func (s *JiraScraper) GetJiraData() (map[string]interface{}, error) {
+	return s.GetJiraDataWithOptions(interfaces.JiraDataOptions{})
+}
+
+// GetJiraDataWithOptions returns all Jira data, optionally enriched with related records
+func (s *JiraScraper) GetJiraDataWithOptions(options interfaces.JiraDataOptions) (map[string]interface{}, error) {
 	result := map[string]interface{}{
 		"projects": make([]map[string]interface{}, 0),
 		"issues":   make([]map[string]interface{}, 0),
@@ -545,10 +903,31 @@ func (s *JiraScraper) GetJiraData() (map[string]interface{}, error) {
 
 		// Get all issues
 		issueBucket := tx.Bucket([]byte("issues"))
+		historyBucket := tx.Bucket([]byte("issue_history"))
+		commentBucket := tx.Bucket([]byte("issue_comments"))
+		worklogBucket := tx.Bucket([]byte("issue_worklogs"))
+		sprintBucket := tx.Bucket([]byte("issue_sprints"))
 		if issueBucket != nil {
 			issueBucket.ForEach(func(k, v []byte) error {
 				var issue map[string]interface{}
 				if err := json.Unmarshal(v, &issue); err == nil {
+					if options.IncludeHistory && historyBucket != nil {
+						if history := historyBucket.Get(k); history != nil {
+							var record map[string]interface{}
+							if err := json.Unmarshal(history, &record); err == nil {
+								issue["history"] = record["histories"]
+							}
+						}
+					}
+					if options.IncludeComments {
+						issue["comments"] = readIssueChildren(commentBucket, string(k))
+					}
+					if options.IncludeWorklogs {
+						issue["worklogs"] = readIssueChildren(worklogBucket, string(k))
+					}
+					if options.IncludeSprints {
+						issue["sprints"] = readIssueChildren(sprintBucket, string(k))
+					}
 					result["issues"] = append(result["issues"].([]map[string]interface{}), issue)
 				}
 				return nil
@@ -561,25 +940,19 @@ func (s *JiraScraper) GetJiraData() (map[string]interface{}, error) {
 	return result, err
 }
 
-// ClearAllData deletes all data from all buckets (projects, issues)
+// ClearAllData deletes all data from all Jira buckets (projects, issues and related records)
 func (s *JiraScraper) ClearAllData() error {
 	s.log.Info().Msg("Clearing all Jira data from database")
 
 	return s.db.Update(func(tx *bolt.Tx) error {
-		// Delete and recreate projects bucket
-		if err := tx.DeleteBucket([]byte("projects")); err != nil && err != bolt.ErrBucketNotFound {
-			return fmt.Errorf("failed to delete projects bucket: %w", err)
-		}
-		if _, err := tx.CreateBucketIfNotExists([]byte("projects")); err != nil {
-			return fmt.Errorf("failed to recreate projects bucket: %w", err)
-		}
-
-		// Delete and recreate issues bucket
-		if err := tx.DeleteBucket([]byte("issues")); err != nil && err != bolt.ErrBucketNotFound {
-			return fmt.Errorf("failed to delete issues bucket: %w", err)
-		}
-		if _, err := tx.CreateBucketIfNotExists([]byte("issues")); err != nil {
-			return fmt.Errorf("failed to recreate issues bucket: %w", err)
+		// Delete and recreate every bucket, which also forces the next sync to be a full one
+		for _, name := range jiraBuckets {
+			if err := tx.DeleteBucket([]byte(name)); err != nil && err != bolt.ErrBucketNotFound {
+				return fmt.Errorf("failed to delete %s bucket: %w", name, err)
+			}
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return fmt.Errorf("failed to recreate %s bucket: %w", name, err)
+			}
 		}
 
 		s.log.Info().Msg("All Jira data cleared successfully")
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"aktis-parser/internal/common"
+	"aktis-parser/internal/interfaces"
+	bolt "go.etcd.io/bbolt"
+)
+
+// testAuth authenticates requests against a fake Atlassian site
+type testAuth struct {
+	baseURL string
+	client  *http.Client
+}
+
+func (a *testAuth) UpdateAuth(*interfaces.AuthData) error { return nil }
+func (a *testAuth) IsAuthenticated() bool                 { return true }
+func (a *testAuth) GetHTTPClient() *http.Client           { return a.client }
+func (a *testAuth) GetBaseURL() string                    { return a.baseURL }
+func (a *testAuth) GetUserAgent() string                  { return "aktis-parser-test" }
+func (a *testAuth) GetCloudID() string                    { return "" }
+func (a *testAuth) GetAtlToken() string                   { return "" }
+
+func (a *testAuth) LoadAuth() (*interfaces.AuthData, error) {
+	return &interfaces.AuthData{BaseURL: a.baseURL}, nil
+}
+
+// openTestDB opens a database in the test's temporary directory
+func openTestDB(t *testing.T) *bolt.DB {
+	t.Helper()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// newTestJiraScraper returns a Cloud Jira scraper talking to handler
+func newTestJiraScraper(t *testing.T, handler http.Handler) *JiraScraper {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	scraper, err := NewJiraScraper(openTestDB(t), &testAuth{baseURL: server.URL, client: server.Client()}, &common.JiraConfig{}, common.GetLogger())
+	if err != nil {
+		t.Fatalf("NewJiraScraper: %v", err)
+	}
+	scraper.SetFlavor(FlavorCloud)
+	return scraper
+}
+
+func TestSearchIssuesStalledToken(t *testing.T) {
+	scraper := newTestJiraScraper(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"issues":[{"key":"P-1"}],"nextPageToken":"page-2","isLast":false}`))
+	}))
+
+	page, err := scraper.searchIssues(`project="P"`, "id", "", 100, "")
+	if err != nil {
+		t.Fatalf("first page: %v", err)
+	}
+	if page.Next != "page-2" {
+		t.Fatalf("Next = %q, want page-2", page.Next)
+	}
+
+	// The same token again means the search cannot advance
+	if _, err := scraper.searchIssues(`project="P"`, "id", "", 100, page.Next); err == nil || !strings.Contains(err.Error(), "did not advance") {
+		t.Fatalf("err = %v, want a stalled token error", err)
+	}
+	if _, err := scraper.countIssuesByPaging(`project="P"`); err == nil {
+		t.Fatal("countIssuesByPaging succeeded on a stalled token")
+	}
+}
@@ -0,0 +1,488 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"aktis-parser/internal/common"
+	"aktis-parser/internal/interfaces"
+	. "github.com/ternarybob/arbor"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Atlassian OAuth 2.0 (3LO) endpoints
+const (
+	oauthAuthorizeURL = "https://auth.atlassian.com/authorize"
+	oauthTokenURL     = "https://auth.atlassian.com/oauth/token"
+	oauthResourcesURL = "https://api.atlassian.com/oauth/token/accessible-resources"
+	oauthGatewayHost  = "api.atlassian.com"
+)
+
+// oauthRefreshMargin is how long before expiry an access token is refreshed
+const oauthRefreshMargin = time.Minute
+
+// oauthStateTTL is how long an authorization started by /api/oauth/start may take to complete
+const oauthStateTTL = 10 * time.Minute
+
+// errOAuthNotAuthorized is returned for requests made before any grant was authorized
+var errOAuthNotAuthorized = errors.New("not authorized, start OAuth through /api/oauth/start")
+
+// oauthToken is the OAuth grant stored in the auth bucket under "oauth"
+type oauthToken struct {
+	AccessToken  string    `json:"accessToken"`
+	RefreshToken string    `json:"refreshToken"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+	Scope        string    `json:"scope"`
+	CloudID      string    `json:"cloudId"`
+	SiteURL      string    `json:"siteUrl"`
+	AuthorizedAt time.Time `json:"authorizedAt"`
+}
+
+// OAuthAuthService implements the AuthService interface with an OAuth 2.0 (3LO) grant
+// Requests go through the api.atlassian.com gateway for the authorized site and access tokens are
+// refreshed before they expire, so no one has to re-capture cookies
+type OAuthAuthService struct {
+	config *common.OAuthConfig
+	site   string
+	db     *bolt.DB
+	cipher *AuthCipher
+	log    ILogger
+	client *http.Client
+	// direct talks to the authorization server without the bearer transport
+	direct *http.Client
+
+	mu     sync.Mutex
+	token  *oauthToken
+	states map[string]time.Time
+	// refreshMu lets one request refresh the access token while the others wait for it
+	refreshMu sync.Mutex
+}
+
+// oauthTransport authorizes every request with a fresh access token and sends requests addressed to
+// the site itself, such as attachment downloads, through the gateway
+type oauthTransport struct {
+	service *OAuthAuthService
+	base    http.RoundTripper
+}
+
+func (t *oauthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.service.accessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// RoundTrippers must not modify the caller's request
+	clone := req.Clone(req.Context())
+	clone.URL = gatewayURL(clone.URL, token)
+	clone.Host = ""
+	// Redirects such as attachment downloads to the media service must not carry the token
+	if clone.URL.Host == oauthGatewayHost {
+		clone.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	}
+	return t.base.RoundTrip(clone)
+}
+
+// NewOAuthAuthService creates an OAuth authentication service, restoring any grant stored in the auth bucket
+// The configured base URL picks the site when a grant covers several; the grant is encrypted with authCipher
+func NewOAuthAuthService(db *bolt.DB, authCipher *AuthCipher, config *common.ScraperConfig, logger ILogger) (*OAuthAuthService, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte("auth"))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create auth bucket: %w", err)
+	}
+
+	service := &OAuthAuthService{
+		config: &config.OAuth,
+		site:   strings.TrimSuffix(config.BaseURL, "/"),
+		db:     db,
+		cipher: authCipher,
+		log:    logger,
+		states: make(map[string]time.Time),
+	}
+	service.client = &http.Client{
+		Transport: &oauthTransport{service: service, base: http.DefaultTransport},
+		Timeout:   time.Duration(config.TimeoutSeconds) * time.Second,
+	}
+	service.direct = &http.Client{Timeout: time.Duration(config.TimeoutSeconds) * time.Second}
+
+	if token, err := service.loadToken(); err == nil {
+		service.token = token
+		logger.Info().Str("site", token.SiteURL).Str("cloudId", token.CloudID).Msg("Loaded stored OAuth grant")
+	} else {
+		logger.Info().Msg("No OAuth grant stored, authorize through /api/oauth/start")
+	}
+
+	return service, nil
+}
+
+// AuthorizationURL starts an authorization and returns the Atlassian consent URL to send the user to
+func (s *OAuthAuthService) AuthorizationURL() (string, error) {
+	state, err := randomState()
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	now := time.Now()
+	for pending, started := range s.states {
+		if now.Sub(started) > oauthStateTTL {
+			delete(s.states, pending)
+		}
+	}
+	s.states[state] = now
+	s.mu.Unlock()
+
+	params := url.Values{}
+	params.Set("audience", oauthGatewayHost)
+	params.Set("client_id", s.config.ClientID)
+	params.Set("scope", strings.Join(s.config.Scopes, " "))
+	params.Set("redirect_uri", s.config.RedirectURL)
+	params.Set("state", state)
+	params.Set("response_type", "code")
+	params.Set("prompt", "consent")
+	return oauthAuthorizeURL + "?" + params.Encode(), nil
+}
+
+// Exchange completes an authorization: the code is traded for tokens, the site is resolved to its
+// cloud id and the grant is stored
+func (s *OAuthAuthService) Exchange(state, code string) error {
+	s.mu.Lock()
+	started, ok := s.states[state]
+	delete(s.states, state)
+	s.mu.Unlock()
+	if !ok || time.Since(started) > oauthStateTTL {
+		return interfaces.ErrOAuthState
+	}
+
+	token, err := s.requestToken(map[string]string{
+		"grant_type":    "authorization_code",
+		"client_id":     s.config.ClientID,
+		"client_secret": s.config.ClientSecret,
+		"code":          code,
+		"redirect_uri":  s.config.RedirectURL,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.resolveSite(token); err != nil {
+		return err
+	}
+	token.AuthorizedAt = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.saveToken(token); err != nil {
+		return err
+	}
+	s.token = token
+
+	s.log.Info().Str("site", token.SiteURL).Str("cloudId", token.CloudID).Msg("OAuth authorization completed")
+	return nil
+}
+
+// accessToken returns a copy of the grant with a valid access token, refreshing it when it is about to expire
+// s.mu is only held to read or swap the grant; refreshMu serializes the refresh round trip itself
+func (s *OAuthAuthService) accessToken() (oauthToken, error) {
+	if token, fresh, err := s.currentToken(); err != nil || fresh {
+		return token, err
+	}
+
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	// Another request may have refreshed the token while this one waited
+	s.mu.Lock()
+	current := s.token
+	s.mu.Unlock()
+	if current == nil || current.RefreshToken == "" {
+		return oauthToken{}, errOAuthNotAuthorized
+	}
+	if time.Until(current.ExpiresAt) > oauthRefreshMargin {
+		return *current, nil
+	}
+
+	refreshed, err := s.requestToken(map[string]string{
+		"grant_type":    "refresh_token",
+		"client_id":     s.config.ClientID,
+		"client_secret": s.config.ClientSecret,
+		"refresh_token": current.RefreshToken,
+	})
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to refresh OAuth access token")
+		return oauthToken{}, fmt.Errorf("auth expired: %w", err)
+	}
+
+	// Refresh tokens rotate, but keep the old one if none was issued
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = current.RefreshToken
+	}
+	refreshed.CloudID = current.CloudID
+	refreshed.SiteURL = current.SiteURL
+	refreshed.AuthorizedAt = current.AuthorizedAt
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// An authorization completed during the refresh replaces the grant that was refreshed
+	if s.token != current {
+		return *s.token, nil
+	}
+	if err := s.saveToken(refreshed); err != nil {
+		s.log.Warn().Err(err).Msg("Failed to store refreshed OAuth grant")
+	}
+	s.token = refreshed
+
+	s.log.Debug().Str("expiresAt", refreshed.ExpiresAt.Format(time.RFC3339)).Msg("Refreshed OAuth access token")
+	return *refreshed, nil
+}
+
+// currentToken returns a copy of the grant and whether its access token is still fresh
+func (s *OAuthAuthService) currentToken() (oauthToken, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == nil || s.token.RefreshToken == "" {
+		return oauthToken{}, false, errOAuthNotAuthorized
+	}
+	return *s.token, time.Until(s.token.ExpiresAt) > oauthRefreshMargin, nil
+}
+
+// requestToken posts a grant to the token endpoint
+func (s *OAuthAuthService) requestToken(grant map[string]string) (*oauthToken, error) {
+	payload, err := json.Marshal(grant)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.direct.Post(oauthTokenURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &httpStatusError{Status: resp.StatusCode, Body: string(body)}
+	}
+
+	var result struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+		Scope        string `json:"scope"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse OAuth token: %w", err)
+	}
+	if result.AccessToken == "" {
+		return nil, fmt.Errorf("OAuth token response has no access token")
+	}
+
+	return &oauthToken{
+		AccessToken:  result.AccessToken,
+		RefreshToken: result.RefreshToken,
+		ExpiresAt:    time.Now().Add(time.Duration(result.ExpiresIn) * time.Second),
+		Scope:        result.Scope,
+	}, nil
+}
+
+// resolveSite picks the granted site matching the configured base URL, or the first one, and records
+// its cloud id and URL on the token
+func (s *OAuthAuthService) resolveSite(token *oauthToken) error {
+	req, err := http.NewRequest("GET", oauthResourcesURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.direct.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &httpStatusError{Status: resp.StatusCode, Body: string(body)}
+	}
+
+	var resources []struct {
+		ID   string `json:"id"`
+		URL  string `json:"url"`
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(body, &resources); err != nil {
+		return fmt.Errorf("failed to parse accessible resources: %w", err)
+	}
+	if len(resources) == 0 {
+		return fmt.Errorf("the OAuth grant does not cover any site")
+	}
+
+	chosen := resources[0]
+	for _, resource := range resources {
+		if strings.TrimSuffix(resource.URL, "/") == s.site {
+			chosen = resource
+			break
+		}
+	}
+	if len(resources) > 1 && strings.TrimSuffix(chosen.URL, "/") != s.site {
+		s.log.Warn().Str("site", chosen.URL).Int("sites", len(resources)).Msg("Configured base_url not among granted sites, using the first")
+	}
+
+	token.CloudID = chosen.ID
+	token.SiteURL = strings.TrimSuffix(chosen.URL, "/")
+	return nil
+}
+
+// gatewayURL rewrites a request URL onto the api.atlassian.com gateway of the site
+// GetBaseURL points at the Jira gateway; Confluence's /wiki paths and requests made to the site
+// itself are sent to the matching product gateway instead
+func gatewayURL(target *url.URL, token oauthToken) *url.URL {
+	rewritten := *target
+	path := target.Path
+
+	switch {
+	case target.Host == oauthGatewayHost:
+		jiraPrefix := "/ex/jira/" + token.CloudID
+		if !strings.HasPrefix(path, jiraPrefix+"/wiki/") {
+			return &rewritten
+		}
+		path = strings.TrimPrefix(path, jiraPrefix)
+	case strings.EqualFold(target.Host, common.SiteHost(token.SiteURL)):
+	default:
+		return &rewritten
+	}
+
+	product := "jira"
+	if strings.HasPrefix(path, "/wiki/") {
+		product = "confluence"
+	}
+	rewritten.Scheme = "https"
+	rewritten.Host = oauthGatewayHost
+	rewritten.Path = fmt.Sprintf("/ex/%s/%s%s", product, token.CloudID, path)
+	rewritten.RawPath = ""
+	return &rewritten
+}
+
+// loadToken reads the stored OAuth grant
+func (s *OAuthAuthService) loadToken() (*oauthToken, error) {
+	var token oauthToken
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data, err := getAuthRecord(tx, s.cipher, "oauth")
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			return fmt.Errorf("no OAuth grant found")
+		}
+		return json.Unmarshal(data, &token)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// saveToken stores the OAuth grant; the caller holds s.mu
+func (s *OAuthAuthService) saveToken(token *oauthToken) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		data, err := json.Marshal(token)
+		if err != nil {
+			return err
+		}
+		return putAuthRecord(tx, s.cipher, "oauth", data)
+	})
+}
+
+// randomState returns an unguessable state value for an authorization request
+func randomState() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// UpdateAuth rejects extension captures; the OAuth grant is the only credential
+func (s *OAuthAuthService) UpdateAuth(authData *interfaces.AuthData) error {
+	return interfaces.ErrExtensionAuthDisabled
+}
+
+// IsAuthenticated reports whether an OAuth grant has been stored
+func (s *OAuthAuthService) IsAuthenticated() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token != nil && s.token.RefreshToken != "" && s.token.CloudID != ""
+}
+
+// LoadAuth describes the OAuth grant; tokens stay in the service and are not shared
+func (s *OAuthAuthService) LoadAuth() (*interfaces.AuthData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == nil {
+		return nil, fmt.Errorf("no OAuth grant found")
+	}
+	return &interfaces.AuthData{
+		Cookies:   []*interfaces.ExtensionCookie{},
+		Tokens:    map[string]interface{}{"cloudId": s.token.CloudID},
+		UserAgent: serviceUserAgent,
+		BaseURL:   s.token.SiteURL,
+		Timestamp: s.token.AuthorizedAt.UnixMilli(),
+	}, nil
+}
+
+// GetHTTPClient returns the HTTP client that authorizes requests with the OAuth access token
+func (s *OAuthAuthService) GetHTTPClient() *http.Client {
+	if !s.IsAuthenticated() {
+		return nil
+	}
+	return s.client
+}
+
+// GetBaseURL returns the Jira gateway of the authorized site on api.atlassian.com
+func (s *OAuthAuthService) GetBaseURL() string {
+	cloudID := s.GetCloudID()
+	if cloudID == "" {
+		return ""
+	}
+	return fmt.Sprintf("https://%s/ex/jira/%s", oauthGatewayHost, cloudID)
+}
+
+// GetUserAgent returns the user agent string
+func (s *OAuthAuthService) GetUserAgent() string {
+	return serviceUserAgent
+}
+
+// GetCloudID returns the cloud ID of the authorized site
+func (s *OAuthAuthService) GetCloudID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == nil {
+		return ""
+	}
+	return s.token.CloudID
+}
+
+// GetAtlToken returns an empty atl_token; bearer requests need no CSRF token
+func (s *OAuthAuthService) GetAtlToken() string {
+	return ""
+}
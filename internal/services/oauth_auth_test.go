@@ -0,0 +1,82 @@
+package services
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"aktis-parser/internal/common"
+)
+
+// roundTripFunc answers requests without a network
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestAccessTokenRefreshesOnceWithoutHoldingTheLock(t *testing.T) {
+	service, err := NewOAuthAuthService(openTestDB(t), nil, &common.ScraperConfig{}, common.GetLogger())
+	if err != nil {
+		t.Fatalf("NewOAuthAuthService: %v", err)
+	}
+	service.token = &oauthToken{
+		AccessToken:  "old",
+		RefreshToken: "refresh",
+		ExpiresAt:    time.Now(),
+		CloudID:      "cloud",
+		SiteURL:      "https://example.atlassian.net",
+	}
+
+	var refreshes int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	service.direct = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if atomic.AddInt32(&refreshes, 1) == 1 {
+			close(started)
+		}
+		<-release
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"access_token":"new","expires_in":3600}`)),
+			Header:     make(http.Header),
+		}, nil
+	})}
+
+	var wg sync.WaitGroup
+	tokens := make([]oauthToken, 5)
+	errs := make([]error, 5)
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokens[i], errs[i] = service.accessToken()
+		}(i)
+	}
+
+	// The grant stays readable while the refresh is in flight
+	<-started
+	done := make(chan bool)
+	go func() { done <- service.IsAuthenticated() }()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("IsAuthenticated blocked during the token refresh")
+	}
+
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&refreshes); got != 1 {
+		t.Errorf("refreshes = %d, want 1", got)
+	}
+	for i := range tokens {
+		if errs[i] != nil || tokens[i].AccessToken != "new" || tokens[i].RefreshToken != "refresh" {
+			t.Errorf("accessToken() = %+v, %v; want the refreshed token", tokens[i], errs[i])
+		}
+	}
+}
@@ -0,0 +1,406 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"aktis-parser/internal/common"
+	"aktis-parser/internal/interfaces"
+	. "github.com/ternarybob/arbor"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// ErrQueryExists is returned when creating a saved query under a name that is taken
+	ErrQueryExists = errors.New("saved query already exists")
+	// ErrQueryReadOnly is returned when changing a saved query defined in the config file
+	ErrQueryReadOnly = errors.New("saved query is defined in the config file and is read-only")
+)
+
+// QueryService manages saved JQL and CQL queries and runs them through the scrapers
+// Queries are stored in the saved_queries bucket keyed by name; queries from the config file are
+// re-seeded on every start and cannot be changed through the API
+type QueryService struct {
+	db         *bolt.DB
+	jira       interfaces.JiraScraper
+	confluence interfaces.ConfluenceScraper
+	log        ILogger
+}
+
+// NewQueryService creates a new query service and seeds the queries defined in the config file
+func NewQueryService(db *bolt.DB, jira interfaces.JiraScraper, confluence interfaces.ConfluenceScraper, queries []common.SavedQueryConfig, logger ILogger) (*QueryService, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte("saved_queries"))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create saved_queries bucket: %w", err)
+	}
+
+	s := &QueryService{
+		db:         db,
+		jira:       jira,
+		confluence: confluence,
+		log:        logger,
+	}
+	if err := s.seedConfigQueries(queries); err != nil {
+		return nil, fmt.Errorf("failed to seed saved queries: %w", err)
+	}
+	return s, nil
+}
+
+// seedConfigQueries stores the config file queries, keeping the run state of unchanged ones,
+// and removes config queries that are no longer in the file
+func (s *QueryService) seedConfigQueries(queries []common.SavedQueryConfig) error {
+	configured := make(map[string]bool, len(queries))
+	for _, entry := range queries {
+		configured[entry.Name] = true
+
+		existing, err := s.GetQuery(entry.Name)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		query := &interfaces.SavedQuery{
+			Name:      entry.Name,
+			Type:      entry.Type,
+			Query:     entry.Query,
+			Source:    "config",
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if existing != nil {
+			if existing.Type == query.Type && existing.Query == query.Query && existing.Source == query.Source {
+				continue
+			}
+			query.CreatedAt = existing.CreatedAt
+			if existing.Type != query.Type {
+				s.untagQuery(existing)
+			}
+		}
+		if err := s.putQuery(query); err != nil {
+			return err
+		}
+		s.log.Info().Str("query", query.Name).Str("type", query.Type).Msg("Seeded saved query from config")
+	}
+
+	stored, err := s.ListQueries()
+	if err != nil {
+		return err
+	}
+	for _, query := range stored {
+		if query.Source == "config" && !configured[query.Name] {
+			if err := s.removeQuery(&query); err != nil {
+				return err
+			}
+			s.log.Info().Str("query", query.Name).Msg("Removed saved query no longer in config")
+		}
+	}
+	return nil
+}
+
+// ListQueries returns every saved query ordered by name
+func (s *QueryService) ListQueries() ([]interfaces.SavedQuery, error) {
+	queries := []interfaces.SavedQuery{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("saved_queries"))
+		if bucket == nil {
+			return nil
+		}
+		// Bolt iterates in key order, so the list is already sorted by name
+		return bucket.ForEach(func(k, v []byte) error {
+			var query interfaces.SavedQuery
+			if err := json.Unmarshal(v, &query); err != nil {
+				return nil
+			}
+			queries = append(queries, query)
+			return nil
+		})
+	})
+	return queries, err
+}
+
+// GetQuery returns a saved query by name, or nil if none exists
+func (s *QueryService) GetQuery(name string) (*interfaces.SavedQuery, error) {
+	var query *interfaces.SavedQuery
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("saved_queries"))
+		if bucket == nil {
+			return nil
+		}
+		data := bucket.Get([]byte(name))
+		if data == nil {
+			return nil
+		}
+		query = &interfaces.SavedQuery{}
+		return json.Unmarshal(data, query)
+	})
+	return query, err
+}
+
+// CreateQuery stores a new saved query defined through the API
+func (s *QueryService) CreateQuery(name, queryType, queryText string) (*interfaces.SavedQuery, error) {
+	if err := common.ValidateSavedQuery(name, queryType, queryText); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.GetQuery(name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrQueryExists
+	}
+
+	now := time.Now()
+	query := &interfaces.SavedQuery{
+		Name:      name,
+		Type:      queryType,
+		Query:     queryText,
+		Source:    "api",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.putQuery(query); err != nil {
+		return nil, err
+	}
+	return query, nil
+}
+
+// UpdateQuery changes the type and text of a saved query, or returns nil if none exists
+// Tags from the previous definition are kept until the query runs again, unless its type changed
+func (s *QueryService) UpdateQuery(name, queryType, queryText string) (*interfaces.SavedQuery, error) {
+	if err := common.ValidateSavedQuery(name, queryType, queryText); err != nil {
+		return nil, err
+	}
+
+	query, err := s.GetQuery(name)
+	if err != nil || query == nil {
+		return nil, err
+	}
+	if query.Source == "config" {
+		return nil, ErrQueryReadOnly
+	}
+
+	if query.Type != queryType {
+		s.untagQuery(query)
+	}
+	query.Type = queryType
+	query.Query = queryText
+	query.UpdatedAt = time.Now()
+	if err := s.putQuery(query); err != nil {
+		return nil, err
+	}
+	return query, nil
+}
+
+// DeleteQuery removes a saved query and its tags, returning false if none exists
+func (s *QueryService) DeleteQuery(name string) (bool, error) {
+	query, err := s.GetQuery(name)
+	if err != nil || query == nil {
+		return false, err
+	}
+	if query.Source == "config" {
+		return false, ErrQueryReadOnly
+	}
+	return true, s.removeQuery(query)
+}
+
+// RunQuery executes a saved query through its scraper and records the outcome, or returns nil if none exists
+func (s *QueryService) RunQuery(name string) (*interfaces.SavedQuery, error) {
+	query, err := s.GetQuery(name)
+	if err != nil || query == nil {
+		return nil, err
+	}
+
+	s.log.Info().Str("query", query.Name).Str("type", query.Type).Msg("Running saved query")
+
+	var count int
+	var runErr error
+	switch query.Type {
+	case "jql":
+		count, runErr = s.jira.RunJQLQuery(query.Name, query.Query)
+	case "cql":
+		count, runErr = s.confluence.RunCQLQuery(query.Name, query.Query)
+	default:
+		runErr = fmt.Errorf("unsupported saved query type: %s", query.Type)
+	}
+
+	// The query may have been changed or deleted while it ran
+	current, err := s.GetQuery(name)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return query, runErr
+	}
+
+	current.LastRun = time.Now()
+	current.LastError = ""
+	if runErr != nil {
+		current.LastError = runErr.Error()
+	} else {
+		current.LastCount = count
+	}
+	if err := s.putQuery(current); err != nil {
+		return nil, err
+	}
+
+	if runErr != nil {
+		s.log.Error().Err(runErr).Str("query", name).Msg("Saved query failed")
+	} else {
+		s.log.Info().Str("query", name).Int("matched", count).Msg("Saved query completed")
+	}
+	return current, runErr
+}
+
+// putQuery stores a saved query under its name
+func (s *QueryService) putQuery(query *interfaces.SavedQuery) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte("saved_queries"))
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(query)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(query.Name), value)
+	})
+}
+
+// removeQuery deletes a saved query and strips its tag from every record
+func (s *QueryService) removeQuery(query *interfaces.SavedQuery) error {
+	s.untagQuery(query)
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("saved_queries"))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.Delete([]byte(query.Name))
+	})
+}
+
+// untagQuery strips a saved query's tag from every record it matched
+func (s *QueryService) untagQuery(query *interfaces.SavedQuery) {
+	recordBucket, tagBucket := queryBuckets(query.Type)
+	if err := tagQueryMatches(s.db, recordBucket, tagBucket, query.Name, nil); err != nil {
+		s.log.Warn().Err(err).Str("query", query.Name).Msg("Failed to remove saved query tags")
+	}
+}
+
+// queryBuckets returns the record and tag buckets a saved query type applies to
+func queryBuckets(queryType string) (string, string) {
+	if queryType == "cql" {
+		return "confluence_pages", "confluence_query_tags"
+	}
+	return "issues", "jira_query_tags"
+}
+
+// queryTags returns the names of the saved queries that matched a record
+func queryTags(tx *bolt.Tx, tagBucket, key string) []string {
+	bucket := tx.Bucket([]byte(tagBucket))
+	if bucket == nil {
+		return nil
+	}
+	var tags []string
+	if data := bucket.Get([]byte(key)); data != nil {
+		json.Unmarshal(data, &tags)
+	}
+	return tags
+}
+
+// tagQueryMatches records which records a saved query matched
+// The tag is added to every matched record and removed from records it matched before but not now;
+// tags are kept in their own bucket keyed by record key and mirrored onto stored records as "queries"
+func tagQueryMatches(db *bolt.DB, recordBucket, tagBucket, name string, matched map[string]bool) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		tags, err := tx.CreateBucketIfNotExists([]byte(tagBucket))
+		if err != nil {
+			return err
+		}
+
+		updates := make(map[string][]string)
+		err = tags.ForEach(func(k, v []byte) error {
+			var names []string
+			if err := json.Unmarshal(v, &names); err != nil {
+				return nil
+			}
+			if matched[string(k)] {
+				return nil
+			}
+			for i, tag := range names {
+				if tag == name {
+					updates[string(k)] = append(names[:i:i], names[i+1:]...)
+					break
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for key := range matched {
+			names := queryTags(tx, tagBucket, key)
+			tagged := false
+			for _, tag := range names {
+				if tag == name {
+					tagged = true
+					break
+				}
+			}
+			if !tagged {
+				names = append(names, name)
+				sort.Strings(names)
+				updates[key] = names
+			}
+		}
+
+		records := tx.Bucket([]byte(recordBucket))
+		for key, names := range updates {
+			if len(names) == 0 {
+				if err := tags.Delete([]byte(key)); err != nil {
+					return err
+				}
+			} else {
+				value, err := json.Marshal(names)
+				if err != nil {
+					return err
+				}
+				if err := tags.Put([]byte(key), value); err != nil {
+					return err
+				}
+			}
+
+			if records == nil {
+				continue
+			}
+			data := records.Get([]byte(key))
+			if data == nil {
+				continue
+			}
+			var record map[string]interface{}
+			if err := json.Unmarshal(data, &record); err != nil {
+				continue
+			}
+			if len(names) == 0 {
+				delete(record, "queries")
+			} else {
+				record["queries"] = names
+			}
+			value, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+			if err := records.Put([]byte(key), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"aktis-parser/internal/common"
+	"aktis-parser/internal/interfaces"
+	bolt "go.etcd.io/bbolt"
+)
+
+// storedIssueQueries returns the tag bucket entry and the "queries" mirrored onto a stored issue
+func storedIssueQueries(t *testing.T, db *bolt.DB, key string) ([]string, []string) {
+	t.Helper()
+	var tags, mirrored []string
+	db.View(func(tx *bolt.Tx) error {
+		tags = queryTags(tx, "jira_query_tags", key)
+		if data := tx.Bucket([]byte("issues")).Get([]byte(key)); data != nil {
+			var issue struct {
+				Queries []string `json:"queries"`
+			}
+			if err := json.Unmarshal(data, &issue); err != nil {
+				t.Fatalf("stored issue %s: %v", key, err)
+			}
+			mirrored = issue.Queries
+		}
+		return nil
+	})
+	return tags, mirrored
+}
+
+func assertIssueQueries(t *testing.T, db *bolt.DB, key string, want ...string) {
+	t.Helper()
+	tags, mirrored := storedIssueQueries(t, db, key)
+	if len(want) == 0 {
+		want = nil
+	}
+	if !reflect.DeepEqual(tags, want) || !reflect.DeepEqual(mirrored, want) {
+		t.Errorf("%s tagged %v, record queries %v; want %v", key, tags, mirrored, want)
+	}
+}
+
+func TestRunQueryTagsMatchingIssues(t *testing.T) {
+	jira := &fakeJira{}
+	jira.setIssues("P-1", "P-2")
+	scraper := newTestJiraScraper(t, jira)
+	db := scraper.GetDB()
+
+	queries, err := NewQueryService(db, scraper, nil, nil, common.GetLogger())
+	if err != nil {
+		t.Fatalf("NewQueryService: %v", err)
+	}
+	for _, name := range []string{"bugs", "mine"} {
+		if _, err := queries.CreateQuery(name, "jql", "type = Bug"); err != nil {
+			t.Fatalf("CreateQuery(%s): %v", name, err)
+		}
+	}
+
+	query, err := queries.RunQuery("bugs")
+	if err != nil {
+		t.Fatalf("RunQuery: %v", err)
+	}
+	if query.LastCount != 2 || query.LastError != "" {
+		t.Errorf("run state = %d, %q; want 2 matches", query.LastCount, query.LastError)
+	}
+	if got := jira.lastQuery(); got != "type = Bug" {
+		t.Errorf("searched %q, want the saved JQL", got)
+	}
+	assertIssueQueries(t, db, "P-1", "bugs")
+	assertIssueQueries(t, db, "P-2", "bugs")
+
+	// A second query adds its tag beside the first, kept in name order
+	jira.setIssues("P-2")
+	if _, err := queries.RunQuery("mine"); err != nil {
+		t.Fatalf("RunQuery(mine): %v", err)
+	}
+	assertIssueQueries(t, db, "P-1", "bugs")
+	assertIssueQueries(t, db, "P-2", "bugs", "mine")
+
+	// Tags survive the issue being stored again by a project sync
+	if err := scraper.SyncProjectIssues("P", interfaces.IssueSyncOptions{FullSync: true}); err != nil {
+		t.Fatalf("SyncProjectIssues: %v", err)
+	}
+	assertIssueQueries(t, db, "P-2", "bugs", "mine")
+
+	// Issues that no longer match lose the tag on the next run
+	jira.setIssues("P-1", "P-2")
+	if err := scraper.SyncProjectIssues("P", interfaces.IssueSyncOptions{FullSync: true}); err != nil {
+		t.Fatalf("SyncProjectIssues: %v", err)
+	}
+	jira.setIssues("P-1")
+	if _, err := queries.RunQuery("bugs"); err != nil {
+		t.Fatalf("RunQuery(bugs) again: %v", err)
+	}
+	assertIssueQueries(t, db, "P-1", "bugs")
+	assertIssueQueries(t, db, "P-2", "mine")
+
+	// Changing the query type drops its Jira tags, and deleting a query drops the rest
+	if _, err := queries.UpdateQuery("bugs", "cql", "label = adr"); err != nil {
+		t.Fatalf("UpdateQuery: %v", err)
+	}
+	assertIssueQueries(t, db, "P-1")
+	if deleted, err := queries.DeleteQuery("mine"); !deleted || err != nil {
+		t.Fatalf("DeleteQuery = %v, %v", deleted, err)
+	}
+	assertIssueQueries(t, db, "P-2")
+}
+
+func TestRunQueryRecordsFailure(t *testing.T) {
+	jira := &fakeJira{}
+	jira.setIssues("P-1")
+	scraper := newTestJiraScraper(t, jira)
+	db := scraper.GetDB()
+
+	queries, err := NewQueryService(db, scraper, nil, nil, common.GetLogger())
+	if err != nil {
+		t.Fatalf("NewQueryService: %v", err)
+	}
+	queries.CreateQuery("bugs", "jql", "type = Bug")
+	if _, err := queries.RunQuery("bugs"); err != nil {
+		t.Fatalf("RunQuery: %v", err)
+	}
+
+	jira.failures = true
+	query, err := queries.RunQuery("bugs")
+	if err == nil || query == nil || query.LastError == "" {
+		t.Fatalf("RunQuery against a failing site = %+v, %v; want the error recorded", query, err)
+	}
+	if query.LastCount != 1 {
+		t.Errorf("LastCount = %d after a failed run, want the previous 1", query.LastCount)
+	}
+	// A failed run never strips tags it could not confirm
+	assertIssueQueries(t, db, "P-1", "bugs")
+}
+
+func TestConfigQueriesAreReadOnly(t *testing.T) {
+	db := openTestDB(t)
+	config := []common.SavedQueryConfig{{Name: "adrs", Type: "cql", Query: "label = adr"}}
+
+	queries, err := NewQueryService(db, nil, nil, config, common.GetLogger())
+	if err != nil {
+		t.Fatalf("NewQueryService: %v", err)
+	}
+	if _, err := queries.CreateQuery("adrs", "cql", "label = other"); !errors.Is(err, ErrQueryExists) {
+		t.Errorf("CreateQuery over a config query = %v, want ErrQueryExists", err)
+	}
+	if _, err := queries.UpdateQuery("adrs", "cql", "label = other"); !errors.Is(err, ErrQueryReadOnly) {
+		t.Errorf("UpdateQuery = %v, want ErrQueryReadOnly", err)
+	}
+	if _, err := queries.DeleteQuery("adrs"); !errors.Is(err, ErrQueryReadOnly) {
+		t.Errorf("DeleteQuery = %v, want ErrQueryReadOnly", err)
+	}
+
+	// Seeding again without the entry removes it
+	if _, err := NewQueryService(db, nil, nil, nil, common.GetLogger()); err != nil {
+		t.Fatalf("NewQueryService: %v", err)
+	}
+	if query, _ := queries.GetQuery("adrs"); query != nil {
+		t.Errorf("query removed from config is still stored: %+v", query)
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"aktis-parser/internal/interfaces"
+	bolt "go.etcd.io/bbolt"
+)
+
+// maxPruneReports is the number of prune reports kept per project or space
+const maxPruneReports = 20
+
+// httpStatusError is returned for non-200 responses other than auth failures
+type httpStatusError struct {
+	Status int
+	Body   string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.Status, e.Body)
+}
+
+// isNotFound reports whether a request failed because the resource does not exist
+func isNotFound(err error) bool {
+	var statusErr *httpStatusError
+	return errors.As(err, &statusErr) && statusErr.Status == http.StatusNotFound
+}
+
+// missingRecords returns the stored records absent from the remote id set, ordered by key
+func missingRecords(local map[string]interfaces.Tombstone, remote map[string]bool) []interfaces.Tombstone {
+	missing := []interfaces.Tombstone{}
+	for key, record := range local {
+		if !remote[key] {
+			missing = append(missing, record)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		return missing[i].Key < missing[j].Key
+	})
+	return missing
+}
+
+// putTombstone records a pruned record in a tombstone bucket keyed by its local key
+func putTombstone(tx *bolt.Tx, bucketName string, tombstone interfaces.Tombstone) error {
+	bucket, err := tx.CreateBucketIfNotExists([]byte(bucketName))
+	if err != nil {
+		return err
+	}
+	value, err := json.Marshal(tombstone)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(tombstone.Key), value)
+}
+
+// clearTombstone removes the tombstone of a record that has been stored again
+func clearTombstone(tx *bolt.Tx, bucketName, key string) error {
+	bucket := tx.Bucket([]byte(bucketName))
+	if bucket == nil {
+		return nil
+	}
+	return bucket.Delete([]byte(key))
+}
+
+// readTombstones returns the tombstones of a bucket, newest first, optionally limited to one project or space
+func readTombstones(db *bolt.DB, bucketName, container string) ([]interfaces.Tombstone, error) {
+	tombstones := []interfaces.Tombstone{}
+	err := db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var tombstone interfaces.Tombstone
+			if err := json.Unmarshal(v, &tombstone); err != nil {
+				return nil
+			}
+			if container == "" || tombstone.Container == container {
+				tombstones = append(tombstones, tombstone)
+			}
+			return nil
+		})
+	})
+	sort.Slice(tombstones, func(i, j int) bool {
+		return tombstones[i].DeletedAt.After(tombstones[j].DeletedAt)
+	})
+	return tombstones, err
+}
+
+// savePruneReport stores a reconciliation report under "<container>/<time>" and keeps only the latest ones
+func savePruneReport(db *bolt.DB, bucketName string, report *interfaces.PruneReport) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(bucketName))
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(report)
+		if err != nil {
+			return err
+		}
+		key := fmt.Sprintf("%s/%s", report.Container, report.SyncedAt.UTC().Format(time.RFC3339Nano))
+		if err := bucket.Put([]byte(key), value); err != nil {
+			return err
+		}
+
+		keys := [][]byte{}
+		prefix := []byte(report.Container + "/")
+		c := bucket.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			keys = append(keys, append([]byte(nil), k...))
+		}
+		for i := 0; i < len(keys)-maxPruneReports; i++ {
+			if err := bucket.Delete(keys[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// readPruneReports returns the stored reports of a bucket, newest first, optionally limited to one project or space
+func readPruneReports(db *bolt.DB, bucketName, container string) ([]interfaces.PruneReport, error) {
+	reports := []interfaces.PruneReport{}
+	err := db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var report interfaces.PruneReport
+			if err := json.Unmarshal(v, &report); err != nil {
+				return nil
+			}
+			if container == "" || report.Container == container {
+				reports = append(reports, report)
+			}
+			return nil
+		})
+	})
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].SyncedAt.After(reports[j].SyncedAt)
+	})
+	return reports, err
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"aktis-parser/internal/common"
+	"aktis-parser/internal/interfaces"
+	. "github.com/ternarybob/arbor"
+)
+
+// serviceUserAgent identifies requests made with service credentials rather than a captured browser session
+const serviceUserAgent = "aktis-parser"
+
+// APITokenAuthService implements the AuthService interface with an Atlassian account email and API token
+// Every request carries basic auth, so headless servers and CI can scrape without a browser
+type APITokenAuthService struct {
+	client      *http.Client
+	baseURL     string
+	email       string
+	validatedAt time.Time
+	log         ILogger
+}
+
+// basicAuthTransport adds the email and API token as basic auth to requests for the configured site
+type basicAuthTransport struct {
+	email string
+	token string
+	site  *url.URL
+	base  http.RoundTripper
+}
+
+func (t *basicAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Redirects such as attachment downloads to the media service must not carry the token
+	if !strings.EqualFold(req.URL.Scheme, t.site.Scheme) || !strings.EqualFold(req.URL.Host, t.site.Host) {
+		return t.base.RoundTrip(req)
+	}
+
+	// RoundTrippers must not modify the caller's request
+	clone := req.Clone(req.Context())
+	clone.SetBasicAuth(t.email, t.token)
+	return t.base.RoundTrip(clone)
+}
+
+// NewAPITokenAuthService creates an authentication service from the configured email and API token
+// The credentials are validated against /rest/api/3/myself before the service is returned
+func NewAPITokenAuthService(config *common.ScraperConfig, logger ILogger) (*APITokenAuthService, error) {
+	site, err := url.Parse(config.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL %q: %w", config.BaseURL, err)
+	}
+
+	service := &APITokenAuthService{
+		client: &http.Client{
+			Transport: &basicAuthTransport{
+				email: config.Email,
+				token: config.APIToken,
+				site:  site,
+				base:  http.DefaultTransport,
+			},
+			Timeout: time.Duration(config.TimeoutSeconds) * time.Second,
+		},
+		baseURL: strings.TrimSuffix(config.BaseURL, "/"),
+		email:   config.Email,
+		log:     logger,
+	}
+
+	// Server and Data Center only serve v2
+	path := "/rest/api/3/myself"
+	if config.Flavor == FlavorDataCenter {
+		path = "/rest/api/2/myself"
+	}
+	if err := service.validate(path); err != nil {
+		return nil, fmt.Errorf("API token rejected by %s: %w", service.baseURL, err)
+	}
+
+	return service, nil
+}
+
+// validate checks the credentials by fetching the account they belong to
+func (s *APITokenAuthService) validate(path string) error {
+	req, err := http.NewRequest("GET", s.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", serviceUserAgent)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &httpStatusError{Status: resp.StatusCode, Body: string(body)}
+	}
+
+	var account struct {
+		AccountID   string `json:"accountId"`
+		DisplayName string `json:"displayName"`
+	}
+	if err := json.Unmarshal(body, &account); err != nil {
+		return fmt.Errorf("failed to parse account: %w", err)
+	}
+
+	s.validatedAt = time.Now()
+	s.log.Info().
+		Str("baseURL", s.baseURL).
+		Str("email", s.email).
+		Str("account", account.DisplayName).
+		Msg("API token validated")
+	return nil
+}
+
+// UpdateAuth rejects extension captures; the configured API token is the only credential
+func (s *APITokenAuthService) UpdateAuth(authData *interfaces.AuthData) error {
+	return interfaces.ErrExtensionAuthDisabled
+}
+
+// IsAuthenticated reports whether the API token was validated at startup
+func (s *APITokenAuthService) IsAuthenticated() bool {
+	return !s.validatedAt.IsZero()
+}
+
+// LoadAuth describes the API token session; there are no cookies or tokens to share
+func (s *APITokenAuthService) LoadAuth() (*interfaces.AuthData, error) {
+	if !s.IsAuthenticated() {
+		return nil, fmt.Errorf("API token not validated")
+	}
+	return &interfaces.AuthData{
+		Cookies:   []*interfaces.ExtensionCookie{},
+		Tokens:    map[string]interface{}{},
+		UserAgent: serviceUserAgent,
+		BaseURL:   s.baseURL,
+		Timestamp: s.validatedAt.UnixMilli(),
+	}, nil
+}
+
+// GetHTTPClient returns the HTTP client that adds basic auth to every request
+func (s *APITokenAuthService) GetHTTPClient() *http.Client {
+	return s.client
+}
+
+// GetBaseURL returns the base URL for API requests
+func (s *APITokenAuthService) GetBaseURL() string {
+	return s.baseURL
+}
+
+// GetUserAgent returns the user agent string
+func (s *APITokenAuthService) GetUserAgent() string {
+	return serviceUserAgent
+}
+
+// GetCloudID returns an empty cloud ID; it is only known from extension captures
+func (s *APITokenAuthService) GetCloudID() string {
+	return ""
+}
+
+// GetAtlToken returns an empty atl_token; basic auth requests need no CSRF token
+func (s *APITokenAuthService) GetAtlToken() string {
+	return ""
+}
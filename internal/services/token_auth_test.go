@@ -0,0 +1,74 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"aktis-parser/internal/common"
+)
+
+func TestAPITokenNotSentAcrossRedirects(t *testing.T) {
+	var mu sync.Mutex
+	var mediaAuth []string
+	media := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		mediaAuth = append(mediaAuth, r.Header.Get("Authorization"))
+		mu.Unlock()
+		w.Write([]byte("blob"))
+	}))
+	t.Cleanup(media.Close)
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, ok := r.BasicAuth(); !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/rest/api/3/myself":
+			w.Write([]byte(`{"accountId":"a-1","displayName":"Jane"}`))
+		case "/secure/attachment/1":
+			http.Redirect(w, r, media.URL+"/file/1", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(site.Close)
+
+	service, err := NewAPITokenAuthService(&common.ScraperConfig{
+		BaseURL:  site.URL + "/",
+		Email:    "jane@example.com",
+		APIToken: "secret",
+	}, common.GetLogger())
+	if err != nil {
+		t.Fatalf("NewAPITokenAuthService: %v", err)
+	}
+
+	resp, err := service.GetHTTPClient().Get(site.URL + "/secure/attachment/1")
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("download status = %d, want the site to accept the token and redirect", resp.StatusCode)
+	}
+
+	// Absolute URLs on other hosts get no credentials either
+	resp, err = service.GetHTTPClient().Get(media.URL + "/file/2")
+	if err != nil {
+		t.Fatalf("direct request: %v", err)
+	}
+	resp.Body.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(mediaAuth) != 2 {
+		t.Fatalf("media host saw %d requests, want 2", len(mediaAuth))
+	}
+	for _, auth := range mediaAuth {
+		if auth != "" {
+			t.Errorf("media host received Authorization %q", auth)
+		}
+	}
+}
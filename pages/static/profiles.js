@@ -0,0 +1,42 @@
+// Site profile selector for the navbar
+// The selection is kept in the aktis_profile cookie, which every page, API call and WebSocket sends
+async function loadProfiles() {
+    const select = document.getElementById('profile-select');
+    if (!select) {
+        return;
+    }
+
+    try {
+        const response = await fetch('/api/profiles');
+        if (!response.ok) {
+            console.error('Failed to load site profiles');
+            return;
+        }
+        const result = await response.json();
+
+        // A single site needs no selector
+        if (!result.profiles || result.profiles.length < 2) {
+            return;
+        }
+
+        select.innerHTML = '';
+        result.profiles.forEach(profile => {
+            const option = document.createElement('option');
+            option.value = profile.name;
+            option.textContent = profile.name;
+            option.title = profile.baseUrl;
+            option.selected = profile.name === result.selected;
+            select.appendChild(option);
+        });
+
+        select.addEventListener('change', () => {
+            document.cookie = `aktis_profile=${encodeURIComponent(select.value)}; path=/; SameSite=Lax`;
+            window.location.reload();
+        });
+        select.style.display = '';
+    } catch (error) {
+        console.error('Error loading site profiles:', error);
+    }
+}
+
+document.addEventListener('DOMContentLoaded', loadProfiles);
@@ -189,6 +189,58 @@ func TestCollector_GetIssuesPaginated(t *testing.T) {
 		len(allIssues), issuesResponse.Pagination.TotalPages)
 }
 
+// TestCollector_GetIssuesWithActivity verifies includeComments and includeWorklogs embed related records
+func TestCollector_GetIssuesWithActivity(t *testing.T) {
+	t.Log("Getting projects to find one with issues...")
+	resp, err := http.Get(config.Test.ParserURL + "/api/collector/projects")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	var projectsResponse CollectorResponse
+	err = json.Unmarshal(body, &projectsResponse)
+	require.NoError(t, err)
+
+	var testProjectKey string
+	for _, project := range projectsResponse.Data {
+		if count, ok := project["issueCount"].(float64); ok && count > 0 {
+			testProjectKey = project["key"].(string)
+			break
+		}
+	}
+
+	if testProjectKey == "" {
+		t.Skip("No projects with issues found (run scrape first)")
+	}
+
+	issuesURL := fmt.Sprintf("%s/api/collector/issues?projectKey=%s&includeComments=true&includeWorklogs=true",
+		config.Test.ParserURL, testProjectKey)
+
+	resp, err = http.Get(issuesURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode, "Should return 200 OK")
+
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	var issuesResponse CollectorResponse
+	err = json.Unmarshal(body, &issuesResponse)
+	require.NoError(t, err)
+
+	for _, issue := range issuesResponse.Data {
+		_, hasComments := issue["comments"].([]interface{})
+		_, hasWorklogs := issue["worklogs"].([]interface{})
+		require.True(t, hasComments, fmt.Sprintf("Issue %v should have comments array", issue["key"]))
+		require.True(t, hasWorklogs, fmt.Sprintf("Issue %v should have worklogs array", issue["key"]))
+	}
+
+	t.Logf("✅ %d issues returned with comments and worklogs", len(issuesResponse.Data))
+}
+
 // TestCollector_GetSpaces verifies getting Confluence spaces with page counts
 func TestCollector_GetSpaces(t *testing.T) {
 	t.Log("Getting Confluence spaces...")
@@ -360,3 +360,177 @@ func TestConfluence_PageFiltering(t *testing.T) {
 	t.Logf("✓ Found %d pages matching filter", len(pages))
 	t.Log("✅ Page filtering API test passed")
 }
+
+// TestConfluence_PagesMarkdownFormat verifies format=markdown replaces storage bodies with Markdown
+func TestConfluence_PagesMarkdownFormat(t *testing.T) {
+	if !config.API.Enabled {
+		t.Skip("API tests disabled in config")
+	}
+
+	timeout := time.Duration(config.Test.TimeoutSeconds) * time.Second
+	client := &http.Client{Timeout: timeout}
+
+	resp, err := client.Get(config.Test.ParserURL + "/api/data/confluence/pages?format=markdown")
+	require.NoError(t, err, "Should query pages as Markdown")
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode, "Should return 200 OK")
+
+	var pageData map[string]interface{}
+	err = json.NewDecoder(resp.Body).Decode(&pageData)
+	require.NoError(t, err, "Should parse JSON")
+
+	pages, _ := pageData["pages"].([]interface{})
+	for _, p := range pages {
+		page := p.(map[string]interface{})
+		if body, ok := page["body"].(map[string]interface{}); ok {
+			_, hasStorage := body["storage"]
+			_, hasMarkdown := body["markdown"]
+			assert.False(t, hasStorage, "Markdown pages should not carry storage bodies")
+			assert.True(t, hasMarkdown, "Markdown pages should carry body.markdown")
+		}
+	}
+
+	badResp, err := client.Get(config.Test.ParserURL + "/api/data/confluence/pages?format=pdf")
+	require.NoError(t, err, "Should call pages endpoint")
+	badResp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, badResp.StatusCode, "Should reject unknown formats")
+
+	t.Logf("✓ %d pages returned as Markdown", len(pages))
+}
+
+// TestConfluence_SpaceTreeUnknownSpace verifies the tree endpoint returns 404 for spaces without a tree
+func TestConfluence_SpaceTreeUnknownSpace(t *testing.T) {
+	if !config.API.Enabled {
+		t.Skip("API tests disabled in config")
+	}
+
+	timeout := time.Duration(config.Test.TimeoutSeconds) * time.Second
+	client := &http.Client{Timeout: timeout}
+
+	resp, err := client.Get(config.Test.ParserURL + "/api/data/confluence/spaces/NOPE999999/tree")
+	require.NoError(t, err, "Should call tree endpoint")
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "Should return 404 for unknown space")
+
+	var result map[string]interface{}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	require.NoError(t, err, "Should return JSON error body")
+	assert.Equal(t, "error", result["status"], "Should return error status")
+
+	badResp, err := client.Get(config.Test.ParserURL + "/api/data/confluence/spaces/NOPE999999/tree?depth=0")
+	require.NoError(t, err, "Should call tree endpoint")
+	badResp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, badResp.StatusCode, "Should reject depth below 1")
+
+	t.Log("✓ Unknown space tree returns 404")
+}
+
+// TestConfluence_PageDiffUnknownPage verifies the version endpoints for a page without stored versions
+func TestConfluence_PageDiffUnknownPage(t *testing.T) {
+	if !config.API.Enabled {
+		t.Skip("API tests disabled in config")
+	}
+
+	timeout := time.Duration(config.Test.TimeoutSeconds) * time.Second
+	client := &http.Client{Timeout: timeout}
+
+	versionsResp, err := client.Get(config.Test.ParserURL + "/api/data/confluence/pages/999999999999/versions")
+	require.NoError(t, err, "Should call versions endpoint")
+	defer versionsResp.Body.Close()
+	assert.Equal(t, http.StatusOK, versionsResp.StatusCode, "Should return 200 for versions")
+
+	var versions map[string]interface{}
+	err = json.NewDecoder(versionsResp.Body).Decode(&versions)
+	require.NoError(t, err, "Should return JSON versions")
+	assert.Empty(t, versions["versions"], "Unknown page should have no versions")
+
+	resp, err := client.Get(config.Test.ParserURL + "/api/data/confluence/pages/999999999999/diff")
+	require.NoError(t, err, "Should call diff endpoint")
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "Should return 404 when versions are missing")
+
+	badResp, err := client.Get(config.Test.ParserURL + "/api/data/confluence/pages/999999999999/diff?from=abc")
+	require.NoError(t, err, "Should call diff endpoint")
+	badResp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, badResp.StatusCode, "Should reject non-numeric versions")
+
+	t.Log("✓ Unknown page has no versions and no diff")
+}
+
+// TestConfluence_PagesIncludeComments verifies includeComments attaches a comment list to every page
+func TestConfluence_PagesIncludeComments(t *testing.T) {
+	if !config.API.Enabled {
+		t.Skip("API tests disabled in config")
+	}
+
+	timeout := time.Duration(config.Test.TimeoutSeconds) * time.Second
+	client := &http.Client{Timeout: timeout}
+
+	resp, err := client.Get(config.Test.ParserURL + "/api/data/confluence/pages?includeComments=true")
+	require.NoError(t, err, "Should call pages endpoint")
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Should return 200")
+
+	var result struct {
+		Pages []map[string]interface{} `json:"pages"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	require.NoError(t, err, "Should decode pages")
+
+	for _, page := range result.Pages {
+		comments, ok := page["comments"].([]interface{})
+		require.True(t, ok, "Page %v should carry a comments list", page["id"])
+		for _, item := range comments {
+			comment, ok := item.(map[string]interface{})
+			require.True(t, ok, "Comment should be an object")
+			assert.Contains(t, []interface{}{"footer", "inline"}, comment["kind"], "Comment should have a kind")
+			assert.NotNil(t, comment["replies"], "Comment should carry its replies")
+		}
+	}
+
+	t.Logf("✓ %d pages returned with comments", len(result.Pages))
+}
+
+// TestConfluence_PagesFilterByTypeAndLabel verifies the type and label filters of the pages endpoint
+func TestConfluence_PagesFilterByTypeAndLabel(t *testing.T) {
+	if !config.API.Enabled {
+		t.Skip("API tests disabled in config")
+	}
+
+	timeout := time.Duration(config.Test.TimeoutSeconds) * time.Second
+	client := &http.Client{Timeout: timeout}
+
+	resp, err := client.Get(config.Test.ParserURL + "/api/data/confluence/pages?type=blogpost")
+	require.NoError(t, err, "Should call pages endpoint")
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Should return 200")
+
+	var result struct {
+		Pages []map[string]interface{} `json:"pages"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	require.NoError(t, err, "Should decode pages")
+	for _, page := range result.Pages {
+		assert.Equal(t, "blogpost", page["type"], "Only blog posts should be returned")
+	}
+
+	labelResp, err := client.Get(config.Test.ParserURL + "/api/data/confluence/pages?label=no-such-label-999999")
+	require.NoError(t, err, "Should call pages endpoint")
+	defer labelResp.Body.Close()
+
+	var labelResult struct {
+		Pages []map[string]interface{} `json:"pages"`
+	}
+	err = json.NewDecoder(labelResp.Body).Decode(&labelResult)
+	require.NoError(t, err, "Should decode pages")
+	assert.Empty(t, labelResult.Pages, "Unknown label should match no pages")
+
+	badResp, err := client.Get(config.Test.ParserURL + "/api/data/confluence/pages?type=whiteboard")
+	require.NoError(t, err, "Should call pages endpoint")
+	badResp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, badResp.StatusCode, "Should reject unknown content types")
+
+	t.Logf("✓ %d blog posts returned", len(result.Pages))
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestJira_BoardsEndpoint verifies the boards endpoint returns a boards array
+func TestJira_BoardsEndpoint(t *testing.T) {
+	if !config.API.Enabled {
+		t.Skip("API tests disabled in config")
+	}
+
+	timeout := time.Duration(config.Test.TimeoutSeconds) * time.Second
+	client := &http.Client{Timeout: timeout}
+
+	resp, err := client.Get(config.Test.ParserURL + "/api/data/jira/boards")
+	require.NoError(t, err, "Should get boards")
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode, "Should return 200 OK")
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err, "Should read response body")
+
+	var data map[string]interface{}
+	err = json.Unmarshal(body, &data)
+	require.NoError(t, err, "Should parse JSON")
+
+	_, isArray := data["boards"].([]interface{})
+	assert.True(t, isArray, "Should return boards as an array")
+
+	t.Log("✓ Boards returned successfully")
+}
+
+// TestJira_UnknownBoardSprints verifies an unknown board has no sprints
+func TestJira_UnknownBoardSprints(t *testing.T) {
+	if !config.API.Enabled {
+		t.Skip("API tests disabled in config")
+	}
+
+	timeout := time.Duration(config.Test.TimeoutSeconds) * time.Second
+	client := &http.Client{Timeout: timeout}
+
+	resp, err := client.Get(config.Test.ParserURL + "/api/data/jira/boards/999999999/sprints")
+	require.NoError(t, err, "Should get board sprints")
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode, "Should return 200 OK")
+
+	var data map[string]interface{}
+	err = json.NewDecoder(resp.Body).Decode(&data)
+	require.NoError(t, err, "Should parse JSON")
+
+	sprints, isArray := data["sprints"].([]interface{})
+	assert.True(t, isArray, "Should return sprints as an array")
+	assert.Empty(t, sprints, "Unknown board should have no sprints")
+
+	t.Log("✓ Unknown board returns no sprints")
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGraph_UnknownIssue verifies the graph endpoint returns 404 for issues without links
+func TestGraph_UnknownIssue(t *testing.T) {
+	if !config.API.Enabled {
+		t.Skip("API tests disabled in config")
+	}
+
+	timeout := time.Duration(config.Test.TimeoutSeconds) * time.Second
+	client := &http.Client{Timeout: timeout}
+
+	for _, query := range []string{"", "?depth=3&type=Blocks", "?view=blocked-by"} {
+		url := config.Test.ParserURL + "/api/graph/issues/NOPE-999999" + query
+		t.Logf("Testing: GET %s", url)
+
+		resp, err := client.Get(url)
+		require.NoError(t, err, "Should be able to call graph endpoint")
+
+		var result map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, "Should return 404 for unknown issue")
+		require.NoError(t, err, "Should return JSON error body")
+		assert.Equal(t, "error", result["status"], "Should return error status")
+	}
+
+	t.Log("✓ Unknown issue graph returns 404")
+}
+
+// TestGraph_InvalidParameters verifies invalid depth and view values are rejected
+func TestGraph_InvalidParameters(t *testing.T) {
+	if !config.API.Enabled {
+		t.Skip("API tests disabled in config")
+	}
+
+	timeout := time.Duration(config.Test.TimeoutSeconds) * time.Second
+	client := &http.Client{Timeout: timeout}
+
+	for _, query := range []string{"?depth=zero", "?depth=-1", "?view=sideways"} {
+		resp, err := client.Get(config.Test.ParserURL + "/api/graph/issues/TEST-1" + query)
+		require.NoError(t, err, "Should be able to call graph endpoint")
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "Should reject %s", query)
+	}
+
+	t.Log("✓ Invalid graph parameters rejected")
+}
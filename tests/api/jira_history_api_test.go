@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestJira_IssueHistoryUnknownIssue verifies the history endpoint returns 404 for issues without history
+func TestJira_IssueHistoryUnknownIssue(t *testing.T) {
+	if !config.API.Enabled {
+		t.Skip("API tests disabled in config")
+	}
+
+	timeout := time.Duration(config.Test.TimeoutSeconds) * time.Second
+	client := &http.Client{Timeout: timeout}
+
+	url := config.Test.ParserURL + "/api/data/jira/issues/NOPE-999999/history"
+	t.Logf("Testing: GET %s", url)
+
+	resp, err := client.Get(url)
+	require.NoError(t, err, "Should be able to call history endpoint")
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "Should return 404 for unknown issue")
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err, "Should read response body")
+
+	var result map[string]interface{}
+	err = json.Unmarshal(body, &result)
+	require.NoError(t, err, "Should return JSON error body")
+	assert.Equal(t, "error", result["status"], "Should return error status")
+
+	t.Log("✓ Unknown issue history returns 404")
+}
+
+// TestJira_DataIncludeHistory verifies includeHistory keeps the Jira data shape intact
+func TestJira_DataIncludeHistory(t *testing.T) {
+	if !config.API.Enabled {
+		t.Skip("API tests disabled in config")
+	}
+
+	timeout := time.Duration(config.Test.TimeoutSeconds) * time.Second
+	client := &http.Client{Timeout: timeout}
+
+	resp, err := client.Get(config.Test.ParserURL + "/api/data/jira?includeHistory=true")
+	require.NoError(t, err, "Should get Jira data")
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode, "Should return 200 OK")
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err, "Should read response body")
+
+	var data map[string]interface{}
+	err = json.Unmarshal(body, &data)
+	require.NoError(t, err, "Should parse JSON")
+
+	_, hasProjects := data["projects"]
+	_, hasIssues := data["issues"]
+	assert.True(t, hasProjects, "Should include projects")
+	assert.True(t, hasIssues, "Should include issues")
+
+	t.Log("✓ Jira data with history returned successfully")
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOAuth_CallbackRejectsUnknownState verifies a callback that was not started through /api/oauth/start is refused
+func TestOAuth_CallbackRejectsUnknownState(t *testing.T) {
+	if !config.API.Enabled {
+		t.Skip("API tests disabled in config")
+	}
+
+	client := &http.Client{
+		Timeout: time.Duration(config.Test.TimeoutSeconds) * time.Second,
+		// A redirect would mean the callback was accepted
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Get(config.Test.ParserURL + "/api/oauth/callback?code=test-code&state=unknown-state")
+	require.NoError(t, err, "Should call OAuth callback")
+	defer resp.Body.Close()
+
+	// 409 when the service is not configured for OAuth, 400 when the state is unknown
+	assert.Contains(t, []int{http.StatusBadRequest, http.StatusConflict}, resp.StatusCode, "Unknown state should be rejected")
+
+	var result map[string]interface{}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	require.NoError(t, err, "Should return JSON")
+	assert.Equal(t, "error", result["status"], "Should report an error")
+
+	t.Log("✓ OAuth callback rejects unknown state")
+}